@@ -0,0 +1,212 @@
+package bundler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	"gopkg.in/yaml.v2"
+)
+
+// AdvisoryDBBindingType is the service binding type used to supply a checkout
+// of https://github.com/rubysec/ruby-advisory-db to the build.
+const AdvisoryDBBindingType = "ruby-advisory-db"
+
+var severityRanks = map[string]int{
+	"unknown":  0,
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+type BindingResolver interface {
+	ResolveOne(typ, provider, platformDir string) (servicebindings.Binding, error)
+}
+
+// Advisory is a ruby-advisory-db entry that applies to a gem locked by the
+// application.
+type Advisory struct {
+	ID              string
+	Gem             string
+	Version         string
+	Title           string
+	URL             string
+	Severity        string
+	PatchedVersions []string
+}
+
+// SeverityAtLeast reports whether the advisory is at or above the given
+// severity. An empty threshold matches every advisory.
+func (a Advisory) SeverityAtLeast(threshold string) bool {
+	if threshold == "" {
+		return true
+	}
+
+	return severityRanks[a.Severity] >= severityRanks[threshold]
+}
+
+// ValidSeverity reports whether the given value is a severity understood by
+// Advisory.SeverityAtLeast.
+func ValidSeverity(severity string) bool {
+	_, ok := severityRanks[severity]
+	return ok
+}
+
+type advisoryDocument struct {
+	Gem                string   `yaml:"gem"`
+	CVE                string   `yaml:"cve"`
+	GHSA               string   `yaml:"ghsa"`
+	OSVDB              string   `yaml:"osvdb"`
+	Title              string   `yaml:"title"`
+	URL                string   `yaml:"url"`
+	CVSSv2             float64  `yaml:"cvss_v2"`
+	CVSSv3             float64  `yaml:"cvss_v3"`
+	PatchedVersions    []string `yaml:"patched_versions"`
+	UnaffectedVersions []string `yaml:"unaffected_versions"`
+}
+
+type AdvisoryAuditor struct {
+	bindings       BindingResolver
+	lockfileParser GemfileLockParser
+}
+
+func NewAdvisoryAuditor(bindings BindingResolver) AdvisoryAuditor {
+	return AdvisoryAuditor{
+		bindings:       bindings,
+		lockfileParser: NewGemfileLockParser(),
+	}
+}
+
+// Audit matches the gems locked in the application's Gemfile.lock, along with
+// the given Bundler version, against the advisory database supplied through a
+// ruby-advisory-db binding. No network access is required.
+func (a AdvisoryAuditor) Audit(workingDir, platformPath, bundlerVersion string) ([]Advisory, error) {
+	binding, err := a.bindings.ResolveOne(AdvisoryDBBindingType, "", platformPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s binding: %w", AdvisoryDBBindingType, err)
+	}
+
+	lockfile, err := a.lockfileParser.Parse(filepath.Join(workingDir, GemfileLockSource))
+	if err != nil {
+		return nil, err
+	}
+
+	gems := lockfile.Gems()
+	if bundlerVersion != "" {
+		gems = append(gems, LockedGem{Name: Bundler, Version: bundlerVersion})
+	}
+
+	var advisories []Advisory
+	for _, gem := range gems {
+		paths, err := filepath.Glob(filepath.Join(binding.Path, "gems", gem.Name, "*.yml"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read advisory database: %w", err)
+		}
+
+		for _, path := range paths {
+			document, err := parseAdvisory(path)
+			if err != nil {
+				return nil, err
+			}
+
+			vulnerable, err := document.affects(gem.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate advisory %s: %w", path, err)
+			}
+
+			if !vulnerable {
+				continue
+			}
+
+			advisories = append(advisories, Advisory{
+				ID:              document.id(path),
+				Gem:             gem.Name,
+				Version:         gem.Version,
+				Title:           document.Title,
+				URL:             document.URL,
+				Severity:        document.severity(),
+				PatchedVersions: document.PatchedVersions,
+			})
+		}
+	}
+
+	sort.SliceStable(advisories, func(i, j int) bool {
+		if advisories[i].Gem != advisories[j].Gem {
+			return advisories[i].Gem < advisories[j].Gem
+		}
+		return advisories[i].ID < advisories[j].ID
+	})
+
+	return advisories, nil
+}
+
+func parseAdvisory(path string) (advisoryDocument, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return advisoryDocument{}, fmt.Errorf("failed to read advisory: %w", err)
+	}
+
+	var document advisoryDocument
+	err = yaml.Unmarshal(content, &document)
+	if err != nil {
+		return advisoryDocument{}, fmt.Errorf("failed to parse advisory %s: %w", path, err)
+	}
+
+	return document, nil
+}
+
+func (d advisoryDocument) affects(version string) (bool, error) {
+	for _, requirements := range [][]string{d.PatchedVersions, d.UnaffectedVersions} {
+		for _, requirement := range requirements {
+			ok, err := gemRequirementSatisfied(requirement, version)
+			if err != nil {
+				return false, err
+			}
+
+			if ok {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func (d advisoryDocument) id(path string) string {
+	switch {
+	case d.CVE != "":
+		return fmt.Sprintf("CVE-%s", d.CVE)
+	case d.GHSA != "":
+		return fmt.Sprintf("GHSA-%s", d.GHSA)
+	case d.OSVDB != "":
+		return fmt.Sprintf("OSVDB-%s", d.OSVDB)
+	default:
+		return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+}
+
+// severity follows the same CVSS banding as bundler-audit's criticality.
+func (d advisoryDocument) severity() string {
+	switch {
+	case d.CVSSv3 >= 9.0:
+		return "critical"
+	case d.CVSSv3 >= 7.0:
+		return "high"
+	case d.CVSSv3 >= 4.0:
+		return "medium"
+	case d.CVSSv3 > 0:
+		return "low"
+	case d.CVSSv2 >= 7.0:
+		return "high"
+	case d.CVSSv2 >= 4.0:
+		return "medium"
+	case d.CVSSv2 > 0:
+		return "low"
+	default:
+		return "unknown"
+	}
+}
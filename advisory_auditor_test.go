@@ -0,0 +1,198 @@
+package bundler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testAdvisoryAuditor(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir   string
+		platformPath string
+		databasePath string
+
+		auditor bundler.AdvisoryAuditor
+	)
+
+	writeAdvisory := func(gem, name, content string) {
+		dir := filepath.Join(databasePath, "gems", gem)
+		Expect(os.MkdirAll(dir, os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).To(Succeed())
+	}
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+		platformPath = t.TempDir()
+
+		t.Setenv("SERVICE_BINDING_ROOT", filepath.Join(platformPath, "bindings"))
+
+		databasePath = filepath.Join(platformPath, "bindings", "advisory-db")
+		Expect(os.MkdirAll(databasePath, os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(databasePath, "type"), []byte("ruby-advisory-db"), 0600)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.13.10-x86_64-linux)
+      racc (~> 1.4)
+    rack (2.2.3)
+    racc (1.6.2)
+
+PLATFORMS
+  x86_64-linux
+
+DEPENDENCIES
+  nokogiri
+  rack
+
+BUNDLED WITH
+   2.4.22
+`), 0600)).To(Succeed())
+
+		writeAdvisory("rack", "CVE-2022-44570.yml", `---
+gem: rack
+cve: 2022-44570
+ghsa: 65f5-mfpf-vfhj
+url: https://github.com/rack/rack/releases/tag/v3.0.4.1
+title: Denial of service via header parsing in Rack
+date: 2023-01-18
+cvss_v3: 7.5
+patched_versions:
+  - "~> 2.0.9, >= 2.0.9.2"
+  - "~> 2.1.4, >= 2.1.4.2"
+  - "~> 2.2.6, >= 2.2.6.2"
+  - ">= 3.0.4.1"
+`)
+		writeAdvisory("rack", "CVE-2020-8161.yml", `---
+gem: rack
+cve: 2020-8161
+url: https://groups.google.com/forum/#!topic/rubyonrails-security/IOO1vNZTzPA
+title: Directory traversal in Rack::Directory app bundled with Rack
+cvss_v3: 8.6
+patched_versions:
+  - "~> 2.1.3"
+  - ">= 2.2.0"
+`)
+		writeAdvisory("nokogiri", "GHSA-2qc6-mcvw-92cw.yml", `---
+gem: nokogiri
+ghsa: 2qc6-mcvw-92cw
+url: https://github.com/sparklemotion/nokogiri/security/advisories/GHSA-2qc6-mcvw-92cw
+title: Update bundled libxml2 to v2.10.3
+cvss_v2: 4.3
+unaffected_versions:
+  - "< 1.11.0"
+patched_versions:
+  - ">= 1.13.9"
+`)
+		writeAdvisory("bundler", "CVE-2021-43809.yml", `---
+gem: bundler
+cve: 2021-43809
+url: https://github.com/rubygems/rubygems/security/advisories/GHSA-fj7f-vq84-fh43
+title: Local Code Execution through Argument Injection via dash leading git url
+patched_versions:
+  - ">= 2.2.33"
+`)
+
+		auditor = bundler.NewAdvisoryAuditor(servicebindings.NewResolver())
+	})
+
+	context("Audit", func() {
+		it("returns the advisories that apply to the locked gems", func() {
+			advisories, err := auditor.Audit(workingDir, platformPath, "2.4.22")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(advisories).To(Equal([]bundler.Advisory{
+				{
+					ID:       "CVE-2022-44570",
+					Gem:      "rack",
+					Version:  "2.2.3",
+					Title:    "Denial of service via header parsing in Rack",
+					URL:      "https://github.com/rack/rack/releases/tag/v3.0.4.1",
+					Severity: "high",
+					PatchedVersions: []string{
+						"~> 2.0.9, >= 2.0.9.2",
+						"~> 2.1.4, >= 2.1.4.2",
+						"~> 2.2.6, >= 2.2.6.2",
+						">= 3.0.4.1",
+					},
+				},
+			}))
+		})
+
+		context("when the bundler version is affected", func() {
+			it("includes bundler in the results", func() {
+				advisories, err := auditor.Audit(workingDir, platformPath, "2.2.10")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(advisories).To(HaveLen(2))
+				Expect(advisories[0]).To(Equal(bundler.Advisory{
+					ID:              "CVE-2021-43809",
+					Gem:             "bundler",
+					Version:         "2.2.10",
+					Title:           "Local Code Execution through Argument Injection via dash leading git url",
+					URL:             "https://github.com/rubygems/rubygems/security/advisories/GHSA-fj7f-vq84-fh43",
+					Severity:        "unknown",
+					PatchedVersions: []string{">= 2.2.33"},
+				}))
+			})
+		})
+
+		context("when there is no Gemfile.lock", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "Gemfile.lock"))).To(Succeed())
+			})
+
+			it("only audits bundler", func() {
+				advisories, err := auditor.Audit(workingDir, platformPath, "2.4.22")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(advisories).To(BeEmpty())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the binding cannot be found", func() {
+				it.Before(func() {
+					Expect(os.RemoveAll(databasePath)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := auditor.Audit(workingDir, platformPath, "2.4.22")
+					Expect(err).To(MatchError(ContainSubstring("failed to resolve ruby-advisory-db binding:")))
+				})
+			})
+
+			context("when an advisory is malformed", func() {
+				it.Before(func() {
+					writeAdvisory("rack", "broken.yml", "%%%")
+				})
+
+				it("returns an error", func() {
+					_, err := auditor.Audit(workingDir, platformPath, "2.4.22")
+					Expect(err).To(MatchError(ContainSubstring("failed to parse advisory")))
+				})
+			})
+
+			context("when an advisory has an invalid requirement", func() {
+				it.Before(func() {
+					writeAdvisory("rack", "invalid.yml", `---
+gem: rack
+patched_versions:
+  - "about 2"
+`)
+				})
+
+				it("returns an error", func() {
+					_, err := auditor.Audit(workingDir, platformPath, "2.4.22")
+					Expect(err).To(MatchError(ContainSubstring(`invalid gem requirement "about 2"`)))
+				})
+			})
+		})
+	})
+}
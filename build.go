@@ -1,8 +1,15 @@
 package bundler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
-	"time"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/Masterminds/semver"
 	"github.com/paketo-buildpacks/packit/v2"
@@ -11,12 +18,20 @@ import (
 	"github.com/paketo-buildpacks/packit/v2/draft"
 	"github.com/paketo-buildpacks/packit/v2/postal"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
-	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
 //go:generate faux --interface DependencyManager --output fakes/dependency_manager.go
 //go:generate faux --interface Shimmer --output fakes/shimmer.go
 //go:generate faux --interface SBOMGenerator --output fakes/sbom_generator.go
+//go:generate faux --interface Auditor --output fakes/auditor.go
+//go:generate faux --interface LicenseChecker --output fakes/license_checker.go
+//go:generate faux --interface ChecksumVerifier --output fakes/checksum_verifier.go
+//go:generate faux --interface OfflineChecker --output fakes/offline_checker.go
+//go:generate faux --interface PlatformChecker --output fakes/platform_checker.go
+//go:generate faux --interface CompatibilityChecker --output fakes/compatibility_checker.go
+//go:generate faux --interface YankedChecker --output fakes/yanked_checker.go
+//go:generate faux --interface ProvenanceVerifier --output fakes/provenance_verifier.go
+//go:generate faux --interface ConfigParser --output fakes/config_parser.go
 
 type DependencyManager interface {
 	Resolve(path, id, version, stack string) (postal.Dependency, error)
@@ -32,38 +47,191 @@ type SBOMGenerator interface {
 	GenerateFromDependency(dependency postal.Dependency, dir string) (sbom.SBOM, error)
 }
 
+type Auditor interface {
+	Audit(workingDir, platformPath, bundlerVersion string) ([]Advisory, error)
+}
+
+type LicenseChecker interface {
+	Check(workingDir string, dependency postal.Dependency, policy LicensePolicy) ([]LicenseResult, error)
+}
+
+type ChecksumVerifier interface {
+	Verify(workingDir string, target packit.TargetInfo, distro packit.TargetDistro) (ChecksumReport, error)
+}
+
+type OfflineChecker interface {
+	Check(workingDir string, target packit.TargetInfo, distro packit.TargetDistro) (OfflineReport, error)
+}
+
+type PlatformChecker interface {
+	Check(workingDir string, target packit.TargetInfo, distro packit.TargetDistro) (PlatformReport, error)
+}
+
+type CompatibilityChecker interface {
+	Check(buildpackTOMLPath, workingDir string) (RubyCompatibility, error)
+}
+
+type YankedChecker interface {
+	Check(buildpackTOMLPath string, dependency postal.Dependency) (bool, error)
+}
+
+type ProvenanceVerifier interface {
+	Verify(dependency postal.Dependency, cnbPath, platformPath, stack string) (ProvenanceReport, error)
+}
+
+type ConfigParser interface {
+	ParseConfig(path string) (config Config, ok bool, err error)
+}
+
+// VersionSourcePriorities orders the sources of a requested Bundler version
+// from highest to lowest priority.
+var VersionSourcePriorities = []interface{}{"BP_BUNDLER_VERSION", BuildpackYMLSource, GemfileLockSource}
+
 func Build(
 	dependencies DependencyManager,
 	versionShimmer Shimmer,
 	sbomGenerator SBOMGenerator,
-	logger scribe.Emitter,
+	auditor Auditor,
+	licenseChecker LicenseChecker,
+	checksumVerifier ChecksumVerifier,
+	offlineChecker OfflineChecker,
+	platformChecker PlatformChecker,
+	compatibilityChecker CompatibilityChecker,
+	yankedChecker YankedChecker,
+	provenanceVerifier ProvenanceVerifier,
+	buildpackYMLParser ConfigParser,
+	logger Emitter,
 	clock chronos.Clock,
 ) packit.BuildFunc {
 	return func(context packit.BuildContext) (packit.BuildResult, error) {
 		logger.Title("%s %s", context.BuildpackInfo.Name, context.BuildpackInfo.Version)
 		logger.Process("Resolving Bundler version")
 
+		disableBuildpackYML, err := lookupBool("BP_BUNDLER_DISABLE_BUILDPACK_YML")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if disableBuildpackYML {
+			err = checkBuildpackYMLDisabled(buildpackYMLParser, context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
 		planner := draft.NewPlanner()
 
-		entry, allEntries := planner.Resolve("bundler", context.Plan.Entries, []interface{}{"BP_BUNDLER_VERSION", "buildpack.yml", "Gemfile.lock"})
+		entry, allEntries := planner.Resolve(Bundler, context.Plan.Entries, VersionSourcePriorities)
 		logger.Candidates(allEntries)
 
 		version, _ := entry.Metadata["version"].(string)
-		dependency, err := dependencies.Resolve(filepath.Join(context.CNBPath, "buildpack.toml"), entry.Name, version, context.Stack)
+		source, _ := entry.Metadata["version-source"].(string)
+		dependency, err := resolveCompatible(dependencies, compatibilityChecker, filepath.Join(context.CNBPath, "buildpack.toml"), context.WorkingDir, context.Stack, entry.Name, version, source, logger)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
 		logger.SelectedDependency(entry, dependency, clock.Now())
 
-		source, _ := entry.Metadata["version-source"].(string)
+		failOnEOL, err := lookupBool("BP_BUNDLER_FAIL_ON_EOL")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if failOnEOL && !dependency.DeprecationDate.IsZero() && !dependency.DeprecationDate.After(clock.Now()) {
+			return packit.BuildResult{}, fmt.Errorf("bundler %s reached end of life on %s: upgrade to a supported version or unset $BP_BUNDLER_FAIL_ON_EOL",
+				dependency.Version, dependency.DeprecationDate.Format("2006-01-02"))
+		}
+
+		yankedMode, err := lookupEnforcementMode("BP_BUNDLER_YANKED_CHECK")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		yanked := false
+		if yankedMode != "" {
+			yanked, err = yankedChecker.Check(filepath.Join(context.CNBPath, "buildpack.toml"), dependency)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		if yanked {
+			if yankedMode == "fail" {
+				return packit.BuildResult{}, fmt.Errorf("bundler %s has been yanked from RubyGems: select another version through $BP_BUNDLER_VERSION or unset $BP_BUNDLER_YANKED_CHECK", dependency.Version)
+			}
+
+			logger.Warning("Bundler %s has been yanked from RubyGems.", dependency.Version)
+			logger.Subprocess("Select another version through the $BP_BUNDLER_VERSION environment variable.")
+			logger.Break()
+		}
+
 		if source == "buildpack.yml" {
 			nextMajorVersion := semver.MustParse(context.BuildpackInfo.Version).IncMajor()
-			logger.Subprocess("WARNING: Setting the Bundler version through buildpack.yml will be deprecated soon in Bundler Buildpack v%s.", nextMajorVersion.String())
+			logger.Warning("Setting the Bundler version through buildpack.yml will be deprecated soon in Bundler Buildpack v%s.", nextMajorVersion.String())
 			logger.Subprocess("Please specify the version through the $BP_BUNDLER_VERSION environment variable instead. See README.md for more information.")
 			logger.Break()
 		}
 
+		auditMode, err := lookupEnforcementMode("BP_BUNDLER_AUDIT")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if auditMode != "" {
+			err = audit(auditor, context, dependency.Version, auditMode, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		policy := ParseLicensePolicy(os.Getenv("BP_BUNDLER_ALLOWED_LICENSES"), os.Getenv("BP_BUNDLER_DENIED_LICENSES"))
+		if !policy.IsEmpty() {
+			err = checkLicenses(licenseChecker, context, dependency, policy, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		checksumMode, err := lookupEnforcementMode("BP_BUNDLER_VERIFY_CHECKSUMS")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if checksumMode != "" {
+			err = verifyChecksums(checksumVerifier, context, checksumMode, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		offlineMode, err := lookupEnforcementMode("BP_BUNDLER_OFFLINE_CHECK")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if offlineMode != "" {
+			err = checkOfflineReadiness(offlineChecker, context, offlineMode, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		platformMode, err := lookupEnforcementMode("BP_BUNDLER_PLATFORM_CHECK")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = checkPlatforms(platformChecker, context, platformMode, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		provenanceMode, err := lookupEnforcementMode("BP_BUNDLER_VERIFY_PROVENANCE")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
 		legacySBOM := dependencies.GenerateBillOfMaterials(dependency)
 		launch, build := planner.MergeLayerTypes("bundler", context.Plan.Entries)
 
@@ -75,6 +243,12 @@ func Build(
 		var launchMetadata packit.LaunchMetadata
 		if launch {
 			launchMetadata.BOM = legacySBOM
+
+			prefixes, err := lookupLabelPrefixes("BP_BUNDLER_LABEL_PREFIXES")
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			launchMetadata.Labels = bundlerLabels(prefixes, dependency, source)
 		}
 
 		logger.Debug.Process("Getting the layer associated with Bundler:")
@@ -85,12 +259,25 @@ func Build(
 		logger.Debug.Subprocess(bundlerLayer.Path)
 		logger.Debug.Break()
 
+		report := BuildReport{
+			Resolution:   newResolution(entry, allEntries, dependency, context.WorkingDir),
+			ShimStrategy: ShimStrategy,
+		}
+
 		cachedChecksum, ok := bundlerLayer.Metadata[DepKey].(string)
 
 		if ok && cargo.Checksum(dependency.Checksum).MatchString(cachedChecksum) {
 			logger.Process("Reusing cached layer %s", bundlerLayer.Path)
 			logger.Break()
 
+			report.Reused = true
+			bundlerLayer.Metadata[ResolutionReportKey] = report
+
+			err = writeResolutionReport(bundlerLayer.Path, report)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
 			bundlerLayer.Launch, bundlerLayer.Build, bundlerLayer.Cache = launch, build, build
 			return packit.BuildResult{
 				Layers: []packit.Layer{bundlerLayer},
@@ -123,8 +310,19 @@ func Build(
 			return packit.BuildResult{}, err
 		}
 
-		logger.Action("Completed in %s", duration.Round(time.Millisecond))
+		logger.Completed(duration)
 		logger.Break()
+		report.InstallMillis = duration.Milliseconds()
+
+		// The provenance is verified once Deliver has validated the artifact
+		// against its checksum, so it is not verified for reused layers, whose
+		// artifact is not delivered again.
+		if provenanceMode != "" {
+			err = verifyProvenance(provenanceVerifier, context, dependency, provenanceMode, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
 
 		logger.GeneratingSBOM(bundlerLayer.Path)
 		var sbomContent sbom.SBOM
@@ -136,8 +334,9 @@ func Build(
 			return packit.BuildResult{}, err
 		}
 
-		logger.Action("Completed in %s", duration.Round(time.Millisecond))
+		logger.Completed(duration)
 		logger.Break()
+		report.SBOMMillis = duration.Milliseconds()
 
 		logger.FormattingSBOM(context.BuildpackInfo.SBOMFormats...)
 		bundlerLayer.SBOM, err = sbomContent.InFormats(context.BuildpackInfo.SBOMFormats...)
@@ -145,8 +344,14 @@ func Build(
 			return packit.BuildResult{}, err
 		}
 
+		err = writeResolutionReport(bundlerLayer.Path, report)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
 		bundlerLayer.Metadata = map[string]interface{}{
-			DepKey: dependency.Checksum,
+			DepKey:              dependency.Checksum,
+			ResolutionReportKey: report,
 		}
 
 		bundlerLayer.SharedEnv.Append("GEM_PATH", bundlerLayer.Path, ":")
@@ -159,3 +364,577 @@ func Build(
 		}, nil
 	}
 }
+
+// writeResolutionReport writes the report to the Bundler layer, replacing the
+// report of the build that last installed or reused the layer.
+func writeResolutionReport(layerPath string, report BuildReport) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(layerPath, ResolutionReportFile), content, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write resolution report: %w", err)
+	}
+
+	return nil
+}
+
+// checkBuildpackYMLDisabled fails the build when buildpack.yml still
+// configures Bundler although it has been disabled as a version source, and
+// names the $BP_BUNDLER_VERSION that replaces it.
+func checkBuildpackYMLDisabled(parser ConfigParser, workingDir string) error {
+	config, ok, err := parser.ParseConfig(filepath.Join(workingDir, BuildpackYMLSource))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	if config.Version == "" {
+		return fmt.Errorf("buildpack.yml is disabled by $BP_BUNDLER_DISABLE_BUILDPACK_YML: remove the bundler key from buildpack.yml")
+	}
+
+	return fmt.Errorf("buildpack.yml is disabled by $BP_BUNDLER_DISABLE_BUILDPACK_YML: remove the bundler key from buildpack.yml and set $BP_BUNDLER_VERSION=%q instead", config.Version)
+}
+
+// resolveCompatible resolves the Bundler dependency and, when the selected
+// version cannot run on the application's Ruby, resolves the newest version
+// of buildpack.toml that both satisfies the constraint and supports the Ruby.
+func resolveCompatible(dependencies DependencyManager, compatibilityChecker CompatibilityChecker, path, workingDir, stack string, id, version, source string, logger Emitter) (postal.Dependency, error) {
+	compatibility, err := compatibilityChecker.Check(path, workingDir)
+	if err != nil {
+		return postal.Dependency{}, err
+	}
+
+	dependency, err := dependencies.Resolve(path, id, version, stack)
+	if err != nil {
+		version, err = fallbackMajor(err, compatibility.Versions, version, source, logger)
+		if err != nil {
+			return postal.Dependency{}, err
+		}
+
+		dependency, err = dependencies.Resolve(path, id, version, stack)
+		if err != nil {
+			return postal.Dependency{}, err
+		}
+	}
+
+	incompatible := map[string]bool{}
+	for _, bundler := range compatibility.Incompatible {
+		incompatible[bundler.Version] = true
+	}
+
+	if !incompatible[dependency.Version] {
+		return dependency, nil
+	}
+
+	requested := version
+	if requested == "" || requested == "default" {
+		requested = compatibility.DefaultVersion
+	}
+	if requested == "" {
+		requested = "*"
+	}
+
+	logger.Subprocess("Skipping Bundler versions that do not support Ruby %s (from %s):", compatibility.Ruby.Version, compatibility.Ruby.Source)
+
+	var excluded []string
+	for _, bundler := range compatibility.Incompatible {
+		logger.Action("%s (requires Ruby %s)", bundler.Version, bundler.RequiredRubyVersion)
+		excluded = append(excluded, fmt.Sprintf("%s (requires Ruby %s)", bundler.Version, bundler.RequiredRubyVersion))
+	}
+	logger.Break()
+
+	candidates, err := matchingVersions(compatibility.Versions, requested)
+	if err != nil {
+		return postal.Dependency{}, err
+	}
+
+	// The candidates are resolved one after the other, as the dependency
+	// manager is the one to know which of them are available for the stack.
+	for _, candidate := range candidates {
+		if incompatible[candidate] {
+			continue
+		}
+
+		dependency, err = dependencies.Resolve(path, id, candidate, stack)
+		if err != nil {
+			var noDeps *postal.ErrNoDeps
+			if errors.As(err, &noDeps) {
+				continue
+			}
+
+			return postal.Dependency{}, err
+		}
+
+		return dependency, nil
+	}
+
+	return postal.Dependency{}, fmt.Errorf("no Bundler version matching %q supports Ruby %s (from %s): incompatible versions are %s; upgrade Ruby or set $BP_BUNDLER_VERSION to a compatible version",
+		requested, compatibility.Ruby.Version, compatibility.Ruby.Source, strings.Join(excluded, ", "))
+}
+
+// matchingVersions returns the versions that satisfy the constraint, newest
+// first. The pessimistic operator is expanded the same way the dependency
+// manager expands it.
+func matchingVersions(versions []string, constraint string) ([]string, error) {
+	if strings.Contains(constraint, "~>") {
+		constraint = strings.TrimSpace(strings.ReplaceAll(constraint, "~>", ""))
+		if len(strings.Split(constraint, ".")) == 3 {
+			constraint = "~" + constraint
+		} else {
+			constraint = "^" + constraint
+		}
+	}
+
+	svConstraint, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Bundler version constraint %q: %w", constraint, err)
+	}
+
+	var matching []*semver.Version
+	for _, version := range versions {
+		svVersion, err := semver.NewVersion(version)
+		if err != nil {
+			continue
+		}
+
+		if svConstraint.Check(svVersion) {
+			matching = append(matching, svVersion)
+		}
+	}
+
+	sort.Sort(sort.Reverse(semver.Collection(matching)))
+
+	var result []string
+	for _, version := range matching {
+		result = append(result, version.Original())
+	}
+
+	return result, nil
+}
+
+// fallbackMajor handles a constraint that failed to resolve because it pins
+// a major version of Bundler that the buildpack does not ship, as happens with
+// lockfiles bundled with Bundler 1. Unless $BP_BUNDLER_ALLOW_MAJOR_FALLBACK is
+// enabled, an error explaining how to upgrade is returned. Any other
+// resolution error is returned unchanged.
+func fallbackMajor(resolveErr error, versions []string, version, source string, logger Emitter) (string, error) {
+	var noDeps *postal.ErrNoDeps
+	if !errors.As(resolveErr, &noDeps) {
+		return "", resolveErr
+	}
+
+	requested, ok := requestedMajor(version)
+	if !ok {
+		return "", resolveErr
+	}
+
+	var majors []int
+	for _, v := range versions {
+		major, ok := requestedMajor(v)
+		if ok && !containsInt(majors, major) {
+			majors = append(majors, major)
+		}
+	}
+	sort.Ints(majors)
+
+	if len(majors) == 0 || containsInt(majors, requested) {
+		return "", resolveErr
+	}
+
+	var supported []string
+	for _, major := range majors {
+		supported = append(supported, strconv.Itoa(major))
+	}
+
+	// Prefer the next newer major, as Bundler reads lockfiles written by
+	// older versions, and only fall back to an older one if there is none.
+	nearest := majors[len(majors)-1]
+	for _, major := range majors {
+		if major > requested {
+			nearest = major
+			break
+		}
+	}
+
+	allowFallback, err := lookupBool("BP_BUNDLER_ALLOW_MAJOR_FALLBACK")
+	if err != nil {
+		return "", err
+	}
+
+	if !allowFallback {
+		return "", fmt.Errorf("unsupported Bundler major version %d (requested %q via %s): supported major versions are %s. "+
+			"Upgrade the lockfile by running 'bundle update --bundler' with Bundler %d and commit Gemfile.lock, "+
+			"or set $BP_BUNDLER_ALLOW_MAJOR_FALLBACK=true to install Bundler %d instead",
+			requested, version, source, strings.Join(supported, ", "), nearest, nearest)
+	}
+
+	logger.Warning("Bundler %d (requested %q via %s) is not supported by this buildpack.", requested, version, source)
+	logger.Warning("Falling back to Bundler %d because $BP_BUNDLER_ALLOW_MAJOR_FALLBACK is set.", nearest)
+	logger.Warning("The application may not work with this Bundler version. Run 'bundle update --bundler' and commit Gemfile.lock to fix this.")
+	logger.Break()
+
+	return fmt.Sprintf("%d.*.*", nearest), nil
+}
+
+// requestedMajor returns the major version pinned by a simple version or
+// constraint such as "1.*.*", "~> 1.17" or "1.17.3".
+func requestedMajor(constraint string) (int, bool) {
+	if strings.ContainsAny(constraint, ",|<") {
+		return 0, false
+	}
+
+	constraint = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(constraint), "~>=^v"))
+	major, _, _ := strings.Cut(constraint, ".")
+
+	value, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupBool reads a boolean environment variable, which defaults to false
+// when unset.
+func lookupBool(name string) (bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %q (must be \"true\" or \"false\")", name, value)
+	}
+
+	return enabled, nil
+}
+
+var labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]+([.-][a-z0-9]+)*$`)
+
+// lookupLabelPrefixes reads a comma separated list of label prefixes that
+// are used in addition to DefaultLabelPrefix.
+func lookupLabelPrefixes(name string) ([]string, error) {
+	prefixes := []string{DefaultLabelPrefix}
+	seen := map[string]bool{DefaultLabelPrefix: true}
+	for _, prefix := range strings.Split(os.Getenv(name), ",") {
+		prefix = strings.TrimSuffix(strings.TrimSpace(prefix), ".")
+		if prefix == "" {
+			continue
+		}
+
+		if !labelPrefixPattern.MatchString(prefix) {
+			return nil, fmt.Errorf("invalid value for %s: %q is not a valid label prefix (must be lowercase alphanumerics separated by \".\" or \"-\")", name, prefix)
+		}
+
+		if !seen[prefix] {
+			seen[prefix] = true
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return prefixes, nil
+}
+
+// bundlerLabels returns the image labels that describe the installed Bundler
+// under each of the given prefixes.
+func bundlerLabels(prefixes []string, dependency postal.Dependency, source string) map[string]string {
+	if source == "" {
+		source = "default"
+	}
+
+	labels := map[string]string{}
+	for _, prefix := range prefixes {
+		labels[prefix+".version"] = dependency.Version
+		labels[prefix+".version-source"] = source
+		if dependency.Checksum != "" {
+			labels[prefix+".checksum"] = dependency.Checksum
+		}
+	}
+
+	return labels
+}
+
+// lookupEnforcementMode reads an environment variable that controls whether
+// a check is skipped (unset), only reported ("warn") or fails the build
+// ("fail").
+func lookupEnforcementMode(name string) (string, error) {
+	mode := os.Getenv(name)
+	switch mode {
+	case "", "warn", "fail":
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid value for %s: %q (must be \"warn\" or \"fail\")", name, mode)
+	}
+}
+
+func audit(auditor Auditor, context packit.BuildContext, bundlerVersion, mode string, logger Emitter) error {
+	threshold := strings.ToLower(os.Getenv("BP_BUNDLER_AUDIT_SEVERITY"))
+	if threshold != "" && !ValidSeverity(threshold) {
+		return fmt.Errorf("invalid value for BP_BUNDLER_AUDIT_SEVERITY: %q (must be one of \"low\", \"medium\", \"high\" or \"critical\")", threshold)
+	}
+
+	logger.Process("Auditing Gemfile.lock against %s", AdvisoryDBBindingType)
+	advisories, err := auditor.Audit(context.WorkingDir, context.Platform.Path, bundlerVersion)
+	if err != nil {
+		return err
+	}
+
+	if len(advisories) == 0 {
+		logger.Subprocess("No advisories found")
+		logger.Break()
+		return nil
+	}
+
+	var failures int
+	logger.Warning("Found %d advisories:", len(advisories))
+	for _, advisory := range advisories {
+		logger.Action("%s %s: %s (%s severity)", advisory.Gem, advisory.Version, advisory.ID, advisory.Severity)
+		if advisory.Title != "" {
+			logger.Detail("Title: %s", advisory.Title)
+		}
+		if advisory.URL != "" {
+			logger.Detail("URL: %s", advisory.URL)
+		}
+
+		patched := "none"
+		if len(advisory.PatchedVersions) > 0 {
+			patched = strings.Join(advisory.PatchedVersions, ", ")
+		}
+		logger.Detail("Patched versions: %s", patched)
+
+		if advisory.SeverityAtLeast(threshold) {
+			failures++
+		}
+	}
+	logger.Break()
+
+	if mode == "fail" && failures > 0 {
+		if threshold == "" {
+			return fmt.Errorf("bundler audit failed: found %d advisories", failures)
+		}
+		return fmt.Errorf("bundler audit failed: found %d advisories at or above %s severity", failures, threshold)
+	}
+
+	return nil
+}
+
+func checkLicenses(licenseChecker LicenseChecker, context packit.BuildContext, dependency postal.Dependency, policy LicensePolicy, logger Emitter) error {
+	logger.Process("Checking license policy")
+	if len(policy.Allowed) > 0 {
+		logger.Subprocess("Allowed licenses: %s", strings.Join(policy.Allowed, ", "))
+	}
+	if len(policy.Denied) > 0 {
+		logger.Subprocess("Denied licenses: %s", strings.Join(policy.Denied, ", "))
+	}
+
+	results, err := licenseChecker.Check(context.WorkingDir, dependency, policy)
+	if err != nil {
+		return err
+	}
+
+	var offenders []string
+	for _, result := range results {
+		expression := result.Expression
+		if expression == "" {
+			expression = "unknown license"
+		}
+
+		status := "PASS"
+		if !result.Permitted {
+			status = "FAIL"
+			offenders = append(offenders, fmt.Sprintf("%s %s (%s)", result.Component, result.Version, expression))
+		}
+
+		logger.Action("%s %s %s: %s (from %s)", status, result.Component, result.Version, expression, result.Source)
+	}
+	logger.Break()
+
+	if len(offenders) > 0 {
+		return fmt.Errorf("license policy violated by %d component(s): %s", len(offenders), strings.Join(offenders, ", "))
+	}
+
+	return nil
+}
+
+func verifyChecksums(checksumVerifier ChecksumVerifier, context packit.BuildContext, mode string, logger Emitter) error {
+	logger.Process("Verifying vendored gems against Gemfile.lock CHECKSUMS")
+	report, err := checksumVerifier.Verify(context.WorkingDir, context.TargetInfo, context.TargetDistro)
+	if err != nil {
+		return err
+	}
+
+	if !report.CacheFound {
+		logger.Subprocess("vendor/cache does not exist, there are no vendored gems to verify")
+		logger.Break()
+		return nil
+	}
+
+	if report.Checksums == 0 {
+		logger.Warning("Gemfile.lock has no CHECKSUMS section, vendored gems cannot be verified")
+		logger.Subprocess("Run 'bundle lock --add-checksums' (Bundler 2.5+) and commit the updated Gemfile.lock.")
+		logger.Break()
+
+		if mode == "fail" {
+			return errors.New("checksum verification failed: Gemfile.lock has no CHECKSUMS section")
+		}
+		return nil
+	}
+
+	logger.Subprocess("Verified %d vendored gem(s)", len(report.Verified))
+	for _, mismatch := range report.Mismatched {
+		logger.Action("MISMATCH %s: expected %s, got %s", mismatch.File, mismatch.Expected, mismatch.Actual)
+	}
+	for _, name := range report.Missing {
+		logger.Action("MISSING %s: listed in Gemfile.lock but not in vendor/cache", name)
+	}
+	for _, name := range report.Extra {
+		logger.Action("EXTRA %s: in vendor/cache but has no Gemfile.lock checksum", name)
+	}
+	logger.Break()
+
+	if mode == "fail" && report.Failed() {
+		return fmt.Errorf("checksum verification failed: %d mismatched, %d missing, %d extra", len(report.Mismatched), len(report.Missing), len(report.Extra))
+	}
+
+	return nil
+}
+
+func verifyProvenance(provenanceVerifier ProvenanceVerifier, context packit.BuildContext, dependency postal.Dependency, mode string, logger Emitter) error {
+	logger.Process("Verifying provenance of Bundler %s", dependency.Version)
+	report, err := provenanceVerifier.Verify(dependency, context.CNBPath, context.Platform.Path, context.Stack)
+	if err != nil {
+		return err
+	}
+
+	logger.Subprocess("Statement: %s", report.URI)
+	if report.Builder != "" {
+		logger.Subprocess("Builder: %s", report.Builder)
+	}
+
+	if !report.Failed() {
+		logger.Subprocess("Artifact %s was built from %s (%s)", dependency.Checksum, dependency.Source, dependency.SourceChecksum)
+		logger.Break()
+		return nil
+	}
+
+	logger.Warning("The provenance statement does not vouch for this dependency:")
+	for _, problem := range report.Problems {
+		logger.Action("%s", problem)
+	}
+	logger.Break()
+
+	if mode == "fail" {
+		return fmt.Errorf("provenance verification failed: %s", strings.Join(report.Problems, "; "))
+	}
+
+	return nil
+}
+
+func checkOfflineReadiness(offlineChecker OfflineChecker, context packit.BuildContext, mode string, logger Emitter) error {
+	logger.Process("Checking vendor/cache for offline installation")
+	report, err := offlineChecker.Check(context.WorkingDir, context.TargetInfo, context.TargetDistro)
+	if err != nil {
+		return err
+	}
+
+	if !report.LockfileFound {
+		logger.Subprocess("Gemfile.lock does not exist, there are no locked gems to check")
+		logger.Break()
+		return nil
+	}
+
+	if report.Ready() {
+		logger.Subprocess("vendor/cache satisfies Gemfile.lock")
+		logger.Break()
+		return nil
+	}
+
+	logger.Warning("An offline 'bundle install' will fail on %s:", report.Target)
+	if !report.CacheFound {
+		logger.Action("vendor/cache does not exist")
+	}
+	for _, file := range report.Missing {
+		logger.Action("Missing %s", file)
+	}
+
+	var git, path bool
+	for _, source := range report.Unsatisfiable {
+		logger.Action("%s source %s: %s", source.Type, source.Remote, source.Reason)
+		git = git || source.Type == "GIT"
+		path = path || source.Type == "PATH"
+	}
+
+	logger.Subprocess("To prepare the application for offline builds:")
+	if !report.CacheFound || len(report.Missing) > 0 {
+		logger.Action("Run 'bundle cache --all-platforms' and commit vendor/cache")
+	}
+	if git {
+		logger.Action("Run 'bundle cache --all' so that GIT sources are included in vendor/cache")
+	}
+	if path {
+		logger.Action("Move PATH gems into the application directory or publish them to a gem server")
+	}
+	logger.Break()
+
+	if mode == "fail" {
+		if !report.CacheFound {
+			return errors.New("offline readiness check failed: vendor/cache does not exist")
+		}
+		return fmt.Errorf("offline readiness check failed: %d missing gem(s), %d unsatisfiable source(s)", len(report.Missing), len(report.Unsatisfiable))
+	}
+
+	return nil
+}
+
+// checkPlatforms is always performed, unlike the other checks, since a
+// lockfile that does not cover the build target breaks the installation of
+// native gems. Problems are reported as warnings unless mode is "fail".
+func checkPlatforms(platformChecker PlatformChecker, context packit.BuildContext, mode string, logger Emitter) error {
+	report, err := platformChecker.Check(context.WorkingDir, context.TargetInfo, context.TargetDistro)
+	if err != nil {
+		return err
+	}
+
+	if len(report.Platforms) == 0 || report.Matched != "" {
+		return nil
+	}
+
+	logger.Process("Checking Gemfile.lock platforms")
+	logger.Subprocess("Build target: %s (%s)", report.Target, strings.Join(report.Candidates, ", "))
+	logger.Subprocess("Locked platforms: %s", strings.Join(report.Platforms, ", "))
+
+	if report.Resolvable() {
+		logger.Warning("Gemfile.lock only covers the generic 'ruby' platform for this target,")
+		logger.Subprocess("native gems will be compiled from source. To use precompiled gems, run:")
+		logger.Action("%s", report.AddPlatformCommand())
+		logger.Break()
+		return nil
+	}
+
+	logger.Warning("Gemfile.lock cannot be resolved for the build target. Run the following and commit the updated Gemfile.lock:")
+	logger.Action("%s", report.AddPlatformCommand())
+	logger.Break()
+
+	if mode == "fail" {
+		return fmt.Errorf("platform check failed: Gemfile.lock PLATFORMS (%s) does not include %s", strings.Join(report.Platforms, ", "), report.Candidates[0])
+	}
+
+	return nil
+}
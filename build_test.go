@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/paketo-buildpacks/bundler"
 	"github.com/paketo-buildpacks/bundler/fakes"
@@ -15,7 +16,6 @@ import (
 	"github.com/paketo-buildpacks/packit/v2/chronos"
 	"github.com/paketo-buildpacks/packit/v2/postal"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
-	"github.com/paketo-buildpacks/packit/v2/scribe"
 
 	//nolint Ignore SA1019, informed usage of deprecated package
 	"github.com/paketo-buildpacks/packit/v2/paketosbom"
@@ -34,6 +34,15 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		dependencyManager *fakes.DependencyManager
 		versionShimmer    *fakes.Shimmer
 		sbomGenerator     *fakes.SBOMGenerator
+		auditor           *fakes.Auditor
+		licenseChecker    *fakes.LicenseChecker
+		checksumVerifier  *fakes.ChecksumVerifier
+		offlineChecker    *fakes.OfflineChecker
+		platformChecker   *fakes.PlatformChecker
+		compatibility     *fakes.CompatibilityChecker
+		yankedChecker     *fakes.YankedChecker
+		configParser      *fakes.ConfigParser
+		provenance        *fakes.ProvenanceVerifier
 
 		clock  chronos.Clock
 		buffer *bytes.Buffer
@@ -73,14 +82,32 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		clock = chronos.DefaultClock
 
 		buffer = bytes.NewBuffer(nil)
-		logEmitter := scribe.NewEmitter(buffer)
+		logEmitter := bundler.NewEmitter(buffer)
 
 		versionShimmer = &fakes.Shimmer{}
+		auditor = &fakes.Auditor{}
+		licenseChecker = &fakes.LicenseChecker{}
+		checksumVerifier = &fakes.ChecksumVerifier{}
+		offlineChecker = &fakes.OfflineChecker{}
+		platformChecker = &fakes.PlatformChecker{}
+		compatibility = &fakes.CompatibilityChecker{}
+		yankedChecker = &fakes.YankedChecker{}
+		provenance = &fakes.ProvenanceVerifier{}
+		configParser = &fakes.ConfigParser{}
 
 		build = bundler.Build(
 			dependencyManager,
 			versionShimmer,
 			sbomGenerator,
+			auditor,
+			licenseChecker,
+			checksumVerifier,
+			offlineChecker,
+			platformChecker,
+			compatibility,
+			yankedChecker,
+			provenance,
+			configParser,
 			logEmitter,
 			clock,
 		)
@@ -91,8 +118,9 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 				Version:     "some-version",
 				SBOMFormats: []string{sbom.CycloneDXFormat, sbom.SPDXFormat},
 			},
-			CNBPath: cnbDir,
-			Stack:   "some-stack",
+			CNBPath:    cnbDir,
+			WorkingDir: "working-dir",
+			Stack:      "some-stack",
 			Plan: packit.BuildpackPlan{
 				Entries: []packit.BuildpackPlanEntry{
 					{
@@ -131,114 +159,1026 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		Expect(layer.Launch).To(BeFalse())
 		Expect(layer.Cache).To(BeFalse())
 
-		Expect(layer.Metadata).To(Equal(map[string]interface{}{
-			"dependency-sha": "",
+		Expect(layer.Metadata).To(HaveLen(2))
+		Expect(layer.Metadata).To(HaveKeyWithValue("dependency-sha", ""))
+		Expect(layer.Metadata).To(HaveKey("resolution-report"))
+
+		report, ok := layer.Metadata["resolution-report"].(bundler.BuildReport)
+		Expect(ok).To(BeTrue())
+		Expect(report.Candidates).To(Equal([]bundler.VersionCandidate{
+			{Source: "BP_BUNDLER_VERSION", Version: "2.0.x"},
 		}))
+		Expect(report.Selected).To(Equal(bundler.VersionCandidate{Source: "BP_BUNDLER_VERSION", Version: "2.0.x"}))
+		Expect(report.Dependency.Version).To(Equal("2.0.1"))
+		Expect(report.Reused).To(BeFalse())
+		Expect(report.ShimStrategy).To(Equal("version-argument"))
+
+		content, err := os.ReadFile(filepath.Join(layersDir, "bundler", "resolution-report.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		var written bundler.BuildReport
+		Expect(json.Unmarshal(content, &written)).To(Succeed())
+		Expect(written).To(Equal(report))
+
+		Expect(layer.SBOM.Formats()).To(HaveLen(2))
+
+		cdx := layer.SBOM.Formats()[0]
+		Expect(cdx.Extension).To(Equal("cdx.json"))
+
+		content, err = io.ReadAll(cdx.Content)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(MatchJSON(`{
+			"$schema": "http://cyclonedx.org/schema/bom-1.3.schema.json",
+			"bomFormat": "CycloneDX",
+			"metadata": {
+				"tools": [
+					{
+						"name": "",
+						"vendor": "anchore"
+					}
+				]
+			},
+			"specVersion": "1.3",
+			"version": 1
+		}`))
+
+		spdx := layer.SBOM.Formats()[1]
+		Expect(spdx.Extension).To(Equal("spdx.json"))
+
+		content, err = io.ReadAll(spdx.Content)
+		Expect(err).NotTo(HaveOccurred())
+
+		var spdxDocument map[string]interface{}
+		Expect(json.Unmarshal(content, &spdxDocument)).To(Succeed())
+		Expect(spdxDocument).To(HaveKeyWithValue("SPDXID", "SPDXRef-DOCUMENT"))
+		Expect(spdxDocument).To(HaveKeyWithValue("dataLicense", "CC0-1.0"))
+		Expect(spdxDocument).To(HaveKeyWithValue("name", "unknown"))
+		Expect(spdxDocument).To(HaveKeyWithValue("spdxVersion", "SPDX-2.2"))
+		Expect(spdxDocument).To(HaveKeyWithValue("documentNamespace", MatchRegexp(`^https://paketo\.io/unknown-source-type/unknown-[0-9a-f-]+$`)))
+
+		Expect(spdxDocument).To(HaveKey("creationInfo"))
+		creationInfo, ok := spdxDocument["creationInfo"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(creationInfo).To(HaveKeyWithValue("created", "0001-01-01T00:00:00Z"))
+		Expect(creationInfo).To(HaveKeyWithValue("creators", ContainElements("Organization: Anchore, Inc", "Tool: -")))
+		Expect(creationInfo).To(HaveKeyWithValue("licenseListVersion", MatchRegexp(`^\d+\.\d+$`)))
+
+		Expect(spdxDocument).To(HaveKeyWithValue("packages", HaveLen(1)))
+		packages, ok := spdxDocument["packages"].([]interface{})
+		Expect(ok).To(BeTrue())
+		pkg, ok := packages[0].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(pkg).To(HaveKeyWithValue("SPDXID", "SPDXRef-DocumentRoot-Unknown-"))
+		Expect(pkg).To(HaveKeyWithValue("copyrightText", "NOASSERTION"))
+		Expect(pkg).To(HaveKeyWithValue("downloadLocation", "NOASSERTION"))
+		Expect(pkg).To(HaveKeyWithValue("filesAnalyzed", BeFalse()))
+		Expect(pkg).To(HaveKeyWithValue("licenseConcluded", "NOASSERTION"))
+		Expect(pkg).To(HaveKeyWithValue("licenseDeclared", "NOASSERTION"))
+		Expect(pkg).To(HaveKeyWithValue("name", ""))
+		Expect(pkg).To(HaveKeyWithValue("supplier", "NOASSERTION"))
+
+		Expect(spdxDocument).To(HaveKeyWithValue("relationships", HaveLen(1)))
+		relationships, ok := spdxDocument["relationships"].([]interface{})
+		Expect(ok).To(BeTrue())
+		relationship, ok := relationships[0].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(relationship).To(HaveKeyWithValue("relatedSpdxElement", "SPDXRef-DocumentRoot-Unknown-"))
+		Expect(relationship).To(HaveKeyWithValue("relationshipType", "DESCRIBES"))
+		Expect(relationship).To(HaveKeyWithValue("spdxElementId", "SPDXRef-DOCUMENT"))
+		Expect(filepath.Join(layersDir, "bundler")).To(BeADirectory())
+
+		Expect(dependencyManager.ResolveCall.Receives.Path).To(Equal(filepath.Join(cnbDir, "buildpack.toml")))
+		Expect(dependencyManager.ResolveCall.Receives.Id).To(Equal("bundler"))
+		Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.0.x"))
+		Expect(dependencyManager.ResolveCall.Receives.Stack).To(Equal("some-stack"))
+		Expect(dependencyManager.ResolveCall.CallCount).To(Equal(1))
+
+		Expect(compatibility.CheckCall.Receives.BuildpackTOMLPath).To(Equal(filepath.Join(cnbDir, "buildpack.toml")))
+		Expect(compatibility.CheckCall.Receives.WorkingDir).To(Equal("working-dir"))
+
+		Expect(dependencyManager.GenerateBillOfMaterialsCall.Receives.Dependencies).To(Equal([]postal.Dependency{
+			{
+				Name:    "Bundler",
+				Version: "2.0.1",
+			},
+		}))
+
+		Expect(dependencyManager.DeliverCall.Receives.Dependency).To(Equal(postal.Dependency{
+			Name:    "Bundler",
+			Version: "2.0.1",
+		}))
+		Expect(dependencyManager.DeliverCall.Receives.CnbPath).To(Equal(cnbDir))
+		Expect(dependencyManager.DeliverCall.Receives.LayerPath).To(Equal(filepath.Join(layersDir, "bundler")))
+		Expect(dependencyManager.DeliverCall.Receives.PlatformPath).To(Equal("platform"))
+
+		Expect(versionShimmer.ShimCall.Receives.Path).To(Equal(filepath.Join(layersDir, "bundler", "bin")))
+		Expect(versionShimmer.ShimCall.Receives.Version).To(Equal("2.0.1"))
+
+		Expect(sbomGenerator.GenerateFromDependencyCall.Receives.Dependency).To(Equal(postal.Dependency{
+			Name:    "Bundler",
+			Version: "2.0.1",
+		}))
+		Expect(sbomGenerator.GenerateFromDependencyCall.Receives.Dir).To(Equal(filepath.Join(layersDir, "bundler")))
+
+		Expect(buffer.String()).To(ContainSubstring("Some Buildpack some-version"))
+		Expect(buffer.String()).To(ContainSubstring("Resolving Bundler version"))
+		Expect(buffer.String()).To(ContainSubstring("Selected Bundler version (using BP_BUNDLER_VERSION): "))
+		Expect(buffer.String()).NotTo(ContainSubstring("WARNING: Setting the Bundler version through buildpack.yml will be deprecated soon in Bundler Buildpack v2.0.0."))
+		Expect(buffer.String()).NotTo(ContainSubstring("Please specify the version through the $BP_BUNDLER_VERSION environment variable instead. See README.md for more information."))
+		Expect(buffer.String()).To(ContainSubstring("Executing build process"))
+		Expect(buffer.String()).To(ContainSubstring("Configuring build environment"))
+		Expect(buffer.String()).To(ContainSubstring("Configuring launch environment"))
+
+		Expect(auditor.AuditCall.CallCount).To(Equal(0))
+		Expect(licenseChecker.CheckCall.CallCount).To(Equal(0))
+		Expect(checksumVerifier.VerifyCall.CallCount).To(Equal(0))
+		Expect(offlineChecker.CheckCall.CallCount).To(Equal(0))
+		Expect(provenance.VerifyCall.CallCount).To(Equal(0))
+
+		Expect(platformChecker.CheckCall.CallCount).To(Equal(1))
+		Expect(buffer.String()).NotTo(ContainSubstring("Checking Gemfile.lock platforms"))
+	})
+
+	context("when $BP_BUNDLER_AUDIT is set", func() {
+		it.Before(func() {
+			t.Setenv("BP_BUNDLER_AUDIT", "warn")
+		})
+
+		context("when no advisories are found", func() {
+			it("audits the lockfile and the selected bundler version", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(auditor.AuditCall.Receives.WorkingDir).To(Equal("working-dir"))
+				Expect(auditor.AuditCall.Receives.PlatformPath).To(Equal("platform"))
+				Expect(auditor.AuditCall.Receives.BundlerVersion).To(Equal("2.0.1"))
+
+				Expect(buffer.String()).To(ContainSubstring("Auditing Gemfile.lock against ruby-advisory-db"))
+				Expect(buffer.String()).To(ContainSubstring("No advisories found"))
+			})
+		})
+
+		context("when advisories are found", func() {
+			it.Before(func() {
+				auditor.AuditCall.Returns.AdvisorySlice = []bundler.Advisory{
+					{
+						ID:              "CVE-2023-1234",
+						Gem:             "rack",
+						Version:         "2.2.3",
+						Title:           "Possible DoS in multipart parsing",
+						URL:             "https://example.com/advisory",
+						Severity:        "medium",
+						PatchedVersions: []string{"~> 2.2.6.3", ">= 3.0.4.2"},
+					},
+					{
+						ID:       "GHSA-abcd",
+						Gem:      "bundler",
+						Version:  "2.0.1",
+						Severity: "high",
+					},
+				}
+			})
+
+			it("reports the advisories and continues the build", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("WARNING: Found 2 advisories:"))
+				Expect(buffer.String()).To(ContainSubstring("rack 2.2.3: CVE-2023-1234 (medium severity)"))
+				Expect(buffer.String()).To(ContainSubstring("Title: Possible DoS in multipart parsing"))
+				Expect(buffer.String()).To(ContainSubstring("Patched versions: ~> 2.2.6.3, >= 3.0.4.2"))
+				Expect(buffer.String()).To(ContainSubstring("bundler 2.0.1: GHSA-abcd (high severity)"))
+				Expect(buffer.String()).To(ContainSubstring("Patched versions: none"))
+			})
+
+			context("when $BP_BUNDLER_AUDIT is fail", func() {
+				it.Before(func() {
+					t.Setenv("BP_BUNDLER_AUDIT", "fail")
+				})
+
+				it("fails the build", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError("bundler audit failed: found 2 advisories"))
+				})
+
+				context("when $BP_BUNDLER_AUDIT_SEVERITY is set", func() {
+					it.Before(func() {
+						t.Setenv("BP_BUNDLER_AUDIT_SEVERITY", "high")
+					})
+
+					it("fails only on advisories at or above that severity", func() {
+						_, err := build(buildContext)
+						Expect(err).To(MatchError("bundler audit failed: found 1 advisories at or above high severity"))
+					})
+				})
+
+				context("when no advisory reaches $BP_BUNDLER_AUDIT_SEVERITY", func() {
+					it.Before(func() {
+						t.Setenv("BP_BUNDLER_AUDIT_SEVERITY", "critical")
+					})
+
+					it("continues the build", func() {
+						_, err := build(buildContext)
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+			})
+		})
+	})
+
+	context("when a license policy is set", func() {
+		it.Before(func() {
+			t.Setenv("BP_BUNDLER_ALLOWED_LICENSES", "MIT, Apache-2.0")
+			t.Setenv("BP_BUNDLER_DENIED_LICENSES", "GPL-3.0-only")
+
+			licenseChecker.CheckCall.Returns.LicenseResultSlice = []bundler.LicenseResult{
+				{Component: "bundler", Version: "2.0.1", Source: "buildpack.toml", Expression: "MIT", Permitted: true},
+				{Component: "rack", Version: "2.2.3", Source: "vendor/bundle/ruby/3.1.0/specifications/rack-2.2.3.gemspec", Expression: "MIT", Permitted: true},
+			}
+		})
+
+		it("checks the components against the policy", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(licenseChecker.CheckCall.Receives.WorkingDir).To(Equal("working-dir"))
+			Expect(licenseChecker.CheckCall.Receives.Dependency).To(Equal(postal.Dependency{
+				Name:    "Bundler",
+				Version: "2.0.1",
+			}))
+			Expect(licenseChecker.CheckCall.Receives.Policy).To(Equal(bundler.LicensePolicy{
+				Allowed: []string{"MIT", "Apache-2.0"},
+				Denied:  []string{"GPL-3.0-only"},
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("Checking license policy"))
+			Expect(buffer.String()).To(ContainSubstring("Allowed licenses: MIT, Apache-2.0"))
+			Expect(buffer.String()).To(ContainSubstring("Denied licenses: GPL-3.0-only"))
+			Expect(buffer.String()).To(ContainSubstring("PASS bundler 2.0.1: MIT (from buildpack.toml)"))
+			Expect(buffer.String()).To(ContainSubstring("PASS rack 2.2.3: MIT (from vendor/bundle/ruby/3.1.0/specifications/rack-2.2.3.gemspec)"))
+		})
+
+		context("when components violate the policy", func() {
+			it.Before(func() {
+				licenseChecker.CheckCall.Returns.LicenseResultSlice = append(licenseChecker.CheckCall.Returns.LicenseResultSlice,
+					bundler.LicenseResult{Component: "readline", Version: "0.0.4", Source: "vendor/cache/readline/readline.gemspec", Expression: "GPL-3.0-only"},
+					bundler.LicenseResult{Component: "mystery", Version: "1.0.0", Source: "vendor/cache/mystery/mystery.gemspec"},
+				)
+			})
+
+			it("names every offending component", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("license policy violated by 2 component(s): readline 0.0.4 (GPL-3.0-only), mystery 1.0.0 (unknown license)"))
+
+				Expect(buffer.String()).To(ContainSubstring("FAIL readline 0.0.4: GPL-3.0-only (from vendor/cache/readline/readline.gemspec)"))
+				Expect(buffer.String()).To(ContainSubstring("FAIL mystery 1.0.0: unknown license (from vendor/cache/mystery/mystery.gemspec)"))
+			})
+		})
+
+		context("when the license check fails", func() {
+			it.Before(func() {
+				licenseChecker.CheckCall.Returns.Error = errors.New("failed to check licenses")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to check licenses"))
+			})
+		})
+	})
+
+	context("when $BP_BUNDLER_VERIFY_CHECKSUMS is set", func() {
+		it.Before(func() {
+			t.Setenv("BP_BUNDLER_VERIFY_CHECKSUMS", "warn")
+
+			checksumVerifier.VerifyCall.Returns.ChecksumReport = bundler.ChecksumReport{
+				CacheFound: true,
+				Checksums:  2,
+				Verified:   []string{"rack-2.2.8.gem", "racc-1.7.3.gem"},
+			}
+		})
+
+		it("verifies the vendored gems", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(checksumVerifier.VerifyCall.Receives.WorkingDir).To(Equal("working-dir"))
+			Expect(checksumVerifier.VerifyCall.Receives.Target).To(Equal(buildContext.TargetInfo))
+
+			Expect(buffer.String()).To(ContainSubstring("Verifying vendored gems against Gemfile.lock CHECKSUMS"))
+			Expect(buffer.String()).To(ContainSubstring("Verified 2 vendored gem(s)"))
+		})
+
+		context("when vendored gems do not match", func() {
+			it.Before(func() {
+				checksumVerifier.VerifyCall.Returns.ChecksumReport = bundler.ChecksumReport{
+					CacheFound: true,
+					Checksums:  3,
+					Verified:   []string{"racc-1.7.3.gem"},
+					Missing:    []string{"json-2.7.1.gem"},
+					Extra:      []string{"evil-0.0.1.gem"},
+					Mismatched: []bundler.ChecksumMismatch{
+						{File: "rack-2.2.8.gem", Expected: "sha256:expected", Actual: "sha256:actual"},
+					},
+				}
+			})
+
+			it("reports every discrepancy", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("MISMATCH rack-2.2.8.gem: expected sha256:expected, got sha256:actual"))
+				Expect(buffer.String()).To(ContainSubstring("MISSING json-2.7.1.gem: listed in Gemfile.lock but not in vendor/cache"))
+				Expect(buffer.String()).To(ContainSubstring("EXTRA evil-0.0.1.gem: in vendor/cache but has no Gemfile.lock checksum"))
+			})
+
+			context("when $BP_BUNDLER_VERIFY_CHECKSUMS is fail", func() {
+				it.Before(func() {
+					t.Setenv("BP_BUNDLER_VERIFY_CHECKSUMS", "fail")
+				})
+
+				it("fails the build", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError("checksum verification failed: 1 mismatched, 1 missing, 1 extra"))
+				})
+			})
+		})
+
+		context("when the Gemfile.lock has no checksums", func() {
+			it.Before(func() {
+				checksumVerifier.VerifyCall.Returns.ChecksumReport = bundler.ChecksumReport{CacheFound: true}
+			})
+
+			it("warns that nothing can be verified", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("WARNING: Gemfile.lock has no CHECKSUMS section, vendored gems cannot be verified"))
+				Expect(buffer.String()).To(ContainSubstring("Run 'bundle lock --add-checksums'"))
+			})
+
+			context("when $BP_BUNDLER_VERIFY_CHECKSUMS is fail", func() {
+				it.Before(func() {
+					t.Setenv("BP_BUNDLER_VERIFY_CHECKSUMS", "fail")
+				})
+
+				it("fails the build", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError("checksum verification failed: Gemfile.lock has no CHECKSUMS section"))
+				})
+			})
+		})
+
+		context("when the application does not vendor its gems", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_VERIFY_CHECKSUMS", "fail")
+				checksumVerifier.VerifyCall.Returns.ChecksumReport = bundler.ChecksumReport{Checksums: 3}
+			})
+
+			it("skips the check", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("vendor/cache does not exist, there are no vendored gems to verify"))
+			})
+		})
+
+		context("when the verification fails", func() {
+			it.Before(func() {
+				checksumVerifier.VerifyCall.Returns.Error = errors.New("failed to verify")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to verify"))
+			})
+		})
+
+		context("when $BP_BUNDLER_VERIFY_CHECKSUMS is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_VERIFY_CHECKSUMS", "strict")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`invalid value for BP_BUNDLER_VERIFY_CHECKSUMS: "strict" (must be "warn" or "fail")`))
+			})
+		})
+	})
+
+	context("when $BP_BUNDLER_OFFLINE_CHECK is set", func() {
+		it.Before(func() {
+			t.Setenv("BP_BUNDLER_OFFLINE_CHECK", "warn")
+
+			offlineChecker.CheckCall.Returns.OfflineReport = bundler.OfflineReport{LockfileFound: true, CacheFound: true}
+		})
+
+		it("checks vendor/cache", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(offlineChecker.CheckCall.Receives.WorkingDir).To(Equal("working-dir"))
+			Expect(offlineChecker.CheckCall.Receives.Target).To(Equal(buildContext.TargetInfo))
+
+			Expect(buffer.String()).To(ContainSubstring("Checking vendor/cache for offline installation"))
+			Expect(buffer.String()).To(ContainSubstring("vendor/cache satisfies Gemfile.lock"))
+		})
+
+		context("when vendor/cache is incomplete", func() {
+			it.Before(func() {
+				offlineChecker.CheckCall.Returns.OfflineReport = bundler.OfflineReport{
+					LockfileFound: true,
+					CacheFound:    true,
+					Target:        "linux/arm64",
+					Missing:       []string{"nokogiri-1.16.0-aarch64-linux.gem", "rack-2.2.8.gem"},
+					Unsatisfiable: []bundler.UnsatisfiableSource{
+						{Type: "GIT", Remote: "https://github.com/example/tools.git", Reason: "revision abc is not cached in vendor/cache"},
+						{Type: "PATH", Remote: "../shared", Reason: "path is outside of the application directory"},
+					},
+				}
+			})
+
+			it("prints an actionable list", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("WARNING: An offline 'bundle install' will fail on linux/arm64:"))
+				Expect(buffer.String()).To(ContainSubstring("Missing nokogiri-1.16.0-aarch64-linux.gem"))
+				Expect(buffer.String()).To(ContainSubstring("Missing rack-2.2.8.gem"))
+				Expect(buffer.String()).To(ContainSubstring("GIT source https://github.com/example/tools.git: revision abc is not cached in vendor/cache"))
+				Expect(buffer.String()).To(ContainSubstring("PATH source ../shared: path is outside of the application directory"))
+				Expect(buffer.String()).To(ContainSubstring("Run 'bundle cache --all-platforms' and commit vendor/cache"))
+				Expect(buffer.String()).To(ContainSubstring("Run 'bundle cache --all' so that GIT sources are included in vendor/cache"))
+				Expect(buffer.String()).To(ContainSubstring("Move PATH gems into the application directory or publish them to a gem server"))
+			})
+
+			context("when $BP_BUNDLER_OFFLINE_CHECK is fail", func() {
+				it.Before(func() {
+					t.Setenv("BP_BUNDLER_OFFLINE_CHECK", "fail")
+				})
+
+				it("fails the build", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError("offline readiness check failed: 2 missing gem(s), 2 unsatisfiable source(s)"))
+				})
+			})
+		})
+
+		context("when vendor/cache does not exist", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_OFFLINE_CHECK", "fail")
+				offlineChecker.CheckCall.Returns.OfflineReport = bundler.OfflineReport{LockfileFound: true}
+			})
+
+			it("fails the build", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("offline readiness check failed: vendor/cache does not exist"))
+
+				Expect(buffer.String()).To(ContainSubstring("vendor/cache does not exist"))
+				Expect(buffer.String()).To(ContainSubstring("Run 'bundle cache --all-platforms' and commit vendor/cache"))
+			})
+		})
+
+		context("when the application has no Gemfile.lock", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_OFFLINE_CHECK", "fail")
+				offlineChecker.CheckCall.Returns.OfflineReport = bundler.OfflineReport{}
+			})
+
+			it("skips the check", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Gemfile.lock does not exist, there are no locked gems to check"))
+			})
+		})
+
+		context("when the check fails", func() {
+			it.Before(func() {
+				offlineChecker.CheckCall.Returns.Error = errors.New("failed to check vendor/cache")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to check vendor/cache"))
+			})
+		})
+	})
+
+	context("when $BP_BUNDLER_VERIFY_PROVENANCE is set", func() {
+		it.Before(func() {
+			t.Setenv("BP_BUNDLER_VERIFY_PROVENANCE", "warn")
+
+			dependencyManager.ResolveCall.Returns.Dependency = postal.Dependency{
+				Name:           "Bundler",
+				Version:        "2.0.1",
+				Checksum:       "sha256:artifact",
+				URI:            "https://artifacts.example.com/bundler-2.0.1.tgz",
+				Source:         "https://rubygems.org/downloads/bundler-2.0.1.gem",
+				SourceChecksum: "sha256:source",
+			}
+
+			provenance.VerifyCall.Returns.ProvenanceReport = bundler.ProvenanceReport{
+				URI:     "https://artifacts.example.com/bundler-2.0.1.tgz.intoto.tar.gz",
+				Builder: "https://github.com/some-org/bundler/.github/workflows/compile.yml@refs/heads/main",
+			}
+		})
+
+		it("verifies the provenance of the delivered dependency", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(provenance.VerifyCall.Receives.Dependency.Version).To(Equal("2.0.1"))
+			Expect(provenance.VerifyCall.Receives.CnbPath).To(Equal(cnbDir))
+			Expect(provenance.VerifyCall.Receives.PlatformPath).To(Equal("platform"))
+			Expect(provenance.VerifyCall.Receives.Stack).To(Equal("some-stack"))
+
+			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+			Expect(buffer.String()).To(MatchRegexp(`(?s)Installing Bundler 2\.0\.1.*Verifying provenance of Bundler 2\.0\.1`))
+			Expect(buffer.String()).To(ContainSubstring("Statement: https://artifacts.example.com/bundler-2.0.1.tgz.intoto.tar.gz"))
+			Expect(buffer.String()).To(ContainSubstring("Builder: https://github.com/some-org/bundler/.github/workflows/compile.yml@refs/heads/main"))
+			Expect(buffer.String()).To(ContainSubstring("Artifact sha256:artifact was built from https://rubygems.org/downloads/bundler-2.0.1.gem (sha256:source)"))
+		})
+
+		context("when the statement does not match the dependency", func() {
+			it.Before(func() {
+				provenance.VerifyCall.Returns.ProvenanceReport = bundler.ProvenanceReport{
+					URI: "https://artifacts.example.com/bundler-2.0.1.tgz.intoto.tar.gz",
+					Problems: []string{
+						"no subject has the checksum of the dependency sha256:artifact",
+						"source https://rubygems.org/downloads/bundler-2.0.1.gem is not among the resolved dependencies",
+					},
+				}
+			})
+
+			it("reports every problem", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("WARNING: The provenance statement does not vouch for this dependency:"))
+				Expect(buffer.String()).To(ContainSubstring("no subject has the checksum of the dependency sha256:artifact"))
+				Expect(buffer.String()).To(ContainSubstring("source https://rubygems.org/downloads/bundler-2.0.1.gem is not among the resolved dependencies"))
+				Expect(sbomGenerator.GenerateFromDependencyCall.CallCount).To(Equal(1))
+			})
+
+			context("when $BP_BUNDLER_VERIFY_PROVENANCE is fail", func() {
+				it.Before(func() {
+					t.Setenv("BP_BUNDLER_VERIFY_PROVENANCE", "fail")
+				})
+
+				it("fails the build", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError("provenance verification failed: no subject has the checksum of the dependency sha256:artifact; source https://rubygems.org/downloads/bundler-2.0.1.gem is not among the resolved dependencies"))
+
+					Expect(sbomGenerator.GenerateFromDependencyCall.CallCount).To(Equal(0))
+				})
+			})
+		})
+
+		context("when the verification fails", func() {
+			it.Before(func() {
+				provenance.VerifyCall.Returns.Error = errors.New("failed to read provenance statement")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to read provenance statement"))
+			})
+		})
+
+		context("when $BP_BUNDLER_VERIFY_PROVENANCE is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_VERIFY_PROVENANCE", "strict")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`invalid value for BP_BUNDLER_VERIFY_PROVENANCE: "strict" (must be "warn" or "fail")`))
+			})
+		})
+	})
+
+	context("when the selected Bundler does not support the application's Ruby", func() {
+		it.Before(func() {
+			compatibility.CheckCall.Returns.RubyCompatibility = bundler.RubyCompatibility{
+				Ruby:           bundler.RubyVersion{Version: "3.1.4", Source: "Gemfile.lock"},
+				DefaultVersion: "2.x.x",
+				Versions:       []string{"1.17.3", "2.0.0", "2.0.1", "2.0.2", "2.1.0", "2.2.0"},
+				Incompatible: []bundler.IncompatibleBundler{
+					{Version: "2.0.1", RequiredRubyVersion: ">= 3.2.0"},
+					{Version: "2.0.2", RequiredRubyVersion: ">= 3.2.0"},
+					{Version: "2.2.0", RequiredRubyVersion: ">= 3.2.0"},
+				},
+			}
+
+			dependencyManager.ResolveCall.Stub = func(path, id, version, stack string) (postal.Dependency, error) {
+				if version == "2.0.x" {
+					return postal.Dependency{Name: "Bundler", Version: "2.0.1"}, nil
+				}
+				return postal.Dependency{Name: "Bundler", Version: version}, nil
+			}
+		})
+
+		it("resolves the newest compatible version that satisfies the constraint", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencyManager.ResolveCall.CallCount).To(Equal(2))
+			Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.0.0"))
+			Expect(dependencyManager.DeliverCall.Receives.Dependency.Version).To(Equal("2.0.0"))
+
+			Expect(buffer.String()).To(ContainSubstring("Skipping Bundler versions that do not support Ruby 3.1.4 (from Gemfile.lock):"))
+			Expect(buffer.String()).To(ContainSubstring("2.0.1 (requires Ruby >= 3.2.0)"))
+		})
+
+		context("when the version uses the pessimistic operator", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata["version"] = "~> 2.0"
+				dependencyManager.ResolveCall.Stub = func(path, id, version, stack string) (postal.Dependency, error) {
+					if version == "~> 2.0" {
+						return postal.Dependency{Name: "Bundler", Version: "2.2.0"}, nil
+					}
+					return postal.Dependency{Name: "Bundler", Version: version}, nil
+				}
+			})
+
+			it("expands the operator before matching versions", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.1.0"))
+			})
+		})
+
+		context("when the constraint has alternatives", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata["version"] = "1.17.x || 2.0.x"
+				dependencyManager.ResolveCall.Stub = func(path, id, version, stack string) (postal.Dependency, error) {
+					if version == "1.17.x || 2.0.x" {
+						return postal.Dependency{Name: "Bundler", Version: "2.0.2"}, nil
+					}
+					return postal.Dependency{Name: "Bundler", Version: version}, nil
+				}
+			})
+
+			it("matches every alternative", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.0.0"))
+			})
+		})
+
+		context("when no version is requested", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata["version"] = ""
+				dependencyManager.ResolveCall.Stub = func(path, id, version, stack string) (postal.Dependency, error) {
+					if version == "" {
+						return postal.Dependency{Name: "Bundler", Version: "2.2.0"}, nil
+					}
+					return postal.Dependency{Name: "Bundler", Version: version}, nil
+				}
+			})
+
+			it("matches versions against the default version", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.1.0"))
+			})
+		})
+
+		context("when the newest compatible version is not available for the stack", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata["version"] = "2.*.*"
+				dependencyManager.ResolveCall.Stub = func(path, id, version, stack string) (postal.Dependency, error) {
+					switch version {
+					case "2.*.*":
+						return postal.Dependency{Name: "Bundler", Version: "2.2.0"}, nil
+					case "2.1.0":
+						return postal.Dependency{}, &postal.ErrNoDeps{}
+					}
+					return postal.Dependency{Name: "Bundler", Version: version}, nil
+				}
+			})
+
+			it("tries the next one", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.CallCount).To(Equal(3))
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.0.0"))
+			})
+		})
+
+		context("when no compatible Bundler exists", func() {
+			it.Before(func() {
+				compatibility.CheckCall.Returns.RubyCompatibility.Versions = []string{"2.0.1", "2.0.2"}
+				compatibility.CheckCall.Returns.RubyCompatibility.Incompatible = []bundler.IncompatibleBundler{
+					{Version: "2.0.1", RequiredRubyVersion: ">= 3.2.0"},
+					{Version: "2.0.2", RequiredRubyVersion: ">= 3.2.0"},
+				}
+			})
+
+			it("fails with the Ruby requirements", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`no Bundler version matching "2.0.x" supports Ruby 3.1.4 (from Gemfile.lock): incompatible versions are 2.0.1 (requires Ruby >= 3.2.0), 2.0.2 (requires Ruby >= 3.2.0); upgrade Ruby or set $BP_BUNDLER_VERSION to a compatible version`))
+				Expect(dependencyManager.ResolveCall.CallCount).To(Equal(1))
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
+			})
+		})
+	})
+
+	context("when the selected Bundler is past its end of life", func() {
+		it.Before(func() {
+			dependencyManager.ResolveCall.Returns.Dependency = postal.Dependency{
+				Name:            "Bundler",
+				Version:         "2.0.1",
+				DeprecationDate: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			}
+		})
+
+		it("warns that the version is deprecated", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buffer.String()).To(ContainSubstring("Version 2.0.1 of Bundler is deprecated."))
+		})
+
+		context("when $BP_BUNDLER_FAIL_ON_EOL is true", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_FAIL_ON_EOL", "true")
+			})
+
+			it("fails the build", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("bundler 2.0.1 reached end of life on 2020-01-01: upgrade to a supported version or unset $BP_BUNDLER_FAIL_ON_EOL"))
+			})
+
+			context("when the end of life is still ahead", func() {
+				it.Before(func() {
+					dependencyManager.ResolveCall.Returns.Dependency.DeprecationDate = time.Now().Add(10 * 24 * time.Hour)
+				})
+
+				it("only warns", func() {
+					_, err := build(buildContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).To(ContainSubstring("Version 2.0.1 of Bundler will be deprecated after"))
+				})
+			})
+		})
+
+		context("when $BP_BUNDLER_FAIL_ON_EOL is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_FAIL_ON_EOL", "often")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`invalid value for BP_BUNDLER_FAIL_ON_EOL: "often" (must be "true" or "false")`))
+			})
+		})
+	})
+
+	context("when buildpack.toml marks the selected Bundler as yanked", func() {
+		it.Before(func() {
+			yankedChecker.CheckCall.Returns.Bool = true
+		})
+
+		it("does not check by default", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(yankedChecker.CheckCall.CallCount).To(Equal(0))
+			Expect(buffer.String()).NotTo(ContainSubstring("yanked"))
+		})
+
+		context("when $BP_BUNDLER_YANKED_CHECK is warn", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_YANKED_CHECK", "warn")
+			})
+
+			it("warns and continues the build", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(yankedChecker.CheckCall.Receives.BuildpackTOMLPath).To(Equal(filepath.Join(cnbDir, "buildpack.toml")))
+				Expect(yankedChecker.CheckCall.Receives.Dependency).To(Equal(postal.Dependency{Name: "Bundler", Version: "2.0.1"}))
+
+				Expect(buffer.String()).To(ContainSubstring("WARNING: Bundler 2.0.1 has been yanked from RubyGems."))
+				Expect(buffer.String()).To(ContainSubstring("Select another version through the $BP_BUNDLER_VERSION environment variable."))
+			})
+		})
+
+		context("when $BP_BUNDLER_YANKED_CHECK is fail", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_YANKED_CHECK", "fail")
+			})
+
+			it("fails the build", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("bundler 2.0.1 has been yanked from RubyGems: select another version through $BP_BUNDLER_VERSION or unset $BP_BUNDLER_YANKED_CHECK"))
+			})
+
+			context("when the selected version is not yanked", func() {
+				it.Before(func() {
+					yankedChecker.CheckCall.Returns.Bool = false
+				})
+
+				it("builds", func() {
+					_, err := build(buildContext)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			context("when the yanked versions cannot be read", func() {
+				it.Before(func() {
+					yankedChecker.CheckCall.Returns.Error = errors.New("failed to parse buildpack.toml")
+				})
+
+				it("returns an error", func() {
+					_, err := build(buildContext)
+					Expect(err).To(MatchError("failed to parse buildpack.toml"))
+				})
+			})
+		})
+
+		context("when $BP_BUNDLER_YANKED_CHECK is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_YANKED_CHECK", "always")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`invalid value for BP_BUNDLER_YANKED_CHECK: "always" (must be "warn" or "fail")`))
+			})
+		})
+	})
+
+	context("when the requested Bundler major is not supported", func() {
+		it.Before(func() {
+			buildContext.Plan.Entries[0].Metadata["version-source"] = "Gemfile.lock"
+			buildContext.Plan.Entries[0].Metadata["version"] = "1.*.*"
+
+			compatibility.CheckCall.Returns.RubyCompatibility = bundler.RubyCompatibility{
+				Versions: []string{"2.7.1", "2.7.2", "4.0.17", "4.0.18"},
+			}
+
+			dependencyManager.ResolveCall.Stub = func(path, id, version, stack string) (postal.Dependency, error) {
+				if version == "1.*.*" {
+					return postal.Dependency{}, &postal.ErrNoDeps{}
+				}
+				return postal.Dependency{Name: "Bundler", Version: "2.7.2"}, nil
+			}
+		})
+
+		it("explains how to upgrade the lockfile", func() {
+			_, err := build(buildContext)
+			Expect(err).To(MatchError(`unsupported Bundler major version 1 (requested "1.*.*" via Gemfile.lock): supported major versions are 2, 4. ` +
+				`Upgrade the lockfile by running 'bundle update --bundler' with Bundler 2 and commit Gemfile.lock, ` +
+				`or set $BP_BUNDLER_ALLOW_MAJOR_FALLBACK=true to install Bundler 2 instead`))
+			Expect(dependencyManager.ResolveCall.CallCount).To(Equal(1))
+		})
+
+		context("when $BP_BUNDLER_ALLOW_MAJOR_FALLBACK is true", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_ALLOW_MAJOR_FALLBACK", "true")
+			})
+
+			it("installs the nearest supported major with a warning", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.CallCount).To(Equal(2))
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.*.*"))
+				Expect(dependencyManager.DeliverCall.Receives.Dependency.Version).To(Equal("2.7.2"))
+
+				Expect(buffer.String()).To(ContainSubstring(`WARNING: Bundler 1 (requested "1.*.*" via Gemfile.lock) is not supported by this buildpack.`))
+				Expect(buffer.String()).To(ContainSubstring("WARNING: Falling back to Bundler 2 because $BP_BUNDLER_ALLOW_MAJOR_FALLBACK is set."))
+			})
+
+			context("when there is no newer major", func() {
+				it.Before(func() {
+					buildContext.Plan.Entries[0].Metadata["version"] = "5.*.*"
+					dependencyManager.ResolveCall.Stub = func(path, id, version, stack string) (postal.Dependency, error) {
+						if version == "5.*.*" {
+							return postal.Dependency{}, &postal.ErrNoDeps{}
+						}
+						return postal.Dependency{Name: "Bundler", Version: "4.0.18"}, nil
+					}
+				})
+
+				it("falls back to the newest older major", func() {
+					_, err := build(buildContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("4.*.*"))
+				})
+			})
+		})
 
-		Expect(layer.SBOM.Formats()).To(HaveLen(2))
+		context("when $BP_BUNDLER_ALLOW_MAJOR_FALLBACK is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_ALLOW_MAJOR_FALLBACK", "maybe")
+			})
 
-		cdx := layer.SBOM.Formats()[0]
-		Expect(cdx.Extension).To(Equal("cdx.json"))
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`invalid value for BP_BUNDLER_ALLOW_MAJOR_FALLBACK: "maybe" (must be "true" or "false")`))
+			})
+		})
 
-		content, err := io.ReadAll(cdx.Content)
-		Expect(err).NotTo(HaveOccurred())
-		Expect(string(content)).To(MatchJSON(`{
-			"$schema": "http://cyclonedx.org/schema/bom-1.3.schema.json",
-			"bomFormat": "CycloneDX",
-			"metadata": {
-				"tools": [
-					{
-						"name": "",
-						"vendor": "anchore"
-					}
-				]
-			},
-			"specVersion": "1.3",
-			"version": 1
-		}`))
+		context("when the major is supported but the version is not", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata["version"] = "2.1.4"
+				dependencyManager.ResolveCall.Stub = nil
+				dependencyManager.ResolveCall.Returns.Error = &postal.ErrNoDeps{}
+			})
 
-		spdx := layer.SBOM.Formats()[1]
-		Expect(spdx.Extension).To(Equal("spdx.json"))
+			it("returns the resolution error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(&postal.ErrNoDeps{}))
+			})
+		})
+	})
 
-		content, err = io.ReadAll(spdx.Content)
-		Expect(err).NotTo(HaveOccurred())
+	context("when Gemfile.lock PLATFORMS does not cover the build target", func() {
+		it.Before(func() {
+			buildContext.TargetInfo = packit.TargetInfo{OS: "linux", Arch: "amd64"}
+			buildContext.TargetDistro = packit.TargetDistro{Name: "ubuntu", Version: "22.04"}
 
-		var spdxDocument map[string]interface{}
-		Expect(json.Unmarshal(content, &spdxDocument)).To(Succeed())
-		Expect(spdxDocument).To(HaveKeyWithValue("SPDXID", "SPDXRef-DOCUMENT"))
-		Expect(spdxDocument).To(HaveKeyWithValue("dataLicense", "CC0-1.0"))
-		Expect(spdxDocument).To(HaveKeyWithValue("name", "unknown"))
-		Expect(spdxDocument).To(HaveKeyWithValue("spdxVersion", "SPDX-2.2"))
-		Expect(spdxDocument).To(HaveKeyWithValue("documentNamespace", MatchRegexp(`^https://paketo\.io/unknown-source-type/unknown-[0-9a-f-]+$`)))
+			platformChecker.CheckCall.Returns.PlatformReport = bundler.PlatformReport{
+				Target:     "linux/amd64",
+				Candidates: []string{"x86_64-linux", "x86_64-linux-gnu"},
+				Platforms:  []string{"arm64-darwin"},
+			}
+		})
 
-		Expect(spdxDocument).To(HaveKey("creationInfo"))
-		creationInfo, ok := spdxDocument["creationInfo"].(map[string]interface{})
-		Expect(ok).To(BeTrue())
-		Expect(creationInfo).To(HaveKeyWithValue("created", "0001-01-01T00:00:00Z"))
-		Expect(creationInfo).To(HaveKeyWithValue("creators", ContainElements("Organization: Anchore, Inc", "Tool: -")))
-		Expect(creationInfo).To(HaveKeyWithValue("licenseListVersion", MatchRegexp(`^\d+\.\d+$`)))
+		it("warns with the command that adds the platform", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
 
-		Expect(spdxDocument).To(HaveKeyWithValue("packages", HaveLen(1)))
-		packages, ok := spdxDocument["packages"].([]interface{})
-		Expect(ok).To(BeTrue())
-		pkg, ok := packages[0].(map[string]interface{})
-		Expect(ok).To(BeTrue())
-		Expect(pkg).To(HaveKeyWithValue("SPDXID", "SPDXRef-DocumentRoot-Unknown-"))
-		Expect(pkg).To(HaveKeyWithValue("copyrightText", "NOASSERTION"))
-		Expect(pkg).To(HaveKeyWithValue("downloadLocation", "NOASSERTION"))
-		Expect(pkg).To(HaveKeyWithValue("filesAnalyzed", BeFalse()))
-		Expect(pkg).To(HaveKeyWithValue("licenseConcluded", "NOASSERTION"))
-		Expect(pkg).To(HaveKeyWithValue("licenseDeclared", "NOASSERTION"))
-		Expect(pkg).To(HaveKeyWithValue("name", ""))
-		Expect(pkg).To(HaveKeyWithValue("supplier", "NOASSERTION"))
+			Expect(platformChecker.CheckCall.Receives.WorkingDir).To(Equal("working-dir"))
+			Expect(platformChecker.CheckCall.Receives.Target).To(Equal(packit.TargetInfo{OS: "linux", Arch: "amd64"}))
+			Expect(platformChecker.CheckCall.Receives.Distro).To(Equal(packit.TargetDistro{Name: "ubuntu", Version: "22.04"}))
 
-		Expect(spdxDocument).To(HaveKeyWithValue("relationships", HaveLen(1)))
-		relationships, ok := spdxDocument["relationships"].([]interface{})
-		Expect(ok).To(BeTrue())
-		relationship, ok := relationships[0].(map[string]interface{})
-		Expect(ok).To(BeTrue())
-		Expect(relationship).To(HaveKeyWithValue("relatedSpdxElement", "SPDXRef-DocumentRoot-Unknown-"))
-		Expect(relationship).To(HaveKeyWithValue("relationshipType", "DESCRIBES"))
-		Expect(relationship).To(HaveKeyWithValue("spdxElementId", "SPDXRef-DOCUMENT"))
-		Expect(filepath.Join(layersDir, "bundler")).To(BeADirectory())
+			Expect(buffer.String()).To(ContainSubstring("Checking Gemfile.lock platforms"))
+			Expect(buffer.String()).To(ContainSubstring("Build target: linux/amd64 (x86_64-linux, x86_64-linux-gnu)"))
+			Expect(buffer.String()).To(ContainSubstring("Locked platforms: arm64-darwin"))
+			Expect(buffer.String()).To(ContainSubstring("WARNING: Gemfile.lock cannot be resolved for the build target."))
+			Expect(buffer.String()).To(ContainSubstring("bundle lock --add-platform x86_64-linux"))
+		})
 
-		Expect(dependencyManager.ResolveCall.Receives.Path).To(Equal(filepath.Join(cnbDir, "buildpack.toml")))
-		Expect(dependencyManager.ResolveCall.Receives.Id).To(Equal("bundler"))
-		Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.0.x"))
-		Expect(dependencyManager.ResolveCall.Receives.Stack).To(Equal("some-stack"))
+		context("when $BP_BUNDLER_PLATFORM_CHECK is fail", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_PLATFORM_CHECK", "fail")
+			})
 
-		Expect(dependencyManager.GenerateBillOfMaterialsCall.Receives.Dependencies).To(Equal([]postal.Dependency{
-			{
-				Name:    "Bundler",
-				Version: "2.0.1",
-			},
-		}))
+			it("fails the build", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("platform check failed: Gemfile.lock PLATFORMS (arm64-darwin) does not include x86_64-linux"))
+			})
+		})
 
-		Expect(dependencyManager.DeliverCall.Receives.Dependency).To(Equal(postal.Dependency{
-			Name:    "Bundler",
-			Version: "2.0.1",
-		}))
-		Expect(dependencyManager.DeliverCall.Receives.CnbPath).To(Equal(cnbDir))
-		Expect(dependencyManager.DeliverCall.Receives.LayerPath).To(Equal(filepath.Join(layersDir, "bundler")))
-		Expect(dependencyManager.DeliverCall.Receives.PlatformPath).To(Equal("platform"))
+		context("when the generic ruby platform is locked", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_PLATFORM_CHECK", "fail")
 
-		Expect(versionShimmer.ShimCall.Receives.Path).To(Equal(filepath.Join(layersDir, "bundler", "bin")))
-		Expect(versionShimmer.ShimCall.Receives.Version).To(Equal("2.0.1"))
+				platformChecker.CheckCall.Returns.PlatformReport.Platforms = []string{"arm64-darwin", "ruby"}
+				platformChecker.CheckCall.Returns.PlatformReport.Generic = true
+			})
 
-		Expect(sbomGenerator.GenerateFromDependencyCall.Receives.Dependency).To(Equal(postal.Dependency{
-			Name:    "Bundler",
-			Version: "2.0.1",
-		}))
-		Expect(sbomGenerator.GenerateFromDependencyCall.Receives.Dir).To(Equal(filepath.Join(layersDir, "bundler")))
+			it("warns that native gems will be compiled", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
 
-		Expect(buffer.String()).To(ContainSubstring("Some Buildpack some-version"))
-		Expect(buffer.String()).To(ContainSubstring("Resolving Bundler version"))
-		Expect(buffer.String()).To(ContainSubstring("Selected Bundler version (using BP_BUNDLER_VERSION): "))
-		Expect(buffer.String()).NotTo(ContainSubstring("WARNING: Setting the Bundler version through buildpack.yml will be deprecated soon in Bundler Buildpack v2.0.0."))
-		Expect(buffer.String()).NotTo(ContainSubstring("Please specify the version through the $BP_BUNDLER_VERSION environment variable instead. See README.md for more information."))
-		Expect(buffer.String()).To(ContainSubstring("Executing build process"))
-		Expect(buffer.String()).To(ContainSubstring("Configuring build environment"))
-		Expect(buffer.String()).To(ContainSubstring("Configuring launch environment"))
+				Expect(buffer.String()).To(ContainSubstring("WARNING: Gemfile.lock only covers the generic 'ruby' platform for this target,"))
+				Expect(buffer.String()).To(ContainSubstring("bundle lock --add-platform x86_64-linux"))
+			})
+		})
+
+		context("when $BP_BUNDLER_PLATFORM_CHECK is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_PLATFORM_CHECK", "strict")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`invalid value for BP_BUNDLER_PLATFORM_CHECK: "strict" (must be "warn" or "fail")`))
+			})
+		})
+
+		context("when the check fails", func() {
+			it.Before(func() {
+				platformChecker.CheckCall.Returns.Error = errors.New("failed to check platforms")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to check platforms"))
+			})
+		})
 	})
 
 	context("when the build plan entry includes the build flag", func() {
@@ -310,6 +1250,58 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					},
 				},
 			))
+
+			Expect(result.Launch.Labels).To(Equal(map[string]string{
+				"io.paketo.bundler.version":        "2.0.1",
+				"io.paketo.bundler.version-source": "BP_BUNDLER_VERSION",
+			}))
+		})
+
+		context("when the dependency has a checksum", func() {
+			it.Before(func() {
+				dependencyManager.ResolveCall.Returns.Dependency.Checksum = "sha256:some-sha"
+			})
+
+			it("labels the image with the checksum", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Labels).To(HaveKeyWithValue("io.paketo.bundler.checksum", "sha256:some-sha"))
+			})
+		})
+
+		context("when the version is not requested", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata["version-source"] = ""
+				buildContext.Plan.Entries[0].Metadata["version"] = ""
+			})
+
+			it("labels the version source as the default", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Labels).To(HaveKeyWithValue("io.paketo.bundler.version-source", "default"))
+			})
+		})
+
+		context("when $BP_BUNDLER_LABEL_PREFIXES is set", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_LABEL_PREFIXES", "com.example.ruby, org.acme.tools., io.paketo.bundler")
+			})
+
+			it("adds the labels under every prefix", func() {
+				result, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Labels).To(Equal(map[string]string{
+					"io.paketo.bundler.version":        "2.0.1",
+					"io.paketo.bundler.version-source": "BP_BUNDLER_VERSION",
+					"com.example.ruby.version":         "2.0.1",
+					"com.example.ruby.version-source":  "BP_BUNDLER_VERSION",
+					"org.acme.tools.version":           "2.0.1",
+					"org.acme.tools.version-source":    "BP_BUNDLER_VERSION",
+				}))
+			})
 		})
 	})
 
@@ -318,6 +1310,10 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			err := os.WriteFile(filepath.Join(layersDir, "bundler.toml"), []byte("[metadata]\ndependency-sha = \"some-sha\"\n"), 0600)
 			Expect(err).NotTo(HaveOccurred())
 
+			Expect(os.MkdirAll(filepath.Join(layersDir, "bundler"), os.ModePerm)).To(Succeed())
+			err = os.WriteFile(filepath.Join(layersDir, "bundler", "resolution-report.json"), []byte(`{"reused": false, "install_ms": 1234}`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
 			dependencyManager.ResolveCall.Returns.Dependency = postal.Dependency{
 				Name:     "Bundler",
 				Checksum: "sha256:some-sha",
@@ -341,6 +1337,40 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			Expect(buffer.String()).To(ContainSubstring("Reusing cached layer"))
 			Expect(buffer.String()).ToNot(ContainSubstring("Executing build process"))
 		})
+
+		context("when $BP_BUNDLER_VERIFY_PROVENANCE is set", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_VERIFY_PROVENANCE", "fail")
+			})
+
+			it("does not verify the provenance of the reused layer", func() {
+				_, err := build(buildContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(provenance.VerifyCall.CallCount).To(Equal(0))
+			})
+		})
+
+		it("reports that the layer was reused and refreshes the report file", func() {
+			result, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			layer := result.Layers[0]
+			Expect(layer.Metadata).To(HaveKeyWithValue("dependency-sha", "some-sha"))
+
+			report, ok := layer.Metadata["resolution-report"].(bundler.BuildReport)
+			Expect(ok).To(BeTrue())
+			Expect(report.Reused).To(BeTrue())
+			Expect(report.InstallMillis).To(BeZero())
+			Expect(report.Dependency.Checksum).To(Equal("sha256:some-sha"))
+
+			content, err := os.ReadFile(filepath.Join(layersDir, "bundler", "resolution-report.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var written bundler.BuildReport
+			Expect(json.Unmarshal(content, &written)).To(Succeed())
+			Expect(written).To(Equal(report))
+		})
 	})
 
 	context("when the build plan entry version source is from buildpack.yml", func() {
@@ -402,6 +1432,55 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 	})
 
+	context("when $BP_BUNDLER_DISABLE_BUILDPACK_YML is true", func() {
+		it.Before(func() {
+			t.Setenv("BP_BUNDLER_DISABLE_BUILDPACK_YML", "true")
+		})
+
+		it("builds when buildpack.yml does not configure Bundler", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configParser.ParseConfigCall.Receives.Path).To(Equal(filepath.Join("working-dir", "buildpack.yml")))
+		})
+
+		it("fails with the $BP_BUNDLER_VERSION to use instead", func() {
+			configParser.ParseConfigCall.Returns.Config = bundler.Config{Version: "2.1.4"}
+			configParser.ParseConfigCall.Returns.Ok = true
+
+			_, err := build(buildContext)
+			Expect(err).To(MatchError(`buildpack.yml is disabled by $BP_BUNDLER_DISABLE_BUILDPACK_YML: remove the bundler key from buildpack.yml and set $BP_BUNDLER_VERSION="2.1.4" instead`))
+			Expect(dependencyManager.ResolveCall.CallCount).To(Equal(0))
+		})
+
+		it("fails when the bundler key has no version", func() {
+			configParser.ParseConfigCall.Returns.Ok = true
+
+			_, err := build(buildContext)
+			Expect(err).To(MatchError("buildpack.yml is disabled by $BP_BUNDLER_DISABLE_BUILDPACK_YML: remove the bundler key from buildpack.yml"))
+		})
+
+		context("when buildpack.yml cannot be parsed", func() {
+			it.Before(func() {
+				configParser.ParseConfigCall.Returns.Err = errors.New("failed to parse buildpack.yml")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to parse buildpack.yml"))
+			})
+		})
+	})
+
+	context("when $BP_BUNDLER_DISABLE_BUILDPACK_YML is not set", func() {
+		it("does not read buildpack.yml", func() {
+			_, err := build(buildContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configParser.ParseConfigCall.CallCount).To(Equal(0))
+		})
+	})
+
 	context("failure cases", func() {
 		context("when a dependency cannot be resolved", func() {
 			it.Before(func() {
@@ -414,6 +1493,52 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when the Ruby compatibility check fails", func() {
+			it.Before(func() {
+				compatibility.CheckCall.Returns.Error = errors.New("failed to parse buildpack.toml")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to parse buildpack.toml"))
+			})
+		})
+
+		context("when $BP_BUNDLER_AUDIT is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_AUDIT", "sometimes")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(`invalid value for BP_BUNDLER_AUDIT: "sometimes" (must be "warn" or "fail")`))
+			})
+		})
+
+		context("when $BP_BUNDLER_AUDIT_SEVERITY is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_AUDIT", "fail")
+				t.Setenv("BP_BUNDLER_AUDIT_SEVERITY", "severe")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring(`invalid value for BP_BUNDLER_AUDIT_SEVERITY: "severe"`)))
+			})
+		})
+
+		context("when the audit fails", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_AUDIT", "warn")
+				auditor.AuditCall.Returns.Error = errors.New("failed to audit")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError("failed to audit"))
+			})
+		})
+
 		context("when a dependency cannot be installed", func() {
 			it.Before(func() {
 				dependencyManager.DeliverCall.Returns.Error = errors.New("failed to install dependency")
@@ -475,6 +1600,37 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when $BP_BUNDLER_LABEL_PREFIXES contains an invalid prefix", func() {
+			it.Before(func() {
+				buildContext.Plan.Entries[0].Metadata["launch"] = true
+				t.Setenv("BP_BUNDLER_LABEL_PREFIXES", "com.example.ruby,Not A Prefix")
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring(`invalid value for BP_BUNDLER_LABEL_PREFIXES: "Not A Prefix" is not a valid label prefix`)))
+			})
+		})
+
+		context("when the resolution report cannot be written", func() {
+			var layerDir string
+			it.Before(func() {
+				layerDir = filepath.Join(layersDir, bundler.Bundler)
+				dependencyManager.DeliverCall.Stub = func(postal.Dependency, string, string, string) error {
+					return os.Chmod(layerDir, 0500)
+				}
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(layerDir, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(buildContext)
+				Expect(err).To(MatchError(ContainSubstring("failed to write resolution report")))
+			})
+		})
+
 		context("when the version shimmer cannot create version shims", func() {
 			it.Before(func() {
 				versionShimmer.ShimCall.Returns.Error = errors.New("failed to create version shims")
@@ -18,17 +18,28 @@ func NewBuildpackYMLParser() BuildpackYMLParser {
 }
 
 func (p BuildpackYMLParser) ParseVersion(path string) (string, error) {
+	config, _, err := p.ParseConfig(path)
+	if err != nil {
+		return "", err
+	}
+
+	return config.Version, nil
+}
+
+// ParseConfig returns the bundler configuration of a buildpack.yml and
+// whether the file has a bundler key at all.
+func (p BuildpackYMLParser) ParseConfig(path string) (Config, bool, error) {
 	var buildpack struct {
-		Bundler Config `yaml:"bundler"`
+		Bundler *Config `yaml:"bundler"`
 	}
 
 	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", nil
+			return Config{}, false, nil
 		}
 
-		return "", err
+		return Config{}, false, err
 	}
 
 	defer func() {
@@ -39,8 +50,12 @@ func (p BuildpackYMLParser) ParseVersion(path string) (string, error) {
 
 	err = yaml.NewDecoder(file).Decode(&buildpack)
 	if err != nil {
-		return "", err
+		return Config{}, false, err
+	}
+
+	if buildpack.Bundler == nil {
+		return Config{}, false, nil
 	}
 
-	return buildpack.Bundler.Version, nil
+	return *buildpack.Bundler, true, nil
 }
@@ -40,6 +40,52 @@ bundler:
 		Expect(os.RemoveAll(path)).To(Succeed())
 	})
 
+	context("ParseConfig", func() {
+		it("parses the bundler configuration from a buildpack.yml file", func() {
+			config, ok, err := parser.ParseConfig(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(config).To(Equal(bundler.Config{Version: "1.2.3"}))
+		})
+
+		context("when the bundler key has no version", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte("bundler: {}\n"), 0644)).To(Succeed())
+			})
+
+			it("reports the key", func() {
+				config, ok, err := parser.ParseConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(config).To(Equal(bundler.Config{}))
+			})
+		})
+
+		context("when the buildpack.yml file has no bundler key", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte("mri:\n  version: 3.2.2\n"), 0644)).To(Succeed())
+			})
+
+			it("reports that the key is missing", func() {
+				_, ok, err := parser.ParseConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		context("when the buildpack.yml file does not exist", func() {
+			it.Before(func() {
+				Expect(os.Remove(path)).To(Succeed())
+			})
+
+			it("reports that the key is missing", func() {
+				_, ok, err := parser.ParseConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+
 	context("ParseVersion", func() {
 		it("parses the bundler version from a buildpack.yml file", func() {
 			version, err := parser.ParseVersion(path)
@@ -0,0 +1,146 @@
+package bundler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+// ChecksumMismatch is a vendored gem whose sha256 differs from the one
+// recorded in the CHECKSUMS section of Gemfile.lock.
+type ChecksumMismatch struct {
+	File     string
+	Expected string
+	Actual   string
+}
+
+// ChecksumReport is the outcome of comparing vendor/cache against the
+// CHECKSUMS section of Gemfile.lock. Checksums is the number of lockfile
+// entries that carry a sha256; when it is zero nothing could be verified.
+// CacheFound is false when the application does not vendor its gems, in
+// which case the check does not apply.
+type ChecksumReport struct {
+	CacheFound bool
+	Checksums  int
+	Verified   []string
+	Missing    []string
+	Extra      []string
+	Mismatched []ChecksumMismatch
+}
+
+// Failed reports whether any vendored gem is missing, unexpected or has been
+// modified.
+func (r ChecksumReport) Failed() bool {
+	return len(r.Missing) > 0 || len(r.Extra) > 0 || len(r.Mismatched) > 0
+}
+
+type GemChecksumVerifier struct {
+	lockfileParser GemfileLockParser
+}
+
+func NewGemChecksumVerifier() GemChecksumVerifier {
+	return GemChecksumVerifier{
+		lockfileParser: NewGemfileLockParser(),
+	}
+}
+
+// Verify computes the sha256 of every .gem file in the application's
+// vendor/cache directory and compares it with the checksum recorded in
+// Gemfile.lock. Of the variants that the lockfile has checksums for, only the
+// one installed on the build target is required to be vendored.
+func (v GemChecksumVerifier) Verify(workingDir string, target packit.TargetInfo, distro packit.TargetDistro) (ChecksumReport, error) {
+	lockfile, err := v.lockfileParser.Parse(filepath.Join(workingDir, GemfileLockSource))
+	if err != nil {
+		return ChecksumReport{}, err
+	}
+
+	var checksummed []LockedGem
+	expected := map[string]string{}
+	for _, gem := range lockfile.Checksums {
+		if gem.Checksum != "" {
+			checksummed = append(checksummed, gem)
+			expected[gem.Filename()] = gem.Checksum
+		}
+	}
+
+	cacheDir := filepath.Join(workingDir, "vendor", "cache")
+	_, err = os.Stat(cacheDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return ChecksumReport{}, fmt.Errorf("failed to stat vendor/cache: %w", err)
+	}
+
+	report := ChecksumReport{CacheFound: err == nil, Checksums: len(expected)}
+	if !report.CacheFound || report.Checksums == 0 {
+		return report, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cacheDir, "*.gem"))
+	if err != nil {
+		return ChecksumReport{}, fmt.Errorf("failed to list vendored gems: %w", err)
+	}
+
+	found := map[string]bool{}
+	for _, path := range paths {
+		name := filepath.Base(path)
+		found[name] = true
+
+		checksum, ok := expected[name]
+		if !ok {
+			report.Extra = append(report.Extra, name)
+			continue
+		}
+
+		actual, err := sha256File(path)
+		if err != nil {
+			return ChecksumReport{}, err
+		}
+
+		if actual != checksum {
+			report.Mismatched = append(report.Mismatched, ChecksumMismatch{
+				File:     name,
+				Expected: checksum,
+				Actual:   actual,
+			})
+			continue
+		}
+
+		report.Verified = append(report.Verified, name)
+	}
+
+	_, candidates := targetPlatforms(target, distro)
+	for _, gem := range installedVariants(checksummed, candidates) {
+		if !found[gem.Filename()] {
+			report.Missing = append(report.Missing, gem.Filename())
+		}
+	}
+	sort.Strings(report.Missing)
+
+	return report, nil
+}
+
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum %s: %w", filepath.Base(path), err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close file: %v\n", err)
+		}
+	}()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum %s: %w", filepath.Base(path), err)
+	}
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(hash.Sum(nil))), nil
+}
@@ -0,0 +1,167 @@
+package bundler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testGemChecksumVerifier(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		target     packit.TargetInfo
+		verifier   bundler.GemChecksumVerifier
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+
+		cacheDir := filepath.Join(workingDir, "vendor", "cache")
+		Expect(os.MkdirAll(cacheDir, os.ModePerm)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(cacheDir, "rack-2.2.8.gem"), []byte("rack"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cacheDir, "nokogiri-1.16.0-x86_64-linux.gem"), []byte("tampered"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cacheDir, "evil-0.0.1.gem"), []byte("evil"), 0600)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    json (2.7.1)
+    nokogiri (1.16.0-x86_64-linux)
+    rack (2.2.8)
+
+PATH
+  remote: engines/admin
+  specs:
+    admin (0.1.0)
+
+PLATFORMS
+  x86_64-linux
+
+CHECKSUMS
+  admin (0.1.0)
+  json (2.7.1) sha256=0000000000000000000000000000000000000000000000000000000000000000
+  nokogiri (1.16.0-arm64-darwin) sha256=2222222222222222222222222222222222222222222222222222222222222222
+  nokogiri (1.16.0-x86_64-linux) sha256=1111111111111111111111111111111111111111111111111111111111111111
+  rack (2.2.8) sha256=8e9d1a8d5b1863f1e99ed1bd8a1b7bd1ea8e19f704f5859adbcf5cdce5ca7cd9
+
+BUNDLED WITH
+   2.5.6
+`), 0600)).To(Succeed())
+
+		target = packit.TargetInfo{OS: "linux", Arch: "amd64"}
+		verifier = bundler.NewGemChecksumVerifier()
+	})
+
+	context("Verify", func() {
+		it("reports verified, mismatched, missing and extra gems", func() {
+			report, err := verifier.Verify(workingDir, target, packit.TargetDistro{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report).To(Equal(bundler.ChecksumReport{
+				CacheFound: true,
+				Checksums:  4,
+				Verified:   []string{"rack-2.2.8.gem"},
+				Missing:    []string{"json-2.7.1.gem"},
+				Extra:      []string{"evil-0.0.1.gem"},
+				Mismatched: []bundler.ChecksumMismatch{
+					{
+						File:     "nokogiri-1.16.0-x86_64-linux.gem",
+						Expected: "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+						Actual:   "sha256:d121be3103007b41edf96f8262925f8c7d61894afe9a041843b631f69445bc57",
+					},
+				},
+			}))
+			Expect(report.Failed()).To(BeTrue())
+		})
+
+		context("when every vendored gem matches", func() {
+			it.Before(func() {
+				cacheDir := filepath.Join(workingDir, "vendor", "cache")
+				Expect(os.Remove(filepath.Join(cacheDir, "evil-0.0.1.gem"))).To(Succeed())
+				Expect(os.Remove(filepath.Join(cacheDir, "nokogiri-1.16.0-x86_64-linux.gem"))).To(Succeed())
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    rack (2.2.8)
+
+CHECKSUMS
+  rack (2.2.8) sha256=8e9d1a8d5b1863f1e99ed1bd8a1b7bd1ea8e19f704f5859adbcf5cdce5ca7cd9
+`), 0600)).To(Succeed())
+			})
+
+			it("reports success", func() {
+				report, err := verifier.Verify(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(bundler.ChecksumReport{
+					CacheFound: true,
+					Checksums:  1,
+					Verified:   []string{"rack-2.2.8.gem"},
+				}))
+				Expect(report.Failed()).To(BeFalse())
+			})
+		})
+
+		context("when the Gemfile.lock has no CHECKSUMS section", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    rack (2.2.8)
+`), 0600)).To(Succeed())
+			})
+
+			it("returns an empty report", func() {
+				report, err := verifier.Verify(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(bundler.ChecksumReport{CacheFound: true}))
+			})
+		})
+
+		context("when the build target uses another variant of a gem", func() {
+			it.Before(func() {
+				target = packit.TargetInfo{OS: "linux", Arch: "arm64"}
+			})
+
+			it("only requires the variant installed on the build target", func() {
+				report, err := verifier.Verify(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Missing).To(Equal([]string{"json-2.7.1.gem"}))
+			})
+		})
+
+		context("when the application does not vendor its gems", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(workingDir, "vendor"))).To(Succeed())
+			})
+
+			it("reports that the check does not apply", func() {
+				report, err := verifier.Verify(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(bundler.ChecksumReport{Checksums: 4}))
+				Expect(report.Failed()).To(BeFalse())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a vendored gem cannot be read", func() {
+				it.Before(func() {
+					Expect(os.Chmod(filepath.Join(workingDir, "vendor", "cache", "rack-2.2.8.gem"), 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := verifier.Verify(workingDir, target, packit.TargetDistro{})
+					Expect(err).To(MatchError(ContainSubstring("failed to checksum rack-2.2.8.gem")))
+				})
+			})
+		})
+	})
+}
@@ -2,9 +2,13 @@ package bundler
 
 const (
 	Bundler            = "bundler"
+	BundlerProvenance  = "bundler-provenance"
 	BuildpackYMLSource = "buildpack.yml"
 	GemfileLockSource  = "Gemfile.lock"
 	GemfileSource      = "Gemfile"
 
-	DepKey = "dependency-sha"
+	DefaultLabelPrefix = "io.paketo.bundler"
+
+	DepKey              = "dependency-sha"
+	ResolutionReportKey = "resolution-report"
 )
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+	"github.com/paketo-buildpacks/packit/cargo"
+)
+
+// compile implements the compile subcommand, which packages a Bundler gem
+// as a dependency artifact.
+func compile(args []string) {
+	flags := flag.NewFlagSet("compile", flag.ExitOnError)
+
+	var version = flags.String("version", "", "the version of Bundler to compile")
+	var outputDir = flags.String("outputDir", "", "the directory into which the artifact and its checksum will be written")
+	var target = flags.String("target", "", "the target to name the artifact after, e.g. jammy-amd64")
+	var builderID = flags.String("builder-id", "", "the builder recorded in the provenance statement (defaults to the GitHub Actions workflow, or \"local\")")
+	registerPlatformFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *version == "" {
+		log.Fatal("version is required")
+	}
+
+	if *outputDir == "" {
+		log.Fatal("outputDir is required")
+	}
+
+	if *target == "" {
+		log.Fatal("target is required")
+	}
+
+	artifact, err := newGemCompiler().Compile(*version, *target, *outputDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	builder, invocation := internal.LookupBuilder()
+	if *builderID != "" {
+		builder = internal.Builder{ID: *builderID}
+	}
+
+	provenance, err := internal.WriteProvenance(artifact, internal.NewProvenanceStatement(artifact, *version, *target, builder, invocation))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Wrote %s to %s\n", artifact.Checksum, artifact.ChecksumPath)
+	log.Printf("Wrote provenance built by %s to %s (%s)\n", builder.ID, provenance.Path, provenance.Checksum)
+	log.Printf("Wrote %s\n", artifact.Path)
+}
+
+// verifyReproducible implements the verify-reproducible subcommand, which
+// compiles a version twice and fails unless both artifacts are identical.
+func verifyReproducible(args []string) {
+	flags := flag.NewFlagSet("verify-reproducible", flag.ExitOnError)
+
+	var version = flags.String("version", "", "the version of Bundler to compile")
+	var target = flags.String("target", "", "the target to name the artifact after, e.g. jammy-amd64")
+	registerPlatformFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *version == "" {
+		log.Fatal("version is required")
+	}
+
+	if *target == "" {
+		log.Fatal("target is required")
+	}
+
+	checksum, err := newGemCompiler().Verify(*version, *target)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Both builds of bundler %s have checksum %s\n", *version, checksum)
+}
+
+// registerPlatformFlags accepts the platform flags of the compile workflow.
+// Bundler is pure Ruby, so the artifact is the same on every platform.
+func registerPlatformFlags(flags *flag.FlagSet) {
+	flags.String("os", "linux", "the target OS (ignored)")
+	flags.String("arch", "amd64", "the target architecture (ignored)")
+}
+
+// newGemCompiler returns a compiler that dates the artifacts with
+// $SOURCE_DATE_EPOCH when it is set.
+func newGemCompiler() internal.GemCompiler {
+	compiler := internal.NewGemCompiler(cargo.NewTransport())
+
+	epoch, err := internal.LookupSourceDateEpoch()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if epoch != nil {
+		compiler = compiler.WithModTime(*epoch)
+	}
+
+	return compiler
+}
@@ -9,11 +9,11 @@ require (
 	github.com/package-url/packageurl-go v0.1.6
 	github.com/paketo-buildpacks/packit v1.3.1
 	github.com/sclevine/spec v1.4.0
+	go.yaml.in/yaml/v3 v3.0.5
 )
 
 require (
 	github.com/google/go-cmp v0.7.0 // indirect
-	go.yaml.in/yaml/v3 v3.0.5 // indirect
 	golang.org/x/net v0.58.0 // indirect
 	golang.org/x/text v0.41.0 // indirect
 )
@@ -0,0 +1,660 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver"
+)
+
+const (
+	dependencyHeader        = "[[metadata.dependencies]]"
+	dependencyTablePrefix   = "metadata.dependencies."
+	constraintHeader        = "[[metadata.dependency-constraints]]"
+	defaultVersionsHeader   = "[metadata.default-versions]"
+	rubyCompatibilityHeader = "[metadata.ruby-compatibility]"
+	yankedVersionsHeader    = "[metadata.yanked-versions]"
+	dependencyIndent        = "  "
+)
+
+// Artifact is a compiled dependency, identified by the version, target, OS
+// and architecture of the metadata it was compiled from, along with the
+// tarball of its provenance statement when it was published.
+type Artifact struct {
+	Version            string `json:"version"`
+	Target             string `json:"target"`
+	OS                 string `json:"os,omitempty"`
+	Arch               string `json:"arch,omitempty"`
+	URI                string `json:"uri"`
+	Checksum           string `json:"checksum"`
+	ProvenanceURI      string `json:"provenance-uri,omitempty"`
+	ProvenanceChecksum string `json:"provenance-checksum,omitempty"`
+}
+
+// BuildpackTOMLUpdate describes the changes to make to buildpack.toml. When
+// DefaultVersion is empty, the default version is left alone. Removed lists
+// the versions the release index no longer offers, as reported by
+// FindRemovedVersions.
+type BuildpackTOMLUpdate struct {
+	Metadata       []ReleaseMetadata
+	Artifacts      []Artifact
+	DefaultVersion string
+	Removed        []RemovedVersion
+}
+
+// BuildpackTOMLUpdater adds retrieved dependencies to buildpack.toml. It edits
+// the file as text so that everything it does not need to touch, including
+// comments and the formatting of existing dependencies, stays as it is. The
+// provenance statement of a dependency is added as a dependency of its own,
+// with the -provenance suffix on its id, so that offline buildpacks package it
+// and the buildpack delivers it the way it delivers the dependency.
+type BuildpackTOMLUpdater struct {
+	depID        string
+	provenanceID string
+}
+
+func NewBuildpackTOMLUpdater() BuildpackTOMLUpdater {
+	return BuildpackTOMLUpdater{
+		depID:        depID,
+		provenanceID: depID + "-provenance",
+	}
+}
+
+type dependencyBlock struct {
+	text     string
+	id       string
+	version  *semver.Version
+	identity string
+}
+
+// Update inserts the metadata as [[metadata.dependencies]] entries, replacing
+// existing entries for the same version and platform, and sorts the entries
+// by version. It then prunes the entries matching each dependency constraint
+// down to the newest versions allowed by its patches count. The Ruby version
+// each remaining version requires is kept in the [metadata.ruby-compatibility]
+// table, and the remaining versions that have been yanked in the
+// [metadata.yanked-versions] table. Tools reading the entries through packit
+// preserve both tables, but not unknown keys of the entries themselves.
+// Provenance statements are kept as long as the version they vouch for.
+func (u BuildpackTOMLUpdater) Update(content string, update BuildpackTOMLUpdate) (string, error) {
+	var config struct {
+		Metadata struct {
+			RubyCompatibility     map[string]string   `toml:"ruby-compatibility"`
+			YankedVersions        map[string][]string `toml:"yanked-versions"`
+			DependencyConstraints []struct {
+				Constraint string `toml:"constraint"`
+				ID         string `toml:"id"`
+				Patches    int    `toml:"patches"`
+			} `toml:"dependency-constraints"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.Decode(content, &config)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse buildpack.toml: %w", err)
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	blocks, start, end, err := parseDependencyBlocks(lines)
+	if err != nil {
+		return "", err
+	}
+
+	for _, metadata := range update.Metadata {
+		metadata, err = withArtifact(metadata, update.Artifacts)
+		if err != nil {
+			return "", err
+		}
+
+		block, err := renderDependency(metadata)
+		if err != nil {
+			return "", err
+		}
+
+		blocks = replaceOrAppend(blocks, block)
+
+		if metadata.ProvenanceURI == "" {
+			continue
+		}
+
+		block, err = renderDependency(ReleaseMetadata{
+			Arch:     metadata.Arch,
+			Checksum: metadata.ProvenanceChecksum,
+			Distros:  metadata.Distros,
+			ID:       u.provenanceID,
+			Name:     metadata.Name + " provenance",
+			OS:       metadata.OS,
+			Stacks:   metadata.Stacks,
+			URI:      metadata.ProvenanceURI,
+			Version:  metadata.Version,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		blocks = replaceOrAppend(blocks, block)
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if blocks[i].id != blocks[j].id {
+			return blocks[i].id < blocks[j].id
+		}
+		return blocks[i].version.LessThan(blocks[j].version)
+	})
+
+	var (
+		kept   = map[string]bool{}
+		pruned = map[string]bool{}
+	)
+	for _, constraint := range config.Metadata.DependencyConstraints {
+		if constraint.Patches <= 0 {
+			continue
+		}
+
+		svConstraint, err := semver.NewConstraint(constraint.Constraint)
+		if err != nil {
+			return "", fmt.Errorf("invalid dependency constraint %q: %w", constraint.Constraint, err)
+		}
+
+		var versions []string
+		for i := len(blocks) - 1; i >= 0; i-- {
+			block := blocks[i]
+			if block.id != constraint.ID || !svConstraint.Check(block.version) {
+				continue
+			}
+
+			key := block.id + "@" + block.version.String()
+			if len(versions) > 0 && versions[len(versions)-1] == key {
+				continue
+			}
+			versions = append(versions, key)
+
+			if len(versions) > constraint.Patches {
+				pruned[key] = true
+			} else {
+				kept[key] = true
+			}
+		}
+	}
+
+	retained := map[string]bool{}
+	for _, block := range blocks {
+		key := block.id + "@" + block.version.String()
+		if block.id == u.depID && (!pruned[key] || kept[key]) {
+			retained[block.version.String()] = true
+		}
+	}
+
+	var texts []string
+	for _, block := range blocks {
+		key := block.id + "@" + block.version.String()
+		if pruned[key] && !kept[key] {
+			continue
+		}
+
+		if block.id == u.provenanceID && !retained[block.version.String()] {
+			continue
+		}
+
+		texts = append(texts, block.text)
+	}
+
+	requirements := map[string]string{}
+	for version, requirement := range config.Metadata.RubyCompatibility {
+		requirements[version] = requirement
+	}
+	for _, metadata := range update.Metadata {
+		if metadata.ID == u.depID && metadata.RequiredRubyVersion != "" {
+			requirements[metadata.Version] = metadata.RequiredRubyVersion
+		}
+	}
+	for version := range requirements {
+		if svVersion, err := semver.NewVersion(version); err != nil || !retained[svVersion.String()] {
+			delete(requirements, version)
+		}
+	}
+
+	// A version that has been yanked stays yanked, so the table only loses
+	// the versions that are no longer in buildpack.toml.
+	yanked := map[string][]string{}
+	for id, versions := range config.Metadata.YankedVersions {
+		yanked[id] = versions
+	}
+	for _, removed := range update.Removed {
+		if removed.Status == StatusYanked {
+			yanked[u.depID] = append(yanked[u.depID], removed.Version)
+		}
+	}
+
+	seen := map[string]bool{}
+	var yankedVersions []*semver.Version
+	for _, version := range yanked[u.depID] {
+		svVersion, err := semver.NewVersion(version)
+		if err != nil || !retained[svVersion.String()] || seen[svVersion.String()] {
+			continue
+		}
+		seen[svVersion.String()] = true
+		yankedVersions = append(yankedVersions, svVersion)
+	}
+	sort.Sort(semver.Collection(yankedVersions))
+
+	yanked[u.depID] = nil
+	for _, version := range yankedVersions {
+		yanked[u.depID] = append(yanked[u.depID], version.Original())
+	}
+
+	var (
+		joined = strings.Join(texts, "\n\n")
+		result = append([]string{}, lines[:start]...)
+	)
+	switch {
+	case len(texts) == 0:
+	case end > start:
+		result = append(result, joined+"\n")
+	case start < len(lines):
+		result = append(result, joined+"\n\n")
+	default:
+		if len(result) > 0 && !strings.HasSuffix(result[len(result)-1], "\n") {
+			result = append(result, "\n")
+		}
+		result = append(result, "\n"+joined+"\n")
+	}
+	result = append(result, lines[end:]...)
+
+	updated := setRubyCompatibility(strings.Join(result, ""), requirements)
+	updated = setYankedVersions(updated, yanked)
+	if update.DefaultVersion != "" {
+		updated, err = u.setDefaultVersion(updated, update.DefaultVersion)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	_, err = toml.Decode(updated, &map[string]interface{}{})
+	if err != nil {
+		return "", fmt.Errorf("failed to update buildpack.toml: %w", err)
+	}
+
+	return updated, nil
+}
+
+// parseDependencyBlocks returns the [[metadata.dependencies]] entries of
+// buildpack.toml along with the range of lines they span. The entries must
+// follow each other, separated by blank lines only. Without entries, the
+// range is empty and sits in front of the first dependency constraint, or at
+// the end of the file.
+func parseDependencyBlocks(lines []string) ([]dependencyBlock, int, int, error) {
+	var (
+		blocks     []dependencyBlock
+		start, end = -1, -1
+		current    []string
+	)
+
+	flush := func(next int) error {
+		if current == nil {
+			return nil
+		}
+
+		for len(current) > 0 && strings.TrimSpace(current[len(current)-1]) == "" {
+			current = current[:len(current)-1]
+			next--
+		}
+
+		block, err := parseDependencyBlock(strings.TrimSuffix(strings.Join(current, ""), "\n"))
+		if err != nil {
+			return err
+		}
+
+		blocks = append(blocks, block)
+		end = next
+		current = nil
+		return nil
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == dependencyHeader:
+			if err := flush(i); err != nil {
+				return nil, 0, 0, err
+			}
+
+			if start >= 0 && strings.TrimSpace(strings.Join(lines[end:i], "")) != "" {
+				return nil, 0, 0, fmt.Errorf("failed to parse buildpack.toml: [[metadata.dependencies]] entries must not be separated by other content (line %d)", i+1)
+			}
+
+			if start < 0 {
+				start = i
+			}
+			current = []string{line}
+		case strings.HasPrefix(trimmed, "["):
+			if current != nil && !strings.HasPrefix(strings.Trim(trimmed, "[ "), dependencyTablePrefix) {
+				if err := flush(i); err != nil {
+					return nil, 0, 0, err
+				}
+			} else if current != nil {
+				current = append(current, line)
+			}
+		default:
+			if current != nil {
+				current = append(current, line)
+			}
+		}
+	}
+
+	if err := flush(len(lines)); err != nil {
+		return nil, 0, 0, err
+	}
+
+	if start >= 0 {
+		return blocks, start, end, nil
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == constraintHeader {
+			return nil, i, i, nil
+		}
+	}
+
+	return nil, len(lines), len(lines), nil
+}
+
+func parseDependencyBlock(text string) (dependencyBlock, error) {
+	var entry struct {
+		Metadata struct {
+			Dependencies []struct {
+				ID      string `toml:"id"`
+				Version string `toml:"version"`
+				OS      string `toml:"os"`
+				Arch    string `toml:"arch"`
+			} `toml:"dependencies"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.Decode(text, &entry)
+	if err != nil || len(entry.Metadata.Dependencies) != 1 {
+		return dependencyBlock{}, fmt.Errorf("failed to parse dependency in buildpack.toml: %q", text)
+	}
+
+	dependency := entry.Metadata.Dependencies[0]
+	version, err := semver.NewVersion(dependency.Version)
+	if err != nil {
+		return dependencyBlock{}, fmt.Errorf("failed to parse version of dependency %s %q: %w", dependency.ID, dependency.Version, err)
+	}
+
+	return dependencyBlock{
+		text:     text,
+		id:       dependency.ID,
+		version:  version,
+		identity: dependencyIdentity(dependency.ID, version, dependency.OS, dependency.Arch),
+	}, nil
+}
+
+// dependencyIdentity identifies the entries that the dependency manager
+// cannot tell apart. It matches entries on their stacks, OS and architecture
+// but ignores their distros, and every entry written by the retrieval
+// supports every stack.
+func dependencyIdentity(id string, version *semver.Version, os, arch string) string {
+	return strings.Join([]string{id, version.String(), os, arch}, "|")
+}
+
+func withArtifact(metadata ReleaseMetadata, artifacts []Artifact) (ReleaseMetadata, error) {
+	for _, artifact := range artifacts {
+		if artifact.Version == metadata.Version && artifact.Target == metadata.Target && artifact.OS == metadata.OS && artifact.Arch == metadata.Arch {
+			metadata.URI = artifact.URI
+			metadata.Checksum = artifact.Checksum
+			if artifact.ProvenanceURI != "" {
+				metadata.ProvenanceURI = artifact.ProvenanceURI
+				metadata.ProvenanceChecksum = artifact.ProvenanceChecksum
+			}
+			break
+		}
+	}
+
+	if metadata.ProvenanceURI != "" && metadata.ProvenanceChecksum == "" {
+		return ReleaseMetadata{}, fmt.Errorf("no checksum for the provenance of %s %s: %s", metadata.ID, metadata.Version, metadata.ProvenanceURI)
+	}
+
+	if metadata.URI == "" || metadata.Checksum == "" {
+		platform := metadata.Target
+		if metadata.OS != "" || metadata.Arch != "" {
+			platform = fmt.Sprintf("%s %s/%s", platform, metadata.OS, metadata.Arch)
+		}
+
+		return ReleaseMetadata{}, fmt.Errorf("no compiled artifact for %s %s (%s)", metadata.ID, metadata.Version, strings.TrimSpace(platform))
+	}
+
+	return metadata, nil
+}
+
+// renderDependency formats the metadata the way the entries of buildpack.toml
+// are formatted: indented, with the keys in alphabetical order.
+func renderDependency(metadata ReleaseMetadata) (dependencyBlock, error) {
+	version, err := semver.NewVersion(metadata.Version)
+	if err != nil {
+		return dependencyBlock{}, fmt.Errorf("failed to parse version of dependency %s %q: %w", metadata.ID, metadata.Version, err)
+	}
+
+	dependency := map[string]interface{}{
+		"checksum":        metadata.Checksum,
+		"cpe":             metadata.CPE,
+		"id":              metadata.ID,
+		"licenses":        metadata.Licenses,
+		"name":            metadata.Name,
+		"purl":            metadata.PURL,
+		"source":          metadata.SourceURI,
+		"source-checksum": metadata.SourceChecksum,
+		"stacks":          metadata.Stacks,
+		"uri":             metadata.URI,
+		"version":         metadata.Version,
+	}
+
+	if metadata.DeprecationDate != nil {
+		dependency["deprecation_date"] = metadata.DeprecationDate.UTC()
+	}
+	if metadata.StripComponents != 0 {
+		dependency["strip-components"] = metadata.StripComponents
+	}
+	if metadata.OS != "" {
+		dependency["os"] = metadata.OS
+	}
+	if metadata.Arch != "" {
+		dependency["arch"] = metadata.Arch
+	}
+	if len(metadata.Distros) > 0 {
+		dependency["distros"] = metadata.Distros
+	}
+	for _, key := range []string{"cpe", "purl", "source", "source-checksum"} {
+		if dependency[key] == "" {
+			delete(dependency, key)
+		}
+	}
+	if metadata.Licenses == nil {
+		delete(dependency, "licenses")
+	}
+	if metadata.Stacks == nil {
+		delete(dependency, "stacks")
+	}
+
+	var buffer bytes.Buffer
+	encoder := toml.NewEncoder(&buffer)
+	encoder.Indent = dependencyIndent
+
+	err = encoder.Encode(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"dependencies": []map[string]interface{}{dependency},
+		},
+	})
+	if err != nil {
+		return dependencyBlock{}, err
+	}
+
+	text := buffer.String()
+	text = text[strings.Index(text, dependencyIndent+dependencyHeader):]
+
+	return dependencyBlock{
+		text:     strings.TrimRight(text, "\n"),
+		id:       metadata.ID,
+		version:  version,
+		identity: dependencyIdentity(metadata.ID, version, metadata.OS, metadata.Arch),
+	}, nil
+}
+
+// replaceOrAppend replaces the entries with the same identity as the block,
+// or appends the block when there are none, so that entries that only differ
+// in their distros collapse into one.
+func replaceOrAppend(blocks []dependencyBlock, block dependencyBlock) []dependencyBlock {
+	var (
+		result   []dependencyBlock
+		replaced bool
+	)
+	for _, existing := range blocks {
+		if existing.identity != block.identity {
+			result = append(result, existing)
+			continue
+		}
+
+		if !replaced {
+			result = append(result, block)
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		result = append(result, block)
+	}
+
+	return result
+}
+
+// setDefaultVersion sets the default version of the dependency in the
+// [metadata.default-versions] table, which must exist.
+func (u BuildpackTOMLUpdater) setDefaultVersion(content, version string) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+
+	header := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == defaultVersionsHeader {
+			header = i
+			break
+		}
+	}
+
+	if header < 0 {
+		return "", fmt.Errorf("failed to set the default version: buildpack.toml has no %s table", defaultVersionsHeader)
+	}
+
+	indent := lines[header][:len(lines[header])-len(strings.TrimLeft(lines[header], " \t"))] + dependencyIndent
+	entry := fmt.Sprintf("%s = %q\n", u.depID, version)
+
+	for i := header + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "[") {
+			break
+		}
+
+		key, _, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.Trim(strings.TrimSpace(key), `"`) != u.depID {
+			continue
+		}
+
+		lines[i] = lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))] + entry
+		return strings.Join(lines, ""), nil
+	}
+
+	lines = append(lines[:header+1], append([]string{indent + entry}, lines[header+1:]...)...)
+	return strings.Join(lines, ""), nil
+}
+
+// setRubyCompatibility replaces the entries of the [metadata.ruby-compatibility]
+// table, which maps each version to the Ruby version it requires.
+func setRubyCompatibility(content string, requirements map[string]string) string {
+	var versions []*semver.Version
+	for version := range requirements {
+		versions = append(versions, semver.MustParse(version))
+	}
+	sort.Sort(semver.Collection(versions))
+
+	var entries []string
+	for _, version := range versions {
+		entries = append(entries, fmt.Sprintf("%q = %q", version.Original(), requirements[version.Original()]))
+	}
+
+	return setTable(content, rubyCompatibilityHeader, entries)
+}
+
+// setYankedVersions replaces the entries of the [metadata.yanked-versions]
+// table, which maps each dependency ID to the versions that have been yanked.
+func setYankedVersions(content string, yanked map[string][]string) string {
+	var ids []string
+	for id, versions := range yanked {
+		if len(versions) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var entries []string
+	for _, id := range ids {
+		var quoted []string
+		for _, version := range yanked[id] {
+			quoted = append(quoted, fmt.Sprintf("%q", version))
+		}
+		entries = append(entries, fmt.Sprintf("%s = [%s]", id, strings.Join(quoted, ", ")))
+	}
+
+	return setTable(content, yankedVersionsHeader, entries)
+}
+
+// setTable replaces the entries of a metadata table. When the table does not
+// exist yet and there are entries, it is inserted in front of the first
+// dependency.
+func setTable(content, header string, entries []string) string {
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != header {
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))] + dependencyIndent
+
+		end := i + 1
+		for j := i + 1; j < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[j]), "["); j++ {
+			if strings.TrimSpace(lines[j]) != "" {
+				end = j + 1
+			}
+		}
+
+		var rendered []string
+		for _, entry := range entries {
+			rendered = append(rendered, indent+entry+"\n")
+		}
+
+		return strings.Join(append(append(append([]string{}, lines[:i+1]...), rendered...), lines[end:]...), "")
+	}
+
+	if len(entries) == 0 {
+		return content
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) != dependencyHeader {
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+		table := []string{indent + header + "\n"}
+		for _, entry := range entries {
+			table = append(table, indent+dependencyIndent+entry+"\n")
+		}
+		table = append(table, "\n")
+
+		return strings.Join(append(append(append([]string{}, lines[:i]...), table...), lines[i:]...), "")
+	}
+
+	return content
+}
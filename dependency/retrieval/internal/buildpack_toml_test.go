@@ -0,0 +1,403 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testBuildpackTOMLUpdater(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		updater internal.BuildpackTOMLUpdater
+		update  internal.BuildpackTOMLUpdate
+	)
+
+	it.Before(func() {
+		updater = internal.NewBuildpackTOMLUpdater()
+		update = internal.BuildpackTOMLUpdate{
+			Metadata: []internal.ReleaseMetadata{
+				{
+					CPE:                 "cpe:2.3:a:bundler:bundler:2.7.3:*:*:*:*:ruby:*:*",
+					ID:                  "bundler",
+					Licenses:            []string{"MIT"},
+					Name:                "bundler",
+					PURL:                "pkg:generic/bundler@2.7.3",
+					RequiredRubyVersion: ">= 3.2.0",
+					SourceChecksum:      "sha256:source",
+					SourceURI:           "https://rubygems.org/downloads/bundler-2.7.3.gem",
+					Stacks:              []string{"*"},
+					StripComponents:     2,
+					Target:              "jammy",
+					OS:                  "linux",
+					Arch:                "amd64",
+					Distros:             []internal.Distro{{Name: "ubuntu", Version: "22.04"}},
+					Version:             "2.7.3",
+				},
+			},
+			Artifacts: []internal.Artifact{
+				{Version: "2.7.3", Target: "noble", OS: "linux", Arch: "amd64", URI: "https://example.com/noble.tgz", Checksum: "sha256:noble"},
+				{Version: "2.7.3", Target: "jammy", OS: "linux", Arch: "amd64", URI: "https://example.com/jammy.tgz", Checksum: "sha256:jammy"},
+			},
+		}
+	})
+
+	context("Update", func() {
+		it("inserts the dependencies in version order and prunes each constraint", func() {
+			updated, err := updater.Update(buildpackTOML, update)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated).To(Equal(`api = "0.7"
+
+[metadata]
+  # keep in sync with the release notes
+  [metadata.default-versions]
+    bundler = "2.x.x"
+
+  [metadata.ruby-compatibility]
+    "2.7.3" = ">= 3.2.0"
+    "4.0.17" = ">= 3.2.0"
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    uri = "https://example.com/bundler-2.7.2.tgz"
+    version = "2.7.2"
+
+  [[metadata.dependencies]]
+    arch = "amd64"
+    checksum = "sha256:jammy"
+    cpe = "cpe:2.3:a:bundler:bundler:2.7.3:*:*:*:*:ruby:*:*"
+    id = "bundler"
+    licenses = ["MIT"]
+    name = "bundler"
+    os = "linux"
+    purl = "pkg:generic/bundler@2.7.3"
+    source = "https://rubygems.org/downloads/bundler-2.7.3.gem"
+    source-checksum = "sha256:source"
+    stacks = ["*"]
+    strip-components = 2
+    uri = "https://example.com/jammy.tgz"
+    version = "2.7.3"
+
+    [[metadata.dependencies.distros]]
+      name = "ubuntu"
+      version = "22.04"
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    uri = "https://example.com/bundler-4.0.17.tgz" # pinned
+    version = "4.0.17"
+
+  [[metadata.dependency-constraints]]
+    constraint = "2.*.*"
+    id = "bundler"
+    patches = 2
+
+  [[metadata.dependency-constraints]]
+    constraint = "4.*.*"
+    id = "bundler"
+    patches = 2
+`))
+		})
+
+		context("when a dependency is already listed", func() {
+			it.Before(func() {
+				update.Metadata[0].Version = "2.7.2"
+				update.Metadata[0].Target = ""
+				update.Metadata[0].OS = ""
+				update.Metadata[0].Arch = ""
+				update.Metadata[0].Distros = nil
+				update.Metadata[0].Checksum = "sha256:updated"
+				update.Metadata[0].URI = "https://example.com/updated.tgz"
+			})
+
+			it("replaces it", func() {
+				updated, err := updater.Update(buildpackTOML, update)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updated).To(ContainSubstring(`uri = "https://example.com/bundler-2.7.1.tgz"`))
+				Expect(updated).To(ContainSubstring(`uri = "https://example.com/updated.tgz"`))
+				Expect(updated).NotTo(ContainSubstring(`uri = "https://example.com/bundler-2.7.2.tgz"`))
+			})
+		})
+
+		context("when the version is listed once per distro", func() {
+			it("replaces the entries with a single one", func() {
+				var entries []string
+				for _, distro := range []string{"22.04", "24.04"} {
+					entries = append(entries, `  [[metadata.dependencies]]
+    arch = "amd64"
+    id = "bundler"
+    os = "linux"
+    stacks = ["*"]
+    uri = "https://example.com/bundler-2.7.3-`+distro+`.tgz"
+    version = "2.7.3"
+
+    [[metadata.dependencies.distros]]
+      name = "ubuntu"
+      version = "`+distro+`"
+
+`)
+				}
+				content := strings.Replace(buildpackTOML, "  [[metadata.dependency-constraints]]", strings.Join(entries, "")+"  [[metadata.dependency-constraints]]", 1)
+
+				updated, err := updater.Update(content, update)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(strings.Count(updated, `version = "2.7.3"`)).To(Equal(1))
+				Expect(updated).To(ContainSubstring(`uri = "https://example.com/jammy.tgz"`))
+				Expect(updated).NotTo(ContainSubstring("bundler-2.7.3-"))
+			})
+		})
+
+		context("when a default version is given", func() {
+			it.Before(func() {
+				update.DefaultVersion = "2.7.3"
+			})
+
+			it("sets it", func() {
+				updated, err := updater.Update(buildpackTOML, update)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updated).To(ContainSubstring("  [metadata.default-versions]\n    bundler = \"2.7.3\"\n"))
+			})
+
+			context("when the table has no entry for the dependency", func() {
+				it("adds one", func() {
+					updated, err := updater.Update("[metadata]\n  [metadata.default-versions]\n    other = \"1.0.0\"\n", internal.BuildpackTOMLUpdate{DefaultVersion: "2.7.3"})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(updated).To(Equal("[metadata]\n  [metadata.default-versions]\n    bundler = \"2.7.3\"\n    other = \"1.0.0\"\n"))
+				})
+			})
+		})
+
+		context("when versions have been yanked", func() {
+			it.Before(func() {
+				update.Removed = []internal.RemovedVersion{
+					{Version: "2.7.1", Status: "yanked"},
+					{Version: "4.0.17", Status: "yanked"},
+					{Version: "2.7.2", Status: "unlisted"},
+				}
+			})
+
+			it("lists the remaining yanked versions", func() {
+				updated, err := updater.Update(buildpackTOML, update)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updated).To(ContainSubstring(`  [metadata.ruby-compatibility]
+    "2.7.3" = ">= 3.2.0"
+    "4.0.17" = ">= 3.2.0"
+
+  [metadata.yanked-versions]
+    bundler = ["4.0.17"]
+
+  [[metadata.dependencies]]
+`))
+			})
+
+			context("when buildpack.toml already lists yanked versions", func() {
+				it("keeps those that remain", func() {
+					content := strings.Replace(buildpackTOML, "  [[metadata.dependencies]]", "  [metadata.yanked-versions]\n    bundler = [\"2.7.1\", \"2.7.2\"]\n    other = [\"1.0.0\"]\n\n  [[metadata.dependencies]]", 1)
+
+					updated, err := updater.Update(content, update)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(updated).To(ContainSubstring("  [metadata.yanked-versions]\n    bundler = [\"2.7.2\", \"4.0.17\"]\n    other = [\"1.0.0\"]\n\n  [[metadata.dependencies]]\n"))
+				})
+			})
+		})
+
+		context("when the artifact has a provenance statement", func() {
+			it.Before(func() {
+				update.Artifacts[1].ProvenanceURI = "https://example.com/jammy.tgz.intoto.tar.gz"
+				update.Artifacts[1].ProvenanceChecksum = "sha256:jammy-provenance"
+			})
+
+			it("adds the statement as a dependency of its own", func() {
+				updated, err := updater.Update(buildpackTOML, update)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updated).To(ContainSubstring(`    uri = "https://example.com/bundler-4.0.17.tgz" # pinned
+    version = "4.0.17"
+
+  [[metadata.dependencies]]
+    arch = "amd64"
+    checksum = "sha256:jammy-provenance"
+    id = "bundler-provenance"
+    name = "bundler provenance"
+    os = "linux"
+    stacks = ["*"]
+    uri = "https://example.com/jammy.tgz.intoto.tar.gz"
+    version = "2.7.3"
+
+    [[metadata.dependencies.distros]]
+      name = "ubuntu"
+      version = "22.04"
+
+  [[metadata.dependency-constraints]]
+`))
+			})
+
+			context("when the version it vouches for is pruned", func() {
+				it("removes the statement", func() {
+					content := strings.Replace(buildpackTOML, `    version = "2.7.2"
+`, `    version = "2.7.2"
+
+  [[metadata.dependencies]]
+    id = "bundler-provenance"
+    uri = "https://example.com/bundler-2.7.1.tgz.intoto.tar.gz"
+    version = "2.7.1"
+
+  [[metadata.dependencies]]
+    id = "bundler-provenance"
+    uri = "https://example.com/bundler-2.7.2.tgz.intoto.tar.gz"
+    version = "2.7.2"
+`, 1)
+
+					updated, err := updater.Update(content, update)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(updated).NotTo(ContainSubstring("bundler-2.7.1.tgz.intoto.tar.gz"))
+					Expect(updated).To(ContainSubstring("bundler-2.7.2.tgz.intoto.tar.gz"))
+				})
+			})
+
+			context("when the statement has no checksum", func() {
+				it.Before(func() {
+					update.Artifacts[1].ProvenanceChecksum = ""
+				})
+
+				it("returns an error", func() {
+					_, err := updater.Update(buildpackTOML, update)
+					Expect(err).To(MatchError("no checksum for the provenance of bundler 2.7.3: https://example.com/jammy.tgz.intoto.tar.gz"))
+				})
+			})
+		})
+
+		context("when buildpack.toml has no dependencies yet", func() {
+			it("inserts them in front of the constraints", func() {
+				deprecationDate := time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+				update.Metadata[0].DeprecationDate = &deprecationDate
+				update.Metadata[0].Distros = nil
+
+				updated, err := updater.Update(`[metadata]
+
+  [[metadata.dependency-constraints]]
+    constraint = "2.*.*"
+    id = "bundler"
+    patches = 2
+`, update)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updated).To(Equal(`[metadata]
+
+  [metadata.ruby-compatibility]
+    "2.7.3" = ">= 3.2.0"
+
+  [[metadata.dependencies]]
+    arch = "amd64"
+    checksum = "sha256:jammy"
+    cpe = "cpe:2.3:a:bundler:bundler:2.7.3:*:*:*:*:ruby:*:*"
+    deprecation_date = 2027-04-01T00:00:00Z
+    id = "bundler"
+    licenses = ["MIT"]
+    name = "bundler"
+    os = "linux"
+    purl = "pkg:generic/bundler@2.7.3"
+    source = "https://rubygems.org/downloads/bundler-2.7.3.gem"
+    source-checksum = "sha256:source"
+    stacks = ["*"]
+    strip-components = 2
+    uri = "https://example.com/jammy.tgz"
+    version = "2.7.3"
+
+  [[metadata.dependency-constraints]]
+    constraint = "2.*.*"
+    id = "bundler"
+    patches = 2
+`))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when buildpack.toml cannot be parsed", func() {
+				it("returns an error", func() {
+					_, err := updater.Update("%%%", update)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse buildpack.toml")))
+				})
+			})
+
+			context("when a dependency has no compiled artifact", func() {
+				it.Before(func() {
+					update.Artifacts = nil
+				})
+
+				it("returns an error", func() {
+					_, err := updater.Update(buildpackTOML, update)
+					Expect(err).To(MatchError("no compiled artifact for bundler 2.7.3 (jammy linux/amd64)"))
+				})
+			})
+
+			context("when the dependencies are separated by other tables", func() {
+				it("returns an error", func() {
+					_, err := updater.Update(`[[metadata.dependencies]]
+  id = "bundler"
+  version = "2.7.1"
+
+[[metadata.dependency-constraints]]
+  constraint = "2.*.*"
+  id = "bundler"
+  patches = 2
+
+[[metadata.dependencies]]
+  id = "bundler"
+  version = "2.7.2"
+`, update)
+					Expect(err).To(MatchError(ContainSubstring("entries must not be separated by other content (line 10)")))
+				})
+			})
+
+			context("when there is no default versions table", func() {
+				it("returns an error", func() {
+					_, err := updater.Update(`[metadata]`, internal.BuildpackTOMLUpdate{DefaultVersion: "2.7.3"})
+					Expect(err).To(MatchError("failed to set the default version: buildpack.toml has no [metadata.default-versions] table"))
+				})
+			})
+		})
+	})
+}
+
+const buildpackTOML = `api = "0.7"
+
+[metadata]
+  # keep in sync with the release notes
+  [metadata.default-versions]
+    bundler = "2.x.x"
+
+  [metadata.ruby-compatibility]
+    "2.7.1" = ">= 3.1.0"
+    "4.0.17" = ">= 3.2.0"
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    uri = "https://example.com/bundler-2.7.1.tgz"
+    version = "2.7.1"
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    uri = "https://example.com/bundler-4.0.17.tgz" # pinned
+    version = "4.0.17"
+
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    uri = "https://example.com/bundler-2.7.2.tgz"
+    version = "2.7.2"
+
+  [[metadata.dependency-constraints]]
+    constraint = "2.*.*"
+    id = "bundler"
+    patches = 2
+
+  [[metadata.dependency-constraints]]
+    constraint = "4.*.*"
+    id = "bundler"
+    patches = 2
+`
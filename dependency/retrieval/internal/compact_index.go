@@ -0,0 +1,291 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// CompactIndexFetcher reads the releases of Bundler from a gem server that
+// implements the compact index API, i.e. the /versions and /info/<gem>
+// endpoints used by Bundler itself.
+type CompactIndexFetcher struct {
+	baseURL      string
+	gem          string
+	detailsIndex string
+}
+
+// NewCompactIndexFetcher takes the URL of a compact index. The URL may point
+// at the root of the gem server or at its /versions or /info/bundler
+// endpoint.
+func NewCompactIndexFetcher(index string) CompactIndexFetcher {
+	baseURL := strings.TrimSuffix(index, "/")
+	for _, suffix := range []string{"/versions", "/info/" + depID} {
+		baseURL = strings.TrimSuffix(baseURL, suffix)
+	}
+
+	return CompactIndexFetcher{
+		baseURL: baseURL,
+		gem:     depID,
+	}
+}
+
+// WithDetailsIndex reads the licenses and release dates of the releases,
+// which the compact index does not publish, from a release index in the
+// RubyGems JSON API format, e.g.
+// https://rubygems.org/api/v1/versions/bundler.json.
+func (f CompactIndexFetcher) WithDetailsIndex(index string) CompactIndexFetcher {
+	f.detailsIndex = index
+	return f
+}
+
+// Get lists the versions of the gem that have not been yanked according to
+// /versions and reads their checksum, platform and requirements from the
+// /info file of the gem. Prereleases are skipped. Licenses and release dates
+// are left empty unless a details index is configured.
+func (f CompactIndexFetcher) Get() ([]Release, error) {
+	versions, err := f.fetch("/versions")
+	if err != nil {
+		return nil, err
+	}
+
+	active, _, infoMD5, err := f.parseVersions(versions)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.fetch("/info/" + f.gem)
+	if err != nil {
+		return nil, err
+	}
+
+	if infoMD5 != "" {
+		sum := md5.Sum(info)
+		if hex.EncodeToString(sum[:]) != infoMD5 {
+			return nil, fmt.Errorf("compact index /info/%s does not match the checksum in /versions", f.gem)
+		}
+	}
+
+	var releases []Release
+	for i, line := range lines(info) {
+		release, err := parseInfoLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("compact index /info/%s line %d: %w", f.gem, i+1, err)
+		}
+
+		name := release.Version
+		if release.Platform != "ruby" {
+			name += "-" + release.Platform
+		}
+
+		if !active[name] || release.Prerelease {
+			continue
+		}
+
+		releases = append(releases, release)
+	}
+
+	if len(releases) == 0 {
+		return nil, errors.New("no valid releases found")
+	}
+
+	if f.detailsIndex != "" {
+		err = f.addVersionDetails(releases)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return releases, nil
+}
+
+// addVersionDetails fills in the licenses and release dates of the given
+// releases from the details index. Releases it does not list keep empty
+// licenses and release dates.
+func (f CompactIndexFetcher) addVersionDetails(releases []Release) error {
+	details, err := NewReleaseFetcher(f.detailsIndex).Get()
+	if err != nil {
+		return fmt.Errorf("failed to read licenses and release dates from %s: %w", f.detailsIndex, err)
+	}
+
+	index := map[[2]string]Release{}
+	for _, detail := range details {
+		index[[2]string{detail.Version, detail.Platform}] = detail
+	}
+
+	for i, release := range releases {
+		detail, ok := index[[2]string{release.Version, release.Platform}]
+		if !ok {
+			continue
+		}
+
+		releases[i].Licenses = detail.Licenses
+		releases[i].CreatedAt = detail.CreatedAt
+	}
+
+	return nil
+}
+
+func (f CompactIndexFetcher) fetch(path string) ([]byte, error) {
+	response, err := http.Get(f.baseURL + path)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("compact index %s returned status %d", path, response.StatusCode)
+	}
+
+	return io.ReadAll(response.Body)
+}
+
+// Yanked lists the versions of the gem that were published and have been
+// yanked since, according to /versions.
+func (f CompactIndexFetcher) Yanked() ([]string, error) {
+	versions, err := f.fetch("/versions")
+	if err != nil {
+		return nil, err
+	}
+
+	_, yanked, _, err := f.parseVersions(versions)
+	if err != nil {
+		return nil, err
+	}
+
+	return yanked, nil
+}
+
+// parseVersions returns the versions of the gem that are listed in /versions
+// and have not been yanked since, the versions that have been yanked, and the
+// MD5 checksum of the most recent /info file. Each line has the form
+//
+//	bundler 2.4.0,2.4.1,-2.4.0 <md5>
+//
+// where a leading "-" marks a yanked version. A gem can appear on several
+// lines as new versions are appended.
+func (f CompactIndexFetcher) parseVersions(content []byte) (map[string]bool, []string, string, error) {
+	var (
+		active  = map[string]bool{}
+		yanked  []string
+		infoMD5 string
+		found   bool
+	)
+
+	for _, line := range lines(content) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != f.gem {
+			continue
+		}
+
+		found = true
+		for _, version := range strings.Split(fields[1], ",") {
+			if version, ok := strings.CutPrefix(version, "-"); ok {
+				delete(active, version)
+				yanked = append(yanked, version)
+				continue
+			}
+
+			active[version] = true
+		}
+		infoMD5 = fields[2]
+	}
+
+	if !found {
+		return nil, nil, "", fmt.Errorf("compact index /versions does not list %s", f.gem)
+	}
+
+	// A version can be yanked and published again.
+	var stillYanked []string
+	seen := map[string]bool{}
+	for _, version := range yanked {
+		if !active[version] && !seen[version] {
+			seen[version] = true
+			stillYanked = append(stillYanked, version)
+		}
+	}
+
+	return active, stillYanked, infoMD5, nil
+}
+
+// lines returns the entries of a compact index file, which follow a header
+// that ends with a "---" line.
+func lines(content []byte) []string {
+	var (
+		result []string
+		header = bytes.Contains(content, []byte("---\n"))
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if header {
+			header = line != "---"
+			continue
+		}
+
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+
+	return result
+}
+
+// parseInfoLine parses an entry of an /info file, which has the form
+//
+//	2.4.22 |checksum:<sha256>,ruby:>= 2.6.0,rubygems:>= 3.0.1
+//
+// The version may carry a platform suffix such as "-java", dependencies may
+// be listed between the version and the "|", and a requirement made of
+// several constraints joins them with "&".
+func parseInfoLine(line string) (Release, error) {
+	head, requirements, ok := strings.Cut(line, "|")
+	if !ok {
+		return Release{}, fmt.Errorf("missing requirements in %q", line)
+	}
+
+	fields := strings.Fields(head)
+	if len(fields) == 0 {
+		return Release{}, fmt.Errorf("missing version in %q", line)
+	}
+
+	release := Release{Version: fields[0], Platform: "ruby"}
+	if version, platform, ok := strings.Cut(fields[0], "-"); ok {
+		release.Version, release.Platform = version, platform
+	}
+
+	for _, requirement := range strings.Split(requirements, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(requirement), ":")
+		if !ok {
+			continue
+		}
+
+		value = strings.Join(strings.Split(value, "&"), ", ")
+		switch name {
+		case "checksum":
+			release.SHA256 = value
+		case "ruby":
+			release.RubyVersion = value
+		case "rubygems":
+			release.RubygemsVersion = value
+		}
+	}
+
+	if release.SHA256 == "" {
+		return Release{}, fmt.Errorf("missing checksum for %s", fields[0])
+	}
+
+	// RubyGems considers any version with a letter in it a prerelease.
+	release.Prerelease = strings.IndexFunc(release.Version, unicode.IsLetter) >= 0
+
+	return release, nil
+}
@@ -0,0 +1,315 @@
+package internal_test
+
+import (
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testCompactIndexFetcher(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		info        string
+		versions    string
+		versionsAPI string
+		server      *httptest.Server
+		fetcher     internal.CompactIndexFetcher
+	)
+
+	it.Before(func() {
+		info = compactIndexInfo
+		versions = fmt.Sprintf(compactIndexVersions, md5.Sum([]byte(compactIndexInfo)))
+		versionsAPI = compactIndexVersionsAPI
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/versions":
+				fmt.Fprint(w, versions)
+			case "/info/bundler":
+				fmt.Fprint(w, info)
+			case "/api/v1/versions/bundler.json":
+				fmt.Fprint(w, versionsAPI)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		fetcher = internal.NewCompactIndexFetcher(server.URL)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	context("Get", func() {
+		it("returns the releases that are neither yanked nor prereleases", func() {
+			releases, err := fetcher.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(Equal([]internal.Release{
+				{
+					Version:         "2.3.25",
+					SHA256:          "fd81ec4635c4189b66fd0789537d5cb38b3810b70765f6e1e82dda15b97591ad",
+					Platform:        "ruby",
+					RubyVersion:     ">= 2.3.0",
+					RubygemsVersion: ">= 2.5.2",
+				},
+				{
+					Version:         "2.4.0",
+					SHA256:          "2222222222222222222222222222222222222222222222222222222222222222",
+					Platform:        "ruby",
+					RubyVersion:     ">= 2.6.0, < 4",
+					RubygemsVersion: ">= 3.0.1",
+				},
+				{
+					Version:     "2.4.1",
+					SHA256:      "3333333333333333333333333333333333333333333333333333333333333333",
+					Platform:    "java",
+					RubyVersion: ">= 2.6.0",
+				},
+			}))
+		})
+
+		context("when the server serves only the compact index", func() {
+			it.Before(func() {
+				compactOnly := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/versions":
+						fmt.Fprint(w, versions)
+					case "/info/bundler":
+						fmt.Fprint(w, info)
+					default:
+						w.WriteHeader(http.StatusNotFound)
+					}
+				}))
+				t.Cleanup(compactOnly.Close)
+
+				fetcher = internal.NewCompactIndexFetcher(compactOnly.URL)
+			})
+
+			it("returns the releases without licenses and release dates", func() {
+				releases, err := fetcher.Get()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(releases).To(HaveLen(3))
+				for _, release := range releases {
+					Expect(release.Licenses).To(BeEmpty())
+					Expect(release.CreatedAt).To(BeNil())
+				}
+			})
+		})
+
+		context("when a details index is configured", func() {
+			it.Before(func() {
+				fetcher = fetcher.WithDetailsIndex(server.URL + "/api/v1/versions/bundler.json")
+			})
+
+			it("reads the licenses and release dates from it", func() {
+				releases, err := fetcher.Get()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(releases).To(Equal([]internal.Release{
+					{
+						Version:         "2.3.25",
+						Licenses:        []string{"MIT"},
+						SHA256:          "fd81ec4635c4189b66fd0789537d5cb38b3810b70765f6e1e82dda15b97591ad",
+						Platform:        "ruby",
+						RubyVersion:     ">= 2.3.0",
+						RubygemsVersion: ">= 2.5.2",
+						CreatedAt:       dateOf(2022, time.November, 2),
+					},
+					{
+						Version:         "2.4.0",
+						Licenses:        []string{"MIT"},
+						SHA256:          "2222222222222222222222222222222222222222222222222222222222222222",
+						Platform:        "ruby",
+						RubyVersion:     ">= 2.6.0, < 4",
+						RubygemsVersion: ">= 3.0.1",
+						CreatedAt:       dateOf(2022, time.December, 24),
+					},
+					{
+						Version:     "2.4.1",
+						Licenses:    []string{"MIT"},
+						SHA256:      "3333333333333333333333333333333333333333333333333333333333333333",
+						Platform:    "java",
+						RubyVersion: ">= 2.6.0",
+						CreatedAt:   dateOf(2022, time.December, 25),
+					},
+				}))
+			})
+
+			context("when it does not list a release", func() {
+				it.Before(func() {
+					versionsAPI = `[{"number": "2.3.25", "platform": "ruby", "licenses": ["MIT"], "sha": "fd81ec4635c4189b66fd0789537d5cb38b3810b70765f6e1e82dda15b97591ad"}]`
+				})
+
+				it("leaves the licenses and release date of that release empty", func() {
+					releases, err := fetcher.Get()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(releases).To(HaveLen(3))
+					Expect(releases[0].Licenses).To(Equal([]string{"MIT"}))
+					Expect(releases[1].Licenses).To(BeEmpty())
+					Expect(releases[1].CreatedAt).To(BeNil())
+				})
+			})
+		})
+
+		context("when the index URL points at an endpoint", func() {
+			it("reads the index from the root of the server", func() {
+				for _, index := range []string{server.URL + "/versions", server.URL + "/info/bundler", server.URL + "/"} {
+					releases, err := internal.NewCompactIndexFetcher(index).Get()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(releases).To(HaveLen(3))
+				}
+			})
+		})
+
+		context("error cases", func() {
+			context("when an endpoint returns an error status", func() {
+				it.Before(func() {
+					fetcher = internal.NewCompactIndexFetcher(server.URL + "/missing")
+				})
+
+				it("returns a descriptive error", func() {
+					_, err := fetcher.Get()
+					Expect(err).To(MatchError("compact index /versions returned status 404"))
+				})
+			})
+
+			context("when /versions does not list bundler", func() {
+				it.Before(func() {
+					versions = "created_at: 2024-04-01T00:00:05Z\n---\nrails 7.1.0 0123\n"
+				})
+
+				it("returns a descriptive error", func() {
+					_, err := fetcher.Get()
+					Expect(err).To(MatchError("compact index /versions does not list bundler"))
+				})
+			})
+
+			context("when the info file does not match its checksum", func() {
+				it.Before(func() {
+					info += "2.5.0 |checksum:4444444444444444444444444444444444444444444444444444444444444444\n"
+				})
+
+				it("returns a descriptive error", func() {
+					_, err := fetcher.Get()
+					Expect(err).To(MatchError("compact index /info/bundler does not match the checksum in /versions"))
+				})
+			})
+
+			context("when an info line has no checksum", func() {
+				it.Before(func() {
+					info = "---\n2.3.25 |ruby:>= 2.3.0\n"
+					versions = fmt.Sprintf(compactIndexVersions, md5.Sum([]byte(info)))
+				})
+
+				it("returns a descriptive error", func() {
+					_, err := fetcher.Get()
+					Expect(err).To(MatchError("compact index /info/bundler line 1: missing checksum for 2.3.25"))
+				})
+			})
+
+			context("when the configured details index cannot be read", func() {
+				it.Before(func() {
+					versionsAPI = "not json"
+					fetcher = fetcher.WithDetailsIndex(server.URL + "/api/v1/versions/bundler.json")
+				})
+
+				it("returns a descriptive error", func() {
+					_, err := fetcher.Get()
+					Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("failed to read licenses and release dates from %s/api/v1/versions/bundler.json", server.URL))))
+				})
+			})
+
+			context("when no releases are left", func() {
+				it.Before(func() {
+					versions = fmt.Sprintf("---\nbundler 2.3.25,2.4.0,2.4.1-java %x\nbundler -2.3.25,-2.4.0,-2.4.1-java %x\n", md5.Sum([]byte(info)), md5.Sum([]byte(info)))
+				})
+
+				it("returns a descriptive error", func() {
+					_, err := fetcher.Get()
+					Expect(err).To(MatchError("no valid releases found"))
+				})
+			})
+		})
+	})
+
+	context("Yanked", func() {
+		it.Before(func() {
+			versions += "bundler -2.4.0,2.3.24,-2.3.25,-2.4.0 0123456789abcdef0123456789abcdef\n"
+		})
+
+		it("returns the versions that are still yanked", func() {
+			yanked, err := fetcher.Yanked()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(yanked).To(Equal([]string{"2.4.0", "2.3.25"}))
+		})
+
+		context("when /versions cannot be fetched", func() {
+			it.Before(func() {
+				fetcher = internal.NewCompactIndexFetcher(server.URL + "/missing")
+			})
+
+			it("returns an error", func() {
+				_, err := fetcher.Yanked()
+				Expect(err).To(MatchError("compact index /versions returned status 404"))
+			})
+		})
+	})
+
+	context("NewReleaseIndex", func() {
+		it("picks the protocol from the URL", func() {
+			index, err := internal.NewReleaseIndex("https://rubygems.org/api/v1/versions/bundler.json", internal.ProtocolAuto)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(BeAssignableToTypeOf(internal.ReleaseFetcher{}))
+
+			index, err = internal.NewReleaseIndex("https://gems.example.com", internal.ProtocolAuto)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(BeAssignableToTypeOf(internal.CompactIndexFetcher{}))
+		})
+
+		it("uses the given protocol", func() {
+			index, err := internal.NewReleaseIndex("https://gems.example.com/bundler.json", internal.ProtocolCompactIndex)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(BeAssignableToTypeOf(internal.CompactIndexFetcher{}))
+
+			index, err = internal.NewReleaseIndex("https://gems.example.com/versions", internal.ProtocolJSON)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(BeAssignableToTypeOf(internal.ReleaseFetcher{}))
+		})
+
+		it("rejects unknown protocols", func() {
+			_, err := internal.NewReleaseIndex("https://gems.example.com", "graphql")
+			Expect(err).To(MatchError(`unsupported release index protocol "graphql" (must be "auto", "json" or "compact-index")`))
+		})
+	})
+}
+
+const compactIndexVersions = `created_at: 2024-04-01T00:00:05Z
+---
+rails 7.1.0 0123456789abcdef0123456789abcdef
+bundler 2.3.24,2.3.25,2.4.0.pre.1 0123456789abcdef0123456789abcdef
+bundler 2.4.0,2.4.1-java,-2.3.24 %x
+`
+
+const compactIndexInfo = `---
+2.3.24 |checksum:eaa2eb8c3892e870f979252b2196bd77eb551e1dbf3cdc4eb164ba01ec4438c4,ruby:>= 2.3.0,rubygems:>= 2.5.2
+2.3.25 |checksum:fd81ec4635c4189b66fd0789537d5cb38b3810b70765f6e1e82dda15b97591ad,ruby:>= 2.3.0,rubygems:>= 2.5.2
+2.4.0.pre.1 |checksum:1111111111111111111111111111111111111111111111111111111111111111,ruby:>= 2.6.0
+2.4.0 |checksum:2222222222222222222222222222222222222222222222222222222222222222,ruby:>= 2.6.0&< 4,rubygems:>= 3.0.1
+2.4.1-java some-dep:>= 1.0&< 2 |checksum:3333333333333333333333333333333333333333333333333333333333333333,ruby:>= 2.6.0
+`
+
+const compactIndexVersionsAPI = `[
+  {"number": "2.4.1", "platform": "java", "licenses": ["MIT"], "created_at": "2022-12-25T00:00:00Z", "sha": "3333333333333333333333333333333333333333333333333333333333333333"},
+  {"number": "2.4.0", "platform": "ruby", "licenses": ["MIT"], "created_at": "2022-12-24T00:00:00Z", "sha": "2222222222222222222222222222222222222222222222222222222222222222"},
+  {"number": "2.4.0.pre.1", "platform": "ruby", "prerelease": true, "licenses": ["MIT"], "created_at": "2022-12-01T00:00:00Z", "sha": "1111111111111111111111111111111111111111111111111111111111111111"},
+  {"number": "2.3.25", "platform": "ruby", "licenses": ["MIT"], "created_at": "2022-11-02T00:00:00Z", "sha": "fd81ec4635c4189b66fd0789537d5cb38b3810b70765f6e1e82dda15b97591ad"}
+]`
@@ -0,0 +1,512 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// compiledPrefix is the directory the artifact is packaged under. The
+// dependency metadata strips it with strip-components = 2.
+const compiledPrefix = "input/bundler"
+
+// CompiledArtifact is a packaged dependency and the file holding its
+// checksum, along with the gem it was packaged from.
+type CompiledArtifact struct {
+	Path           string
+	ChecksumPath   string
+	Checksum       string
+	Source         string
+	SourceChecksum string
+}
+
+// GemCompiler packages a gem the way `gem install --env-shebang` lays it out
+// in a GEM_HOME, without needing Ruby. This only works for gems without native
+// extensions, which is the case for Bundler.
+//
+// The artifacts are reproducible: the same gem always packages into the same
+// bytes. Entries are sorted, owned by root with normalized modes and dated
+// with the modification time, which defaults to the date of the gem.
+type GemCompiler struct {
+	name             string
+	sourceURIPattern string
+	transport        Transport
+	modTime          *time.Time
+}
+
+func NewGemCompiler(transport Transport) GemCompiler {
+	return GemCompiler{
+		name:             depID,
+		sourceURIPattern: sourceURI,
+		transport:        transport,
+	}
+}
+
+// WithModTime sets the modification time of every entry of the artifacts,
+// e.g. to $SOURCE_DATE_EPOCH.
+func (c GemCompiler) WithModTime(modTime time.Time) GemCompiler {
+	modTime = modTime.UTC().Truncate(time.Second)
+	c.modTime = &modTime
+	return c
+}
+
+// LookupSourceDateEpoch reads $SOURCE_DATE_EPOCH, the number of seconds since
+// the Unix epoch that reproducible builds date their output with. It returns
+// nil when the variable is not set.
+func LookupSourceDateEpoch() (*time.Time, error) {
+	value, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return nil, fmt.Errorf("invalid value for SOURCE_DATE_EPOCH: %q (must be a non-negative number of seconds)", value)
+	}
+
+	epoch := time.Unix(seconds, 0).UTC()
+	return &epoch, nil
+}
+
+// Compile downloads the gem of the version and writes
+// bundler-<target>-<version>-<sha8>.tgz along with a .checksum file holding
+// its SHA256 checksum to the output directory.
+func (c GemCompiler) Compile(version, target, outputDir string) (CompiledArtifact, error) {
+	uri := fmt.Sprintf(c.sourceURIPattern, version)
+	gem, err := c.transport.Drop("", uri)
+	if err != nil {
+		return CompiledArtifact{}, fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	defer gem.Close()
+
+	source, err := io.ReadAll(gem)
+	if err != nil {
+		return CompiledArtifact{}, fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	sourceSum := sha256.Sum256(source)
+
+	var artifact bytes.Buffer
+	err = c.Package(bytes.NewReader(source), version, &artifact)
+	if err != nil {
+		return CompiledArtifact{}, err
+	}
+
+	sum := sha256.Sum256(artifact.Bytes())
+	checksum := hex.EncodeToString(sum[:])
+
+	name := fmt.Sprintf("%s-%s-%s-%s.tgz", c.name, target, version, checksum[:8])
+	compiled := CompiledArtifact{
+		Path:           filepath.Join(outputDir, name),
+		ChecksumPath:   filepath.Join(outputDir, name+".checksum"),
+		Checksum:       "sha256:" + checksum,
+		Source:         uri,
+		SourceChecksum: "sha256:" + hex.EncodeToString(sourceSum[:]),
+	}
+
+	err = os.WriteFile(compiled.Path, artifact.Bytes(), 0644)
+	if err != nil {
+		return CompiledArtifact{}, err
+	}
+
+	err = os.WriteFile(compiled.ChecksumPath, []byte(compiled.Checksum+"\n"), 0644)
+	if err != nil {
+		return CompiledArtifact{}, err
+	}
+
+	return compiled, nil
+}
+
+// Package reads a .gem file and writes the gzipped tarball of the GEM_HOME it
+// installs into: the unpacked gem under gems/, its specification under
+// specifications/ and a binstub for each of its executables under bin/.
+func (c GemCompiler) Package(gem io.Reader, version string, output io.Writer) error {
+	spec, data, err := c.readGem(gem)
+	if err != nil {
+		return err
+	}
+
+	if spec.Name != c.name || spec.Version.Version != version {
+		return fmt.Errorf("gem is %s %s, expected %s %s", spec.Name, spec.Version.Version, c.name, version)
+	}
+
+	if len(spec.Extensions) > 0 {
+		return fmt.Errorf("gem %s %s has native extensions and cannot be compiled without Ruby", spec.Name, version)
+	}
+
+	fullName := fmt.Sprintf("%s-%s", spec.Name, version)
+	archive := newArchiveWriter(output, c.modTimeOf(spec))
+
+	executables := append([]string{}, spec.Executables...)
+	sort.Strings(executables)
+	for _, executable := range executables {
+		err = archive.file(path.Join("bin", executable), 0755, []byte(binstub(spec.Name, executable)))
+		if err != nil {
+			return err
+		}
+	}
+
+	// gem install creates these directories even when they stay empty.
+	for _, dir := range []string{"build_info", "cache", "doc", "extensions"} {
+		err = archive.dir(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = archive.copy(path.Join("gems", fullName), data)
+	if err != nil {
+		return err
+	}
+
+	err = archive.dir("plugins")
+	if err != nil {
+		return err
+	}
+
+	err = archive.file(path.Join("specifications", fullName+".gemspec"), 0644, []byte(spec.ruby()))
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// modTimeOf returns the modification time of the entries of an artifact: the
+// configured one, or else the date of the gem.
+func (c GemCompiler) modTimeOf(spec gemSpecification) time.Time {
+	if c.modTime != nil {
+		return *c.modTime
+	}
+
+	if len(spec.Date) >= 10 {
+		date, err := time.Parse(time.DateOnly, spec.Date[:10])
+		if err == nil {
+			return date
+		}
+	}
+
+	return time.Unix(0, 0).UTC()
+}
+
+// Verify compiles the version twice and checks that both builds produce the
+// same artifact. It returns the checksum of the artifact.
+func (c GemCompiler) Verify(version, target string) (string, error) {
+	var checksums []string
+	for i := 0; i < 2; i++ {
+		dir, err := os.MkdirTemp("", "bundler-verify")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(dir)
+
+		artifact, err := c.Compile(version, target, dir)
+		if err != nil {
+			return "", err
+		}
+
+		checksums = append(checksums, artifact.Checksum)
+	}
+
+	if checksums[0] != checksums[1] {
+		return "", fmt.Errorf("%s %s is not reproducible: the first build has checksum %s and the second %s", c.name, version, checksums[0], checksums[1])
+	}
+
+	return checksums[0], nil
+}
+
+// readGem returns the specification of a .gem file, which is a tar archive
+// holding metadata.gz, data.tar.gz and checksums.yaml.gz, and the contents of
+// its data.tar.gz. The checksums are verified when they are present.
+func (c GemCompiler) readGem(gem io.Reader) (gemSpecification, []byte, error) {
+	entries := map[string][]byte{}
+
+	reader := tar.NewReader(gem)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return gemSpecification{}, nil, fmt.Errorf("failed to read gem: %w", err)
+		}
+
+		switch header.Name {
+		case "metadata.gz", "data.tar.gz", "checksums.yaml.gz":
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				return gemSpecification{}, nil, fmt.Errorf("failed to read gem: %w", err)
+			}
+			entries[header.Name] = content
+		}
+	}
+
+	for _, name := range []string{"metadata.gz", "data.tar.gz"} {
+		if _, ok := entries[name]; !ok {
+			return gemSpecification{}, nil, fmt.Errorf("failed to read gem: missing %s", name)
+		}
+	}
+
+	if content, ok := entries["checksums.yaml.gz"]; ok {
+		checksums, err := gunzip(content)
+		if err != nil {
+			return gemSpecification{}, nil, fmt.Errorf("failed to read gem checksums: %w", err)
+		}
+
+		var sums struct {
+			SHA256 map[string]string `yaml:"SHA256"`
+		}
+		err = yaml.Unmarshal(checksums, &sums)
+		if err != nil {
+			return gemSpecification{}, nil, fmt.Errorf("failed to read gem checksums: %w", err)
+		}
+
+		for name, expected := range sums.SHA256 {
+			content, ok := entries[name]
+			if !ok {
+				continue
+			}
+
+			sum := sha256.Sum256(content)
+			if hex.EncodeToString(sum[:]) != expected {
+				return gemSpecification{}, nil, fmt.Errorf("checksum mismatch for %s in gem", name)
+			}
+		}
+	}
+
+	metadata, err := gunzip(entries["metadata.gz"])
+	if err != nil {
+		return gemSpecification{}, nil, fmt.Errorf("failed to read gem metadata: %w", err)
+	}
+
+	var spec gemSpecification
+	err = yaml.Unmarshal(metadata, &spec)
+	if err != nil {
+		return gemSpecification{}, nil, fmt.Errorf("failed to parse gem metadata: %w", err)
+	}
+
+	return spec, entries["data.tar.gz"], nil
+}
+
+func gunzip(content []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// archiveWriter writes a gzipped tarball under compiledPrefix, adding the
+// entries of parent directories before their contents. Neither the gzip
+// header nor the tar headers depend on the time or the user of the build.
+type archiveWriter struct {
+	gzip    *gzip.Writer
+	tar     *tar.Writer
+	dirs    map[string]bool
+	modTime time.Time
+}
+
+func newArchiveWriter(output io.Writer, modTime time.Time) *archiveWriter {
+	gz := gzip.NewWriter(output)
+	gz.Header = gzip.Header{OS: 255}
+
+	return &archiveWriter{
+		gzip:    gz,
+		tar:     tar.NewWriter(gz),
+		dirs:    map[string]bool{},
+		modTime: modTime.UTC().Truncate(time.Second),
+	}
+}
+
+func (a *archiveWriter) writeHeader(header *tar.Header) error {
+	header.ModTime = a.modTime
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+	header.Format = tar.FormatPAX
+
+	return a.tar.WriteHeader(header)
+}
+
+func (a *archiveWriter) dir(name string) error {
+	name = path.Join(compiledPrefix, name)
+
+	var parents []string
+	for dir := name; dir != "." && !a.dirs[dir]; dir = path.Dir(dir) {
+		parents = append(parents, dir)
+	}
+
+	for i := len(parents) - 1; i >= 0; i-- {
+		a.dirs[parents[i]] = true
+		err := a.writeHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     parents[i] + "/",
+			Mode:     0755,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *archiveWriter) file(name string, mode int64, content []byte) error {
+	err := a.dir(path.Dir(name))
+	if err != nil {
+		return err
+	}
+
+	err = a.writeHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(compiledPrefix, name),
+		Mode:     mode,
+		Size:     int64(len(content)),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = a.tar.Write(content)
+	return err
+}
+
+// copy adds the entries of a gzipped tarball under the given directory,
+// sorted by name.
+func (a *archiveWriter) copy(dir string, tgz []byte) error {
+	data, err := gunzip(tgz)
+	if err != nil {
+		return fmt.Errorf("failed to read gem data: %w", err)
+	}
+
+	type entry struct {
+		header  *tar.Header
+		content []byte
+	}
+
+	var entries []entry
+	reader := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read gem data: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("failed to read gem data: %s is outside of the gem", header.Name)
+		}
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read gem data: %w", err)
+		}
+
+		header.Name = name
+		entries = append(entries, entry{header: header, content: content})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].header.Name < entries[j].header.Name
+	})
+
+	err = a.dir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := path.Join(dir, e.header.Name)
+		switch e.header.Typeflag {
+		case tar.TypeDir:
+			err = a.dir(name)
+		case tar.TypeReg:
+			mode := int64(0644)
+			if e.header.Mode&0111 != 0 {
+				mode = 0755
+			}
+			err = a.file(name, mode, e.content)
+		case tar.TypeSymlink:
+			err = a.dir(path.Dir(name))
+			if err == nil {
+				err = a.writeHeader(&tar.Header{
+					Typeflag: tar.TypeSymlink,
+					Name:     path.Join(compiledPrefix, name),
+					Linkname: e.header.Linkname,
+					Mode:     0777,
+				})
+			}
+		default:
+			err = fmt.Errorf("failed to read gem data: %s has unsupported type %q", e.header.Name, e.header.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *archiveWriter) Close() error {
+	err := a.tar.Close()
+	if err != nil {
+		return err
+	}
+
+	return a.gzip.Close()
+}
+
+// binstub returns the executable that RubyGems generates for an executable
+// of a gem when it is installed with --env-shebang.
+func binstub(gem, executable string) string {
+	versionRequirement := "version = str"
+	if gem == "bundler" {
+		versionRequirement += "\n    ENV['BUNDLER_VERSION'] = str"
+	}
+
+	return fmt.Sprintf(`#!/usr/bin/env ruby
+#
+# This file was generated by RubyGems.
+#
+# The application '%[1]s' is installed as part of a gem, and
+# this file is here to facilitate running it.
+#
+
+require 'rubygems'
+
+version = ">= 0.a"
+
+str = ARGV.first
+if str
+  str = str.b[/\A_(.*)_\z/, 1]
+  if str and Gem::Version.correct?(str)
+    %[3]s
+
+    ARGV.shift
+  end
+end
+
+if Gem.respond_to?(:activate_bin_path)
+load Gem.activate_bin_path('%[1]s', '%[2]s', version)
+else
+gem %[4]s, version
+load Gem.bin_path(%[4]s, %[5]s, version)
+end
+`, gem, executable, versionRequirement, rubyString(gem), rubyString(executable))
+}
@@ -0,0 +1,521 @@
+package internal_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testGemCompiler(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		transport *fakes.Transport
+		compiler  internal.GemCompiler
+		gem       []byte
+	)
+
+	it.Before(func() {
+		gem = buildGem(t, gemMetadata, map[string]string{
+			"exe/bundle":             "#!/usr/bin/env ruby\nload File.expand_path(\"bundler\", __dir__)\n",
+			"exe/bundler":            "#!/usr/bin/env ruby\nrequire \"bundler/friendly_errors\"\n",
+			"lib/bundler.rb":         "module Bundler; end\n",
+			"lib/bundler/version.rb": "module Bundler; VERSION = \"2.7.2\"; end\n",
+		}, true)
+
+		transport = &fakes.Transport{}
+		transport.DropCall.Stub = func(string, string) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(gem)), nil
+		}
+
+		compiler = internal.NewGemCompiler(transport)
+	})
+
+	context("Package", func() {
+		it("lays the gem out like gem install does", func() {
+			var artifact bytes.Buffer
+			Expect(compiler.Package(bytes.NewReader(gem), "2.7.2", &artifact)).To(Succeed())
+
+			files := readArtifact(t, artifact.Bytes())
+			var names []string
+			for _, file := range files {
+				names = append(names, file.name)
+			}
+
+			Expect(names).To(Equal([]string{
+				"input/",
+				"input/bundler/",
+				"input/bundler/bin/",
+				"input/bundler/bin/bundle",
+				"input/bundler/bin/bundler",
+				"input/bundler/build_info/",
+				"input/bundler/cache/",
+				"input/bundler/doc/",
+				"input/bundler/extensions/",
+				"input/bundler/gems/",
+				"input/bundler/gems/bundler-2.7.2/",
+				"input/bundler/gems/bundler-2.7.2/exe/",
+				"input/bundler/gems/bundler-2.7.2/exe/bundle",
+				"input/bundler/gems/bundler-2.7.2/exe/bundler",
+				"input/bundler/gems/bundler-2.7.2/lib/",
+				"input/bundler/gems/bundler-2.7.2/lib/bundler.rb",
+				"input/bundler/gems/bundler-2.7.2/lib/bundler/",
+				"input/bundler/gems/bundler-2.7.2/lib/bundler/version.rb",
+				"input/bundler/plugins/",
+				"input/bundler/specifications/",
+				"input/bundler/specifications/bundler-2.7.2.gemspec",
+			}))
+
+			bundle := files[3]
+			Expect(bundle.mode).To(Equal(int64(0755)))
+			Expect(bundle.content).To(HavePrefix("#!/usr/bin/env ruby\n"))
+			Expect(bundle.content).To(ContainSubstring("    version = str\n    ENV['BUNDLER_VERSION'] = str\n"))
+			Expect(bundle.content).To(ContainSubstring("load Gem.activate_bin_path('bundler', 'bundle', version)\n"))
+			Expect(bundle.content).To(ContainSubstring(`load Gem.bin_path("bundler", "bundle", version)`))
+
+			Expect(files[13].content).To(Equal("#!/usr/bin/env ruby\nrequire \"bundler/friendly_errors\"\n"))
+
+			Expect(files[20].content).To(Equal(`# -*- encoding: utf-8 -*-
+# stub: bundler 2.7.2 ruby lib
+
+Gem::Specification.new do |s|
+  s.name = "bundler".freeze
+  s.version = "2.7.2".freeze
+
+  s.required_rubygems_version = Gem::Requirement.new(">= 3.4.1".freeze) if s.respond_to? :required_rubygems_version=
+  s.metadata = { "bug_tracker_uri" => "https://github.com/rubygems/rubygems/issues?q=is%3Aopen+is%3Aissue+label%3ABundler".freeze, "homepage_uri" => "https://bundler.io/".freeze } if s.respond_to? :metadata=
+  s.require_paths = ["lib".freeze]
+  s.authors = ["Andr\u00E9 Arko".freeze, "Samuel Giddins".freeze]
+  s.bindir = "exe".freeze
+  s.date = "1980-01-02"
+  s.description = "Bundler manages an application's dependencies \#{through} its entire life".freeze
+  s.email = ["team@bundler.io".freeze]
+  s.executables = ["bundle".freeze, "bundler".freeze]
+  s.homepage = "https://bundler.io".freeze
+  s.licenses = ["MIT".freeze]
+  s.required_ruby_version = Gem::Requirement.new(">= 3.2.0".freeze)
+  s.rubygems_version = "3.6.9".freeze
+  s.summary = "The best way to manage your application's dependencies".freeze
+end
+`))
+		})
+
+		it("packages reproducibly", func() {
+			var first, second bytes.Buffer
+			Expect(compiler.Package(bytes.NewReader(gem), "2.7.2", &first)).To(Succeed())
+			time.Sleep(time.Second)
+			Expect(compiler.Package(bytes.NewReader(gem), "2.7.2", &second)).To(Succeed())
+			Expect(first.Bytes()).To(Equal(second.Bytes()))
+
+			// The gzip header has no modification time and an unknown OS.
+			Expect(first.Bytes()[4:8]).To(Equal([]byte{0, 0, 0, 0}))
+			Expect(first.Bytes()[9]).To(Equal(byte(255)))
+
+			for _, file := range readArtifact(t, first.Bytes()) {
+				Expect(file.modTime).To(BeTemporally("==", time.Date(1980, time.January, 2, 0, 0, 0, 0, time.UTC)), file.name)
+				Expect(file.uid).To(Equal(0), file.name)
+
+				switch {
+				case strings.HasSuffix(file.name, "/"), strings.Contains(file.name, "/bin/"), strings.Contains(file.name, "/exe/"):
+					Expect(file.mode).To(Equal(int64(0755)), file.name)
+				default:
+					Expect(file.mode).To(Equal(int64(0644)), file.name)
+				}
+			}
+		})
+
+		context("when a modification time is set", func() {
+			it.Before(func() {
+				compiler = compiler.WithModTime(time.Unix(1700000000, 500))
+			})
+
+			it("dates the entries with it", func() {
+				var artifact bytes.Buffer
+				Expect(compiler.Package(bytes.NewReader(gem), "2.7.2", &artifact)).To(Succeed())
+
+				for _, file := range readArtifact(t, artifact.Bytes()) {
+					Expect(file.modTime).To(BeTemporally("==", time.Unix(1700000000, 0)), file.name)
+				}
+			})
+		})
+
+		context("when the gem has runtime dependencies", func() {
+			it("adds them to the specification", func() {
+				metadata := strings.Replace(gemMetadata, "dependencies: []\n", `dependencies:
+- !ruby/object:Gem::Dependency
+  name: thor
+  requirement: !ruby/object:Gem::Requirement
+    requirements:
+    - - "<"
+      - !ruby/object:Gem::Version
+        version: '2'
+    - - ">="
+      - !ruby/object:Gem::Version
+        version: 1.0.0
+  type: :runtime
+  prerelease: false
+`, 1)
+
+				var artifact bytes.Buffer
+				Expect(compiler.Package(bytes.NewReader(buildGem(t, metadata, nil, false)), "2.7.2", &artifact)).To(Succeed())
+
+				files := readArtifact(t, artifact.Bytes())
+				Expect(files[len(files)-1].content).To(HaveSuffix(`
+  s.specification_version = 4
+
+  s.add_runtime_dependency(%q<thor>.freeze, ["< 2".freeze, ">= 1.0.0".freeze])
+end
+`))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the gem is another version", func() {
+				it("returns an error", func() {
+					err := compiler.Package(bytes.NewReader(gem), "2.7.1", io.Discard)
+					Expect(err).To(MatchError("gem is bundler 2.7.2, expected bundler 2.7.1"))
+				})
+			})
+
+			context("when the gem has native extensions", func() {
+				it("returns an error", func() {
+					metadata := strings.Replace(gemMetadata, "extensions: []\n", "extensions:\n- ext/extconf.rb\n", 1)
+					err := compiler.Package(bytes.NewReader(buildGem(t, metadata, nil, false)), "2.7.2", io.Discard)
+					Expect(err).To(MatchError("gem bundler 2.7.2 has native extensions and cannot be compiled without Ruby"))
+				})
+			})
+
+			context("when the gem does not match its checksums", func() {
+				it("returns an error", func() {
+					corrupted := buildGem(t, gemMetadata, map[string]string{"lib/bundler.rb": ""}, true)
+
+					var entries []tarEntry
+					for _, entry := range readTar(t, corrupted) {
+						if entry.name == "checksums.yaml.gz" {
+							entry.content = string(gzipBytes(t, []byte("---\nSHA256:\n  data.tar.gz: 0000\n")))
+						}
+						entries = append(entries, entry)
+					}
+
+					err := compiler.Package(bytes.NewReader(writeTar(t, entries)), "2.7.2", io.Discard)
+					Expect(err).To(MatchError("checksum mismatch for data.tar.gz in gem"))
+				})
+			})
+
+			context("when the gem has no metadata", func() {
+				it("returns an error", func() {
+					err := compiler.Package(bytes.NewReader(writeTar(t, nil)), "2.7.2", io.Discard)
+					Expect(err).To(MatchError("failed to read gem: missing metadata.gz"))
+				})
+			})
+
+			context("when the gem data leaves the gem directory", func() {
+				it("returns an error", func() {
+					err := compiler.Package(bytes.NewReader(buildGem(t, gemMetadata, map[string]string{"../../bin/ruby": ""}, false)), "2.7.2", io.Discard)
+					Expect(err).To(MatchError("failed to read gem data: ../../bin/ruby is outside of the gem"))
+				})
+			})
+		})
+	})
+
+	context("Compile", func() {
+		var outputDir string
+
+		it.Before(func() {
+			outputDir = t.TempDir()
+		})
+
+		it("writes the artifact and its checksum", func() {
+			artifact, err := compiler.Compile("2.7.2", "jammy", outputDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(transport.DropCall.Receives.Uri).To(Equal("https://rubygems.org/downloads/bundler-2.7.2.gem"))
+
+			content, err := os.ReadFile(artifact.Path)
+			Expect(err).NotTo(HaveOccurred())
+
+			checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+			Expect(artifact.Checksum).To(Equal("sha256:" + checksum))
+			Expect(artifact.Path).To(Equal(filepath.Join(outputDir, fmt.Sprintf("bundler-jammy-2.7.2-%s.tgz", checksum[:8]))))
+			Expect(artifact.ChecksumPath).To(Equal(artifact.Path + ".checksum"))
+
+			content, err = os.ReadFile(artifact.ChecksumPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("sha256:" + checksum + "\n"))
+
+			Expect(artifact.Source).To(Equal("https://rubygems.org/downloads/bundler-2.7.2.gem"))
+			Expect(artifact.SourceChecksum).To(Equal(fmt.Sprintf("sha256:%x", sha256.Sum256(gem))))
+		})
+
+		context("failure cases", func() {
+			context("when the gem cannot be downloaded", func() {
+				it.Before(func() {
+					transport.DropCall.Stub = nil
+					transport.DropCall.Returns.Error = errors.New("failed to make request")
+				})
+
+				it("returns an error", func() {
+					_, err := compiler.Compile("2.7.2", "jammy", outputDir)
+					Expect(err).To(MatchError("failed to download https://rubygems.org/downloads/bundler-2.7.2.gem: failed to make request"))
+				})
+			})
+
+			context("when the output directory cannot be written to", func() {
+				it("returns an error", func() {
+					_, err := compiler.Compile("2.7.2", "jammy", filepath.Join(outputDir, "missing"))
+					Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+				})
+			})
+		})
+	})
+
+	context("Verify", func() {
+		it("returns the checksum of the reproducible artifact", func() {
+			checksum, err := compiler.Verify("2.7.2", "jammy")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checksum).To(HavePrefix("sha256:"))
+			Expect(transport.DropCall.CallCount).To(Equal(2))
+		})
+
+		context("when the builds differ", func() {
+			it.Before(func() {
+				gems := [][]byte{
+					gem,
+					buildGem(t, gemMetadata, map[string]string{"lib/bundler.rb": "module Bundler; end\n"}, true),
+				}
+
+				transport.DropCall.Stub = func(string, string) (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(gems[transport.DropCall.CallCount-1])), nil
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := compiler.Verify("2.7.2", "jammy")
+				Expect(err).To(MatchError(MatchRegexp(`^bundler 2\.7\.2 is not reproducible: the first build has checksum sha256:\w+ and the second sha256:\w+$`)))
+			})
+		})
+	})
+
+	context("LookupSourceDateEpoch", func() {
+		it("returns nothing when SOURCE_DATE_EPOCH is not set", func() {
+			t.Setenv("SOURCE_DATE_EPOCH", "")
+
+			epoch, err := internal.LookupSourceDateEpoch()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(epoch).To(BeNil())
+		})
+
+		it("reads SOURCE_DATE_EPOCH", func() {
+			t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+			epoch, err := internal.LookupSourceDateEpoch()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*epoch).To(Equal(time.Unix(1700000000, 0).UTC()))
+		})
+
+		it("rejects invalid values", func() {
+			t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+
+			_, err := internal.LookupSourceDateEpoch()
+			Expect(err).To(MatchError(`invalid value for SOURCE_DATE_EPOCH: "yesterday" (must be a non-negative number of seconds)`))
+		})
+	})
+}
+
+type tarEntry struct {
+	name    string
+	mode    int64
+	modTime time.Time
+	uid     int
+	content string
+}
+
+func readTar(t *testing.T, content []byte) []tarEntry {
+	var entries []tarEntry
+
+	reader := tar.NewReader(bytes.NewReader(content))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, tarEntry{
+			name:    header.Name,
+			mode:    header.Mode,
+			modTime: header.ModTime,
+			uid:     header.Uid,
+			content: string(body),
+		})
+	}
+
+	return entries
+}
+
+func readArtifact(t *testing.T, content []byte) []tarEntry {
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return readTar(t, data)
+}
+
+func writeTar(t *testing.T, entries []tarEntry) []byte {
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, entry := range entries {
+		mode := entry.mode
+		if mode == 0 {
+			mode = 0644
+		}
+
+		// Like files packaged on a developer machine, the entries are owned
+		// by a user and dated with the current time.
+		err := writer.WriteHeader(&tar.Header{
+			Name:    entry.name,
+			Mode:    mode,
+			Size:    int64(len(entry.content)),
+			ModTime: time.Now(),
+			Uid:     1000,
+			Uname:   "someone",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = writer.Write([]byte(entry.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+func gzipBytes(t *testing.T, content []byte) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(content); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// buildGem builds a .gem file from its metadata YAML and data files, the
+// executables of which are listed in exe/.
+func buildGem(t *testing.T, metadata string, files map[string]string, checksums bool) []byte {
+	var dataEntries []tarEntry
+	for name, content := range files {
+		mode := int64(0664)
+		if strings.HasPrefix(name, "exe/") {
+			mode = 0775
+		}
+		dataEntries = append(dataEntries, tarEntry{name: name, mode: mode, content: content})
+	}
+
+	metadataGz := gzipBytes(t, []byte(metadata))
+	dataGz := gzipBytes(t, writeTar(t, dataEntries))
+
+	entries := []tarEntry{
+		{name: "metadata.gz", content: string(metadataGz)},
+		{name: "data.tar.gz", content: string(dataGz)},
+	}
+
+	if checksums {
+		entries = append(entries, tarEntry{
+			name: "checksums.yaml.gz",
+			content: string(gzipBytes(t, []byte(fmt.Sprintf("---\nSHA256:\n  metadata.gz: %x\n  data.tar.gz: %x\n",
+				sha256.Sum256(metadataGz), sha256.Sum256(dataGz))))),
+		})
+	}
+
+	return writeTar(t, entries)
+}
+
+const gemMetadata = `--- !ruby/object:Gem::Specification
+name: bundler
+version: !ruby/object:Gem::Version
+  version: 2.7.2
+platform: ruby
+authors:
+- André Arko
+- Samuel Giddins
+bindir: exe
+cert_chain: []
+date: 1980-01-02 00:00:00.000000000 Z
+dependencies: []
+description: 'Bundler manages an application''s dependencies #{through} its entire life'
+email:
+- team@bundler.io
+executables:
+- bundle
+- bundler
+extensions: []
+extra_rdoc_files: []
+files:
+- exe/bundle
+- exe/bundler
+- lib/bundler.rb
+homepage: https://bundler.io
+licenses:
+- MIT
+metadata:
+  homepage_uri: https://bundler.io/
+  bug_tracker_uri: https://github.com/rubygems/rubygems/issues?q=is%3Aopen+is%3Aissue+label%3ABundler
+rdoc_options: []
+require_paths:
+- lib
+required_ruby_version: !ruby/object:Gem::Requirement
+  requirements:
+  - - ">="
+    - !ruby/object:Gem::Version
+      version: 3.2.0
+required_rubygems_version: !ruby/object:Gem::Requirement
+  requirements:
+  - - ">="
+    - !ruby/object:Gem::Version
+      version: 3.4.1
+requirements: []
+rubygems_version: 3.6.9
+specification_version: 4
+summary: The best way to manage your application's dependencies
+test_files: []
+`
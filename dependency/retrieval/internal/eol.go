@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// EOLCycle is a release cycle in the endoflife.date API format, e.g.
+// {"cycle": "2.7", "eol": "2026-07-01"}. EOL is either a date or a boolean.
+type EOLCycle struct {
+	Cycle string      `json:"cycle"`
+	EOL   interface{} `json:"eol"`
+}
+
+type EOLSchedule struct {
+	cycles []EOLCycle
+}
+
+// LoadEOLSchedule reads a file containing an array of release cycles in the
+// endoflife.date format.
+func LoadEOLSchedule(path string) (EOLSchedule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return EOLSchedule{}, fmt.Errorf("failed to read EOL data: %w", err)
+	}
+
+	var cycles []EOLCycle
+	err = json.Unmarshal(content, &cycles)
+	if err != nil {
+		return EOLSchedule{}, fmt.Errorf("failed to parse EOL data: %w", err)
+	}
+
+	for _, cycle := range cycles {
+		if date, ok := cycle.EOL.(string); ok {
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return EOLSchedule{}, fmt.Errorf("failed to parse EOL date of cycle %s: %w", cycle.Cycle, err)
+			}
+		}
+	}
+
+	return EOLSchedule{cycles: cycles}, nil
+}
+
+// DeprecationDate returns the end-of-life date of the most specific cycle
+// that contains the version, so that a "2.7" cycle takes precedence over a
+// "2" cycle. It returns nil when no cycle with an EOL date matches.
+func (s EOLSchedule) DeprecationDate(version string) *time.Time {
+	var (
+		match *time.Time
+		depth int
+	)
+
+	for _, cycle := range s.cycles {
+		if version != cycle.Cycle && !strings.HasPrefix(version, cycle.Cycle+".") {
+			continue
+		}
+
+		date, ok := cycle.EOL.(string)
+		if !ok {
+			continue
+		}
+
+		segments := len(strings.Split(cycle.Cycle, "."))
+		if match != nil && segments <= depth {
+			continue
+		}
+
+		eol, _ := time.Parse("2006-01-02", date)
+		match, depth = &eol, segments
+	}
+
+	return match
+}
@@ -0,0 +1,87 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testEOLSchedule(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "eol.json")
+		Expect(os.WriteFile(path, []byte(`[
+  {"cycle": "4", "releaseDate": "2025-12-17", "eol": "2028-03-31", "latest": "4.0.18"},
+  {"cycle": "2.7", "releaseDate": "2025-07-16", "eol": "2026-03-31", "latest": "2.7.2"},
+  {"cycle": "2", "releaseDate": "2018-12-19", "eol": "2027-03-31", "latest": "2.7.2"},
+  {"cycle": "1", "releaseDate": "2015-12-01", "eol": true, "latest": "1.17.3"}
+]`), 0600)).To(Succeed())
+	})
+
+	context("DeprecationDate", func() {
+		it("returns the EOL date of the most specific matching cycle", func() {
+			schedule, err := internal.LoadEOLSchedule(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.DeprecationDate("2.7.2")).To(Equal(dateOf(2026, time.March, 31)))
+			Expect(schedule.DeprecationDate("2.6.9")).To(Equal(dateOf(2027, time.March, 31)))
+			Expect(schedule.DeprecationDate("4.0.18")).To(Equal(dateOf(2028, time.March, 31)))
+		})
+
+		it("returns nil when no cycle has an EOL date", func() {
+			schedule, err := internal.LoadEOLSchedule(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.DeprecationDate("1.17.3")).To(BeNil())
+			Expect(schedule.DeprecationDate("5.0.0")).To(BeNil())
+			Expect(schedule.DeprecationDate("27.0.0")).To(BeNil())
+		})
+	})
+
+	context("error cases", func() {
+		context("when the file does not exist", func() {
+			it("returns an error", func() {
+				_, err := internal.LoadEOLSchedule(filepath.Join(t.TempDir(), "missing.json"))
+				Expect(err).To(MatchError(ContainSubstring("failed to read EOL data")))
+			})
+		})
+
+		context("when the file is not a JSON array", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`{}`), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := internal.LoadEOLSchedule(path)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse EOL data")))
+			})
+		})
+
+		context("when an EOL date is malformed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`[{"cycle": "2.7", "eol": "next year"}]`), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := internal.LoadEOLSchedule(path)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse EOL date of cycle 2.7")))
+			})
+		})
+	})
+}
+
+func dateOf(year int, month time.Month, day int) *time.Time {
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &date
+}
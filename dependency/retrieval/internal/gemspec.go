@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// gemSpecification is the subset of the Gem::Specification YAML in the
+// metadata.gz of a gem that an installed specification carries.
+type gemSpecification struct {
+	Name                    string            `yaml:"name"`
+	Version                 gemVersion        `yaml:"version"`
+	Platform                string            `yaml:"platform"`
+	Authors                 []string          `yaml:"authors"`
+	Bindir                  string            `yaml:"bindir"`
+	Date                    string            `yaml:"date"`
+	Dependencies            []gemDependency   `yaml:"dependencies"`
+	Description             string            `yaml:"description"`
+	Email                   gemStrings        `yaml:"email"`
+	Executables             []string          `yaml:"executables"`
+	Extensions              []string          `yaml:"extensions"`
+	Homepage                string            `yaml:"homepage"`
+	Licenses                []string          `yaml:"licenses"`
+	Metadata                map[string]string `yaml:"metadata"`
+	PostInstallMessage      string            `yaml:"post_install_message"`
+	RequirePaths            []string          `yaml:"require_paths"`
+	RequiredRubyVersion     gemRequirement    `yaml:"required_ruby_version"`
+	RequiredRubygemsVersion gemRequirement    `yaml:"required_rubygems_version"`
+	RubygemsVersion         string            `yaml:"rubygems_version"`
+	SpecificationVersion    int               `yaml:"specification_version"`
+	Summary                 string            `yaml:"summary"`
+}
+
+type gemVersion struct {
+	Version string `yaml:"version"`
+}
+
+// gemRequirement is a list of [operator, version] pairs.
+type gemRequirement struct {
+	Requirements [][]interface{} `yaml:"requirements"`
+}
+
+type gemDependency struct {
+	Name        string         `yaml:"name"`
+	Requirement gemRequirement `yaml:"requirement"`
+	Type        string         `yaml:"type"`
+}
+
+// gemStrings is a string or a list of strings, as the email of a gem can be
+// either.
+type gemStrings []string
+
+func (s *gemStrings) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*s = gemStrings{single}
+		return nil
+	}
+
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+
+	*s = list
+	return nil
+}
+
+// list returns the requirement as RubyGems lists it, e.g. [">= 2.6.0"].
+func (r gemRequirement) list() []string {
+	var list []string
+	for _, requirement := range r.Requirements {
+		if len(requirement) != 2 {
+			continue
+		}
+
+		operator, _ := requirement[0].(string)
+		version := ""
+		if v, ok := requirement[1].(map[string]interface{}); ok {
+			version = fmt.Sprint(v["version"])
+		}
+
+		list = append(list, fmt.Sprintf("%s %s", operator, version))
+	}
+
+	if len(list) == 0 {
+		return []string{">= 0"}
+	}
+
+	sort.Strings(list)
+	return list
+}
+
+// ruby returns the requirement as Gem::Specification#to_ruby writes it.
+func (r gemRequirement) ruby() string {
+	list := r.list()
+	if len(list) == 1 {
+		return fmt.Sprintf("Gem::Requirement.new(%s)", rubyCode(list[0]))
+	}
+
+	return fmt.Sprintf("Gem::Requirement.new(%s)", rubyCode(list))
+}
+
+// ruby returns the specification in the format that RubyGems writes to the
+// specifications directory of a GEM_HOME, which leaves out the file lists.
+func (s gemSpecification) ruby() string {
+	platform := s.Platform
+	if platform == "" {
+		platform = "ruby"
+	}
+
+	requirePaths := s.RequirePaths
+	if len(requirePaths) == 0 {
+		requirePaths = []string{"lib"}
+	}
+
+	lines := []string{
+		"# -*- encoding: utf-8 -*-",
+		fmt.Sprintf("# stub: %s %s %s %s", s.Name, s.Version.Version, platform, strings.Join(requirePaths, "\x00")),
+		"",
+		"Gem::Specification.new do |s|",
+		fmt.Sprintf("  s.name = %s", rubyCode(s.Name)),
+		fmt.Sprintf("  s.version = %s", rubyCode(s.Version.Version)),
+	}
+
+	if platform != "ruby" {
+		lines = append(lines, fmt.Sprintf("  s.platform = %s", rubyCode(platform)))
+	}
+
+	lines = append(lines, "", fmt.Sprintf("  s.required_rubygems_version = %s if s.respond_to? :required_rubygems_version=", s.RequiredRubygemsVersion.ruby()))
+
+	if len(s.Metadata) > 0 {
+		lines = append(lines, fmt.Sprintf("  s.metadata = %s if s.respond_to? :metadata=", rubyCode(s.Metadata)))
+	}
+
+	lines = append(lines, fmt.Sprintf("  s.require_paths = %s", rubyCode(requirePaths)))
+
+	// The remaining attributes follow in alphabetical order and are left out
+	// when they hold their default value, unless they are required.
+	if len(s.Authors) > 0 {
+		lines = append(lines, fmt.Sprintf("  s.authors = %s", rubyCode(s.Authors)))
+	}
+	if s.Bindir != "" && s.Bindir != "bin" {
+		lines = append(lines, fmt.Sprintf("  s.bindir = %s", rubyCode(s.Bindir)))
+	}
+	if len(s.Date) >= 10 {
+		lines = append(lines, fmt.Sprintf("  s.date = %s", rubyString(s.Date[:10])))
+	}
+	if s.Description != "" {
+		lines = append(lines, fmt.Sprintf("  s.description = %s", rubyCode(s.Description)))
+	}
+	if len(s.Email) > 0 {
+		lines = append(lines, fmt.Sprintf("  s.email = %s", rubyCode([]string(s.Email))))
+	}
+	if len(s.Executables) > 0 {
+		lines = append(lines, fmt.Sprintf("  s.executables = %s", rubyCode(s.Executables)))
+	}
+	if s.Homepage != "" {
+		lines = append(lines, fmt.Sprintf("  s.homepage = %s", rubyCode(s.Homepage)))
+	}
+	if len(s.Licenses) > 0 {
+		lines = append(lines, fmt.Sprintf("  s.licenses = %s", rubyCode(s.Licenses)))
+	}
+	if s.PostInstallMessage != "" {
+		lines = append(lines, fmt.Sprintf("  s.post_install_message = %s", rubyCode(s.PostInstallMessage)))
+	}
+	if requirement := s.RequiredRubyVersion.list(); len(requirement) != 1 || requirement[0] != ">= 0" {
+		lines = append(lines, fmt.Sprintf("  s.required_ruby_version = %s", s.RequiredRubyVersion.ruby()))
+	}
+	lines = append(lines, fmt.Sprintf("  s.rubygems_version = %s", rubyCode(s.RubygemsVersion)))
+	lines = append(lines, fmt.Sprintf("  s.summary = %s", rubyCode(s.Summary)))
+
+	if len(s.Dependencies) > 0 {
+		specificationVersion := s.SpecificationVersion
+		if specificationVersion == 0 {
+			specificationVersion = 4
+		}
+
+		lines = append(lines, "", fmt.Sprintf("  s.specification_version = %d", specificationVersion), "")
+		for _, dependency := range s.Dependencies {
+			kind := strings.TrimPrefix(dependency.Type, ":")
+			if kind == "" {
+				kind = "runtime"
+			}
+
+			lines = append(lines, fmt.Sprintf("  s.add_%s_dependency(%%q<%s>.freeze, %s)", kind, dependency.Name, rubyCode(dependency.Requirement.list())))
+		}
+	}
+
+	lines = append(lines, "end", "")
+
+	return strings.Join(lines, "\n")
+}
+
+// rubyCode formats a value the way Gem::Specification#ruby_code does.
+func rubyCode(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return rubyString(v) + ".freeze"
+	case []string:
+		var items []string
+		for _, item := range v {
+			items = append(items, rubyCode(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]string:
+		var keys []string
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var items []string
+		for _, key := range keys {
+			items = append(items, fmt.Sprintf("%s => %s", rubyString(key), rubyCode(v[key])))
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	default:
+		panic(fmt.Sprintf("unsupported value %#v", value))
+	}
+}
+
+// rubyString quotes a string the way String#dump does, so that it can be
+// read back by Ruby without interpolation.
+func rubyString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+
+	for i, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '#':
+			if i+1 < len(s) && strings.ContainsRune("{$@", rune(s[i+1])) {
+				b.WriteByte('\\')
+			}
+			b.WriteByte('#')
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == '\v':
+			b.WriteString(`\v`)
+		case r == '\a':
+			b.WriteString(`\a`)
+		case r == '\b':
+			b.WriteString(`\b`)
+		case r == 0x1b:
+			b.WriteString(`\e`)
+		case r == utf8.RuneError:
+			fmt.Fprintf(&b, `\x%02X`, s[i])
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02X`, r)
+		case r < 0x80:
+			b.WriteRune(r)
+		case r <= 0xffff:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			fmt.Fprintf(&b, `\u{%X}`, r)
+		}
+	}
+
+	b.WriteByte('"')
+	return b.String()
+}
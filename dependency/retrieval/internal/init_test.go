@@ -9,8 +9,15 @@ import (
 
 func TestUnitRetrieval(t *testing.T) {
 	suite := spec.New("retrieval", spec.Report(report.Terminal{}))
+	suite("ArtifactValidator", testArtifactValidator)
+	suite("Provenance", testProvenance)
 	suite("ReleaseFetcher", testReleaseFetcher)
+	suite("BuildpackTOMLUpdater", testBuildpackTOMLUpdater)
+	suite("CompactIndexFetcher", testCompactIndexFetcher)
 	suite("MetadataGenerator", testMetadataGenerator)
+	suite("EOLSchedule", testEOLSchedule)
+	suite("GemCompiler", testGemCompiler)
+	suite("Targets", testTargets)
 	suite("VersionFinder", testVersionFinder)
 	suite.Run(t)
 }
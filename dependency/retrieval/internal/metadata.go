@@ -1,29 +1,46 @@
 package internal
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
 
 const cpeTemplate string = `cpe:2.3:a:bundler:bundler:%s:*:*:*:*:ruby:*:*`
 const sourceURI string = `https://rubygems.org/downloads/bundler-%s.gem`
 const depID string = "bundler"
 
 type ReleaseMetadata struct {
-	CPE string `json:"cpe"`
-	// DeprecationDate string // This information is not published anywhere
-	Licenses        []string `json:"licenses"`
-	Name            string   `json:"name"`
-	ID              string   `json:"id"`
-	PURL            string   `json:"purl"`
-	SourceChecksum  string   `json:"source-checksum"`
-	SourceURI       string   `json:"source"`
-	Stacks          []string `json:"stacks"`
-	StripComponents int      `json:"strip-components,omitempty"`
-	Target          string   `json:"target"`
-	Version         string   `json:"version"`
+	Checksum            string     `json:"checksum,omitempty"`
+	CPE                 string     `json:"cpe"`
+	DeprecationDate     *time.Time `json:"deprecation_date,omitempty"`
+	Distros             []Distro   `json:"distros,omitempty"`
+	Licenses            []string   `json:"licenses"`
+	Name                string     `json:"name"`
+	ID                  string     `json:"id"`
+	OS                  string     `json:"os,omitempty"`
+	Arch                string     `json:"arch,omitempty"`
+	PURL                string     `json:"purl"`
+	ProvenanceChecksum  string     `json:"provenance-checksum,omitempty"`
+	ProvenanceURI       string     `json:"provenance-uri,omitempty"`
+	RequiredRubyVersion string     `json:"required_ruby_version,omitempty"`
+	SourceChecksum      string     `json:"source-checksum"`
+	SourceURI           string     `json:"source"`
+	Stacks              []string   `json:"stacks"`
+	StripComponents     int        `json:"strip-components,omitempty"`
+	Target              string     `json:"target"`
+	URI                 string     `json:"uri,omitempty"`
+	Version             string     `json:"version"`
 }
 
 type MetadataGenerator struct {
 	name             string
 	sourceURIPattern string
+	transport        Transport
 	purlGenerator    PackageURLGenerator
 	checksummer      Checksummer
 }
@@ -33,28 +50,153 @@ type Checksummer interface {
 	Sum(paths ...string) (string, error)
 }
 
-func NewMetadataGenerator(checksummer Checksummer, purl PackageURLGenerator) MetadataGenerator {
+//go:generate faux --interface Transport --output fakes/transport.go
+type Transport interface {
+	Drop(root, uri string) (io.ReadCloser, error)
+}
+
+func NewMetadataGenerator(transport Transport, checksummer Checksummer, purl PackageURLGenerator) MetadataGenerator {
 	return MetadataGenerator{
 		name:             depID,
 		sourceURIPattern: sourceURI,
+		transport:        transport,
 		checksummer:      checksummer,
 		purlGenerator:    purl,
 	}
 }
 
-func (m MetadataGenerator) Generate(r Release, stackIDs []string, target string) (ReleaseMetadata, error) {
+// GenerateAll generates the metadata of several releases, downloading their
+// sources with at most the given number of concurrent workers. The metadata
+// is returned in the order of the releases, and for each release in the
+// order of the targets. When several releases fail, the error of the first
+// one is returned.
+func (m MetadataGenerator) GenerateAll(releases []Release, stackIDs []string, targets []Target, workers int) ([]ReleaseMetadata, error) {
+	if len(releases) == 0 {
+		return nil, nil
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		metadata = make([][]ReleaseMetadata, len(releases))
+		errs     = make([]error, len(releases))
+		indices  = make(chan int)
+		wg       sync.WaitGroup
+	)
+
+	for w := 0; w < min(workers, len(releases)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				metadata[i], errs[i] = m.Generate(releases[i], stackIDs, targets)
+			}
+		}()
+	}
+
+	for i := range releases {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	var all []ReleaseMetadata
+	for i, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, metadata[i]...)
+	}
+
+	return all, nil
+}
+
+// Generate downloads the source of a release, verifies it against the
+// checksum published in the release index and returns its metadata for each
+// of the targets. The entries share their stacks, so no two targets may have
+// the same OS and architecture.
+func (m MetadataGenerator) Generate(r Release, stackIDs []string, targets []Target) ([]ReleaseMetadata, error) {
+	platforms := map[[2]string]string{}
+	for _, target := range targets {
+		platform := [2]string{target.OS, target.Arch}
+		if name, ok := platforms[platform]; ok {
+			return nil, fmt.Errorf("targets %q and %q have the same os and arch %q: the dependency entries would be ambiguous", name, target.Name, target.OS+"/"+target.Arch)
+		}
+		platforms[platform] = target.Name
+	}
+
 	sourceURI := fmt.Sprintf(m.sourceURIPattern, r.Version)
-	return ReleaseMetadata{
-		Name:            m.name,
-		ID:              m.name,
-		Version:         r.Version,
-		Stacks:          stackIDs,
-		StripComponents: 2,
-		SourceURI:       sourceURI,
-		SourceChecksum:  fmt.Sprintf("sha256:%s", r.SHA256),
-		CPE:             fmt.Sprintf(cpeTemplate, r.Version),
-		PURL:            m.purlGenerator.Generate(m.name, r.Version, r.SHA256, sourceURI),
-		Licenses:        r.Licenses,
-		Target:          target,
-	}, nil
+
+	err := m.verifySource(r, sourceURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata []ReleaseMetadata
+	for _, target := range targets {
+		metadata = append(metadata, ReleaseMetadata{
+			Name:                m.name,
+			ID:                  m.name,
+			Version:             r.Version,
+			Stacks:              stackIDs,
+			StripComponents:     2,
+			SourceURI:           sourceURI,
+			SourceChecksum:      fmt.Sprintf("sha256:%s", r.SHA256),
+			CPE:                 fmt.Sprintf(cpeTemplate, r.Version),
+			PURL:                m.purlGenerator.Generate(m.name, r.Version, r.SHA256, sourceURI),
+			Licenses:            r.Licenses,
+			DeprecationDate:     r.DeprecationDate,
+			RequiredRubyVersion: r.RubyVersion,
+			Target:              target.Name,
+			OS:                  target.OS,
+			Arch:                target.Arch,
+			Distros:             target.Distros,
+		})
+	}
+
+	return metadata, nil
+}
+
+func (m MetadataGenerator) verifySource(r Release, uri string) error {
+	dir, err := os.MkdirTemp("", "bundler-source")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	source, err := m.transport.Drop("", uri)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	defer source.Close()
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.gem", m.name, r.Version))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, source)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	sum, err := m.checksummer.Sum(path)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(sum, r.SHA256) {
+		return fmt.Errorf("checksum mismatch for %s %s: the release index lists sha256:%s but %s has sha256:%s", m.name, r.Version, r.SHA256, uri, sum)
+	}
+
+	return nil
 }
@@ -1,7 +1,13 @@
 package internal_test
 
 import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
@@ -11,46 +17,252 @@ import (
 
 func testMetadataGenerator(t *testing.T, context spec.G, it spec.S) {
 	var (
-		Expect = NewWithT(t).Expect
+		Expect       = NewWithT(t).Expect
+		Consistently = NewWithT(t).Consistently
 
-		cs  *fakes.Checksummer
-		pg  *fakes.PackageURLGenerator
-		gen internal.MetadataGenerator
+		transport *fakes.Transport
+		cs        *fakes.Checksummer
+		pg        *fakes.PackageURLGenerator
+		gen       internal.MetadataGenerator
 	)
 
-	context("Generate", func() {
-		it.Before(func() {
-			cs = &fakes.Checksummer{}
-			cs.SumCall.Returns.String = "some-checksum"
-			pg = &fakes.PackageURLGenerator{}
-			pg.GenerateCall.Returns.String = "some-purl"
-			gen = internal.NewMetadataGenerator(cs, pg)
-		})
+	it.Before(func() {
+		transport = &fakes.Transport{}
+		transport.DropCall.Stub = func(string, string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("some-gem")), nil
+		}
+		cs = &fakes.Checksummer{}
+		cs.SumCall.Returns.String = "abcdef"
+		pg = &fakes.PackageURLGenerator{}
+		pg.GenerateCall.Returns.String = "some-purl"
+		gen = internal.NewMetadataGenerator(transport, cs, pg)
+	})
 
+	context("Generate", func() {
 		it("generates a ReleaseMetadata type with the expected values", func() {
+			deprecationDate := time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)
 			metadata, err := gen.Generate(internal.Release{
-				Version:  "1.2.3",
-				SHA256:   "abcdef",
-				Licenses: []string{"SomeLicense"},
+				Version:         "1.2.3",
+				SHA256:          "abcdef",
+				Licenses:        []string{"SomeLicense"},
+				RubyVersion:     ">= 3.2.0",
+				DeprecationDate: &deprecationDate,
 			},
 				[]string{"some.stack", "other.stack"},
-				"some-target",
+				[]internal.Target{{Name: "some-target"}},
 			)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(metadata).To(Equal(internal.ReleaseMetadata{
-				CPE:             "cpe:2.3:a:bundler:bundler:1.2.3:*:*:*:*:ruby:*:*",
-				Licenses:        []string{"SomeLicense"},
-				Name:            "bundler",
-				ID:              "bundler",
-				PURL:            "some-purl",
-				SourceChecksum:  "sha256:abcdef",
-				SourceURI:       "https://rubygems.org/downloads/bundler-1.2.3.gem",
-				Stacks:          []string{"some.stack", "other.stack"},
-				StripComponents: 2,
-				Target:          "some-target",
-				Version:         "1.2.3",
+			Expect(metadata).To(ConsistOf(internal.ReleaseMetadata{
+				CPE:                 "cpe:2.3:a:bundler:bundler:1.2.3:*:*:*:*:ruby:*:*",
+				DeprecationDate:     &deprecationDate,
+				Licenses:            []string{"SomeLicense"},
+				Name:                "bundler",
+				ID:                  "bundler",
+				PURL:                "some-purl",
+				RequiredRubyVersion: ">= 3.2.0",
+				SourceChecksum:      "sha256:abcdef",
+				SourceURI:           "https://rubygems.org/downloads/bundler-1.2.3.gem",
+				Stacks:              []string{"some.stack", "other.stack"},
+				StripComponents:     2,
+				Target:              "some-target",
+				Version:             "1.2.3",
 			}))
 
 		})
+
+		it("generates the metadata of each target", func() {
+			metadata, err := gen.Generate(internal.Release{Version: "1.2.3", SHA256: "abcdef"}, []string{"*"}, []internal.Target{
+				{Name: "jammy-amd64", OS: "linux", Arch: "amd64", Distros: []internal.Distro{{Name: "ubuntu", Version: "22.04"}, {Name: "ubuntu", Version: "24.04"}}},
+				{Name: "noble-arm64", OS: "linux", Arch: "arm64", Distros: []internal.Distro{{Name: "ubuntu", Version: "24.04"}}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(HaveLen(2))
+
+			Expect(metadata[0].Target).To(Equal("jammy-amd64"))
+			Expect(metadata[0].OS).To(Equal("linux"))
+			Expect(metadata[0].Arch).To(Equal("amd64"))
+			Expect(metadata[0].Distros).To(Equal([]internal.Distro{{Name: "ubuntu", Version: "22.04"}, {Name: "ubuntu", Version: "24.04"}}))
+
+			Expect(metadata[1].Target).To(Equal("noble-arm64"))
+			Expect(metadata[1].OS).To(Equal("linux"))
+			Expect(metadata[1].Arch).To(Equal("arm64"))
+			Expect(metadata[1].Distros).To(Equal([]internal.Distro{{Name: "ubuntu", Version: "24.04"}}))
+
+			Expect(transport.DropCall.CallCount).To(Equal(1))
+		})
+
+		it("verifies the downloaded source", func() {
+			cs.SumCall.Stub = func(paths ...string) (string, error) {
+				Expect(paths).To(HaveLen(1))
+				Expect(paths[0]).To(HaveSuffix("bundler-1.2.3.gem"))
+
+				content, err := os.ReadFile(paths[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("some-gem"))
+
+				return "ABCDEF", nil
+			}
+
+			_, err := gen.Generate(internal.Release{Version: "1.2.3", SHA256: "abcdef"}, nil, []internal.Target{{}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(transport.DropCall.Receives.Uri).To(Equal("https://rubygems.org/downloads/bundler-1.2.3.gem"))
+			Expect(cs.SumCall.CallCount).To(Equal(1))
+		})
+
+		context("failure cases", func() {
+			context("when the source checksum does not match the index", func() {
+				it.Before(func() {
+					cs.SumCall.Returns.String = "123456"
+				})
+
+				it("returns an error", func() {
+					_, err := gen.Generate(internal.Release{Version: "1.2.3", SHA256: "abcdef"}, nil, []internal.Target{{}})
+					Expect(err).To(MatchError("checksum mismatch for bundler 1.2.3: the release index lists sha256:abcdef but https://rubygems.org/downloads/bundler-1.2.3.gem has sha256:123456"))
+				})
+			})
+
+			context("when the source cannot be downloaded", func() {
+				it.Before(func() {
+					transport.DropCall.Stub = nil
+					transport.DropCall.Returns.Error = errors.New("failed to make request")
+				})
+
+				it("returns an error", func() {
+					_, err := gen.Generate(internal.Release{Version: "1.2.3", SHA256: "abcdef"}, nil, []internal.Target{{}})
+					Expect(err).To(MatchError("failed to download https://rubygems.org/downloads/bundler-1.2.3.gem: failed to make request"))
+				})
+			})
+
+			context("when the checksum cannot be calculated", func() {
+				it.Before(func() {
+					cs.SumCall.Returns.Error = errors.New("failed to calculate checksum")
+				})
+
+				it("returns an error", func() {
+					_, err := gen.Generate(internal.Release{Version: "1.2.3", SHA256: "abcdef"}, nil, []internal.Target{{}})
+					Expect(err).To(MatchError("failed to calculate checksum"))
+				})
+			})
+
+			context("when two targets have the same os and arch", func() {
+				it("returns an error", func() {
+					_, err := gen.Generate(internal.Release{Version: "1.2.3", SHA256: "abcdef"}, []string{"*"}, []internal.Target{
+						{Name: "jammy-amd64", OS: "linux", Arch: "amd64"},
+						{Name: "noble-amd64", OS: "linux", Arch: "amd64"},
+					})
+					Expect(err).To(MatchError(`targets "jammy-amd64" and "noble-amd64" have the same os and arch "linux/amd64": the dependency entries would be ambiguous`))
+					Expect(transport.DropCall.CallCount).To(Equal(0))
+				})
+			})
+		})
+	})
+
+	context("GenerateAll", func() {
+		var releases []internal.Release
+
+		it.Before(func() {
+			releases = []internal.Release{
+				{Version: "2.7.2", SHA256: "abcdef"},
+				{Version: "2.7.1", SHA256: "abcdef"},
+				{Version: "2.6.9", SHA256: "abcdef"},
+				{Version: "2.6.8", SHA256: "abcdef"},
+			}
+		})
+
+		it("generates the metadata of every release in order", func() {
+			metadata, err := gen.GenerateAll(releases, []string{"*"}, []internal.Target{
+				{Name: "jammy-amd64", OS: "linux", Arch: "amd64"},
+				{Name: "jammy-arm64", OS: "linux", Arch: "arm64"},
+			}, 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(HaveLen(8))
+			for i, release := range releases {
+				Expect(metadata[2*i].Version).To(Equal(release.Version))
+				Expect(metadata[2*i].Target).To(Equal("jammy-amd64"))
+				Expect(metadata[2*i+1].Version).To(Equal(release.Version))
+				Expect(metadata[2*i+1].Target).To(Equal("jammy-arm64"))
+			}
+
+			Expect(transport.DropCall.CallCount).To(Equal(4))
+		})
+
+		it("downloads with at most the given number of workers", func() {
+			var (
+				mutex             sync.Mutex
+				active, maxActive int
+			)
+
+			release := make(chan struct{})
+			started := make(chan struct{}, len(releases))
+			transport.DropCall.Stub = func(string, string) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader("some-gem")), nil
+			}
+			cs.SumCall.Stub = func(...string) (string, error) {
+				return "abcdef", nil
+			}
+
+			// Block the downloads outside of the fake, whose calls are
+			// serialised by a mutex.
+			gen = internal.NewMetadataGenerator(blockingTransport{
+				Transport: transport,
+				before: func() {
+					mutex.Lock()
+					active++
+					maxActive = max(maxActive, active)
+					mutex.Unlock()
+					started <- struct{}{}
+					<-release
+				},
+				after: func() {
+					mutex.Lock()
+					active--
+					mutex.Unlock()
+				},
+			}, cs, pg)
+
+			done := make(chan error)
+			go func() {
+				_, err := gen.GenerateAll(releases, nil, []internal.Target{{}}, 2)
+				done <- err
+			}()
+
+			<-started
+			<-started
+			Consistently(started).ShouldNot(Receive())
+			close(release)
+
+			Expect(<-done).To(Succeed())
+			Expect(maxActive).To(Equal(2))
+		})
+
+		it("returns the error of the first failing release", func() {
+			cs.SumCall.Stub = func(paths ...string) (string, error) {
+				if strings.HasSuffix(paths[0], "bundler-2.6.9.gem") || strings.HasSuffix(paths[0], "bundler-2.7.1.gem") {
+					return "123456", nil
+				}
+				return "abcdef", nil
+			}
+
+			_, err := gen.GenerateAll(releases, nil, []internal.Target{{}}, 3)
+			Expect(err).To(MatchError(ContainSubstring("checksum mismatch for bundler 2.7.1")))
+		})
+
+		it("returns nothing without releases", func() {
+			metadata, err := gen.GenerateAll(nil, nil, []internal.Target{{}}, 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(BeNil())
+		})
 	})
 }
+
+type blockingTransport struct {
+	*fakes.Transport
+	before, after func()
+}
+
+func (t blockingTransport) Drop(root, uri string) (io.ReadCloser, error) {
+	t.before()
+	defer t.after()
+	return t.Transport.Drop(root, uri)
+}
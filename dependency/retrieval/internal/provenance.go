@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// ProvenanceSuffix is appended to the name of an artifact to name the
+	// tarball of its provenance statement, which is published next to it.
+	ProvenanceSuffix = ".intoto.tar.gz"
+
+	// ProvenanceStatementFile is the name of the statement in the tarball.
+	// The statement is packaged rather than published as JSON so that the
+	// buildpack can deliver it like any other dependency.
+	ProvenanceStatementFile = "statement.intoto.json"
+
+	StatementType      = "https://in-toto.io/Statement/v1"
+	SLSAProvenanceType = "https://slsa.dev/provenance/v1"
+	CompileBuildType   = "https://github.com/paketo-buildpacks/bundler/dependency/compile/v1"
+)
+
+// ProvenanceStatement is an in-toto statement carrying a SLSA provenance
+// predicate.
+type ProvenanceStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     SLSAProvenance       `json:"predicate"`
+}
+
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+type SLSAProvenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]string    `json:"externalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies"`
+}
+
+type RunDetails struct {
+	Builder  Builder        `json:"builder"`
+	Metadata *BuildMetadata `json:"metadata,omitempty"`
+}
+
+// Builder identifies what produced an artifact, e.g. the workflow that
+// compiled it.
+type Builder struct {
+	ID string `json:"id"`
+}
+
+type BuildMetadata struct {
+	InvocationID string `json:"invocationId,omitempty"`
+}
+
+// LocalBuilderID identifies artifacts compiled outside of GitHub Actions.
+const LocalBuilderID = "local"
+
+// LookupBuilder identifies the GitHub Actions workflow run that is compiling
+// the artifact from the variables GitHub sets. Outside of GitHub Actions it
+// returns LocalBuilderID and no invocation.
+func LookupBuilder() (Builder, string) {
+	server := os.Getenv("GITHUB_SERVER_URL")
+	workflowRef := os.Getenv("GITHUB_WORKFLOW_REF")
+	if os.Getenv("GITHUB_ACTIONS") != "true" || server == "" || workflowRef == "" {
+		return Builder{ID: LocalBuilderID}, ""
+	}
+
+	var invocation string
+	if repository, runID := os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"); repository != "" && runID != "" {
+		invocation = fmt.Sprintf("%s/%s/actions/runs/%s", server, repository, runID)
+		if attempt := os.Getenv("GITHUB_RUN_ATTEMPT"); attempt != "" {
+			invocation = fmt.Sprintf("%s/attempts/%s", invocation, attempt)
+		}
+	}
+
+	return Builder{ID: fmt.Sprintf("%s/%s", server, workflowRef)}, invocation
+}
+
+// NewProvenanceStatement describes how an artifact was compiled: the gem it
+// was packaged from, the builder that packaged it and the resulting artifact.
+func NewProvenanceStatement(artifact CompiledArtifact, version, target string, builder Builder, invocation string) ProvenanceStatement {
+	statement := ProvenanceStatement{
+		Type: StatementType,
+		Subject: []ResourceDescriptor{{
+			Name:   filepath.Base(artifact.Path),
+			Digest: digestOf(artifact.Checksum),
+		}},
+		PredicateType: SLSAProvenanceType,
+		Predicate: SLSAProvenance{
+			BuildDefinition: BuildDefinition{
+				BuildType: CompileBuildType,
+				ExternalParameters: map[string]string{
+					"version": version,
+					"target":  target,
+				},
+				ResolvedDependencies: []ResourceDescriptor{{
+					URI:    artifact.Source,
+					Digest: digestOf(artifact.SourceChecksum),
+				}},
+			},
+			RunDetails: RunDetails{Builder: builder},
+		},
+	}
+
+	if invocation != "" {
+		statement.Predicate.RunDetails.Metadata = &BuildMetadata{InvocationID: invocation}
+	}
+
+	return statement
+}
+
+// Provenance is the packaged provenance statement of an artifact and the file
+// holding its checksum.
+type Provenance struct {
+	Path         string
+	ChecksumPath string
+	Checksum     string
+}
+
+// WriteProvenance packages the statement in a gzipped tarball next to the
+// artifact, along with a .checksum file holding its SHA256 checksum.
+func WriteProvenance(artifact CompiledArtifact, statement ProvenanceStatement) (Provenance, error) {
+	content, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return Provenance{}, err
+	}
+	content = append(content, '\n')
+
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	gz.Header = gzip.Header{OS: 255}
+	tw := tar.NewWriter(gz)
+
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     ProvenanceStatementFile,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return Provenance{}, err
+	}
+
+	_, err = tw.Write(content)
+	if err != nil {
+		return Provenance{}, err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return Provenance{}, err
+	}
+
+	err = gz.Close()
+	if err != nil {
+		return Provenance{}, err
+	}
+
+	sum := sha256.Sum256(buffer.Bytes())
+	provenance := Provenance{
+		Path:         artifact.Path + ProvenanceSuffix,
+		ChecksumPath: artifact.Path + ProvenanceSuffix + ".checksum",
+		Checksum:     "sha256:" + hex.EncodeToString(sum[:]),
+	}
+
+	err = os.WriteFile(provenance.Path, buffer.Bytes(), 0644)
+	if err != nil {
+		return Provenance{}, fmt.Errorf("failed to write provenance: %w", err)
+	}
+
+	err = os.WriteFile(provenance.ChecksumPath, []byte(provenance.Checksum+"\n"), 0644)
+	if err != nil {
+		return Provenance{}, fmt.Errorf("failed to write provenance: %w", err)
+	}
+
+	return provenance, nil
+}
+
+// digestOf turns a checksum of the form <algorithm>:<hex> into an in-toto
+// digest set.
+func digestOf(checksum string) map[string]string {
+	algorithm, hash, ok := strings.Cut(checksum, ":")
+	if !ok {
+		return map[string]string{"sha256": checksum}
+	}
+
+	return map[string]string{algorithm: hash}
+}
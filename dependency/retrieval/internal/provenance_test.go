@@ -0,0 +1,152 @@
+package internal_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testProvenance(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		artifact internal.CompiledArtifact
+	)
+
+	it.Before(func() {
+		artifact = internal.CompiledArtifact{
+			Path:           filepath.Join(t.TempDir(), "bundler-jammy-2.7.2-01234567.tgz"),
+			Checksum:       "sha256:0123456789abcdef",
+			Source:         "https://rubygems.org/downloads/bundler-2.7.2.gem",
+			SourceChecksum: "sha256:fedcba9876543210",
+		}
+	})
+
+	context("NewProvenanceStatement", func() {
+		it("records the source, the builder and the artifact", func() {
+			statement := internal.NewProvenanceStatement(artifact, "2.7.2", "jammy", internal.Builder{ID: "https://github.com/some-org/bundler/.github/workflows/compile.yml@refs/heads/main"}, "https://github.com/some-org/bundler/actions/runs/42/attempts/1")
+
+			Expect(statement).To(Equal(internal.ProvenanceStatement{
+				Type: "https://in-toto.io/Statement/v1",
+				Subject: []internal.ResourceDescriptor{{
+					Name:   "bundler-jammy-2.7.2-01234567.tgz",
+					Digest: map[string]string{"sha256": "0123456789abcdef"},
+				}},
+				PredicateType: "https://slsa.dev/provenance/v1",
+				Predicate: internal.SLSAProvenance{
+					BuildDefinition: internal.BuildDefinition{
+						BuildType:          "https://github.com/paketo-buildpacks/bundler/dependency/compile/v1",
+						ExternalParameters: map[string]string{"version": "2.7.2", "target": "jammy"},
+						ResolvedDependencies: []internal.ResourceDescriptor{{
+							URI:    "https://rubygems.org/downloads/bundler-2.7.2.gem",
+							Digest: map[string]string{"sha256": "fedcba9876543210"},
+						}},
+					},
+					RunDetails: internal.RunDetails{
+						Builder:  internal.Builder{ID: "https://github.com/some-org/bundler/.github/workflows/compile.yml@refs/heads/main"},
+						Metadata: &internal.BuildMetadata{InvocationID: "https://github.com/some-org/bundler/actions/runs/42/attempts/1"},
+					},
+				},
+			}))
+		})
+
+		context("when there is no invocation", func() {
+			it("leaves out the metadata", func() {
+				statement := internal.NewProvenanceStatement(artifact, "2.7.2", "jammy", internal.Builder{ID: "local"}, "")
+				Expect(statement.Predicate.RunDetails.Metadata).To(BeNil())
+			})
+		})
+	})
+
+	context("WriteProvenance", func() {
+		it("packages the statement next to the artifact", func() {
+			provenance, err := internal.WriteProvenance(artifact, internal.NewProvenanceStatement(artifact, "2.7.2", "jammy", internal.Builder{ID: "local"}, ""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(provenance.Path).To(Equal(artifact.Path + ".intoto.tar.gz"))
+			Expect(provenance.ChecksumPath).To(Equal(artifact.Path + ".intoto.tar.gz.checksum"))
+
+			content, err := os.ReadFile(provenance.Path)
+			Expect(err).NotTo(HaveOccurred())
+
+			sum := sha256.Sum256(content)
+			Expect(provenance.Checksum).To(Equal("sha256:" + hex.EncodeToString(sum[:])))
+
+			checksum, err := os.ReadFile(provenance.ChecksumPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(checksum)).To(Equal(provenance.Checksum + "\n"))
+
+			gz, err := gzip.NewReader(bytes.NewReader(content))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := tar.NewReader(gz)
+			header, err := reader.Next()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(header.Name).To(Equal("statement.intoto.json"))
+
+			var statement map[string]interface{}
+			Expect(json.NewDecoder(reader).Decode(&statement)).To(Succeed())
+			Expect(statement).To(HaveKeyWithValue("_type", "https://in-toto.io/Statement/v1"))
+			Expect(statement).To(HaveKeyWithValue("predicateType", "https://slsa.dev/provenance/v1"))
+			Expect(statement["predicate"]).To(HaveKeyWithValue("runDetails", map[string]interface{}{
+				"builder": map[string]interface{}{"id": "local"},
+			}))
+
+			_, err = reader.Next()
+			Expect(err).To(Equal(io.EOF))
+		})
+
+		context("failure cases", func() {
+			context("when the directory of the artifact does not exist", func() {
+				it("returns an error", func() {
+					artifact.Path = filepath.Join(t.TempDir(), "missing", "bundler.tgz")
+
+					_, err := internal.WriteProvenance(artifact, internal.ProvenanceStatement{})
+					Expect(err).To(MatchError(ContainSubstring("failed to write provenance")))
+				})
+			})
+		})
+	})
+
+	context("LookupBuilder", func() {
+		context("when running in GitHub Actions", func() {
+			it.Before(func() {
+				t.Setenv("GITHUB_ACTIONS", "true")
+				t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+				t.Setenv("GITHUB_WORKFLOW_REF", "some-org/bundler/.github/workflows/compile.yml@refs/heads/main")
+				t.Setenv("GITHUB_REPOSITORY", "some-org/bundler")
+				t.Setenv("GITHUB_RUN_ID", "42")
+				t.Setenv("GITHUB_RUN_ATTEMPT", "2")
+			})
+
+			it("identifies the workflow and its run", func() {
+				builder, invocation := internal.LookupBuilder()
+				Expect(builder).To(Equal(internal.Builder{ID: "https://github.com/some-org/bundler/.github/workflows/compile.yml@refs/heads/main"}))
+				Expect(invocation).To(Equal("https://github.com/some-org/bundler/actions/runs/42/attempts/2"))
+			})
+		})
+
+		context("when running elsewhere", func() {
+			it.Before(func() {
+				t.Setenv("GITHUB_ACTIONS", "")
+			})
+
+			it("identifies a local build", func() {
+				builder, invocation := internal.LookupBuilder()
+				Expect(builder).To(Equal(internal.Builder{ID: "local"}))
+				Expect(invocation).To(BeEmpty())
+			})
+		})
+	})
+}
@@ -5,13 +5,62 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type Release struct {
-	Version    string `json:"number"`
-	Licenses   []string
-	SHA256     string `json:"sha"`
-	Prerelease bool
+	Version         string `json:"number"`
+	Licenses        []string
+	SHA256          string `json:"sha"`
+	Platform        string `json:"platform"`
+	RubyVersion     string `json:"ruby_version"`
+	RubygemsVersion string `json:"rubygems_version"`
+	Prerelease      bool
+	CreatedAt       *time.Time `json:"created_at"`
+
+	// DeprecationDate is not published by RubyGems and is filled in from an
+	// EOL schedule, if one is provided.
+	DeprecationDate *time.Time `json:"-"`
+}
+
+// ReleaseIndex lists the releases of Bundler published by a gem server.
+type ReleaseIndex interface {
+	Get() ([]Release, error)
+}
+
+// YankedVersionLister is implemented by release indexes that report yanked
+// versions explicitly rather than just leaving them out.
+type YankedVersionLister interface {
+	Yanked() ([]string, error)
+}
+
+// The protocols a release index can be read with.
+const (
+	ProtocolAuto         = "auto"
+	ProtocolJSON         = "json"
+	ProtocolCompactIndex = "compact-index"
+)
+
+// NewReleaseIndex returns the client for a release index. With
+// ProtocolAuto, URLs of a JSON document such as
+// https://rubygems.org/api/v1/versions/bundler.json use the RubyGems JSON API
+// and every other URL is read as a compact index.
+func NewReleaseIndex(index, protocol string) (ReleaseIndex, error) {
+	switch protocol {
+	case ProtocolAuto:
+		if strings.HasSuffix(strings.SplitN(index, "?", 2)[0], ".json") {
+			return NewReleaseFetcher(index), nil
+		}
+
+		return NewCompactIndexFetcher(index), nil
+	case ProtocolJSON:
+		return NewReleaseFetcher(index), nil
+	case ProtocolCompactIndex:
+		return NewCompactIndexFetcher(index), nil
+	default:
+		return nil, fmt.Errorf("unsupported release index protocol %q (must be %q, %q or %q)", protocol, ProtocolAuto, ProtocolJSON, ProtocolCompactIndex)
+	}
 }
 
 type ReleaseFetcher struct {
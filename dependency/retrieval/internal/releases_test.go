@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
 	"github.com/sclevine/spec"
@@ -55,18 +56,31 @@ func testReleaseFetcher(t *testing.T, context spec.G, it spec.S) {
 
 	context("Get", func() {
 		it("returns a slice of all releases that aren't prerelease from the index with needed subset of metadata", func() {
+			createdAt := []time.Time{
+				time.Date(2022, time.November, 2, 15, 49, 16, 992000000, time.UTC),
+				time.Date(2022, time.October, 17, 12, 48, 57, 839000000, time.UTC),
+			}
+
 			releases, err := fetcher.Get()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(releases).To(Equal([]internal.Release{
 				{
-					Version:  "2.3.25",
-					Licenses: []string{"MIT"},
-					SHA256:   "fd81ec4635c4189b66fd0789537d5cb38b3810b70765f6e1e82dda15b97591ad",
+					Version:         "2.3.25",
+					Licenses:        []string{"MIT"},
+					SHA256:          "fd81ec4635c4189b66fd0789537d5cb38b3810b70765f6e1e82dda15b97591ad",
+					Platform:        "ruby",
+					RubyVersion:     ">= 2.3.0",
+					RubygemsVersion: ">= 2.5.2",
+					CreatedAt:       &createdAt[0],
 				},
 				{
-					Version:  "2.3.24",
-					Licenses: []string{"MIT"},
-					SHA256:   "eaa2eb8c3892e870f979252b2196bd77eb551e1dbf3cdc4eb164ba01ec4438c4",
+					Version:         "2.3.24",
+					Licenses:        []string{"MIT"},
+					SHA256:          "eaa2eb8c3892e870f979252b2196bd77eb551e1dbf3cdc4eb164ba01ec4438c4",
+					Platform:        "ruby",
+					RubyVersion:     ">= 2.3.0",
+					RubygemsVersion: ">= 2.5.2",
+					CreatedAt:       &createdAt[1],
 				},
 			}))
 		})
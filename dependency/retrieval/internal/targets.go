@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultDistros lists the distributions that dependencies are compiled for,
+// in the format accepted by ParseDistros.
+const DefaultDistros = "jammy=ubuntu:22.04,noble=ubuntu:24.04,resolute=ubuntu:26.04"
+
+type Distro struct {
+	Name    string `toml:"name"    json:"name"`
+	Version string `toml:"version" json:"version"`
+}
+
+// BuildpackTarget is an entry of the [[targets]] table of buildpack.toml.
+type BuildpackTarget struct {
+	OS      string   `toml:"os"`
+	Arch    string   `toml:"arch"`
+	Distros []Distro `toml:"distros"`
+}
+
+// ConfiguredDistro is a distribution that dependencies can be compiled for.
+// Target is the name of the compile image, e.g. "jammy".
+type ConfiguredDistro struct {
+	Target string
+	Distro Distro
+}
+
+// Target is a platform for which metadata is generated.
+type Target struct {
+	Name    string
+	OS      string
+	Arch    string
+	Distros []Distro
+}
+
+// ParseDistros parses a comma-separated list of distributions of the form
+// <target>=<name>:<version>, e.g. "jammy=ubuntu:22.04".
+func ParseDistros(value string) ([]ConfiguredDistro, error) {
+	var distros []ConfiguredDistro
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		target, distro, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid distro %q: must be of the form <target>=<name>:<version>", entry)
+		}
+
+		name, version, ok := strings.Cut(distro, ":")
+		if !ok || target == "" || name == "" || version == "" {
+			return nil, fmt.Errorf("invalid distro %q: must be of the form <target>=<name>:<version>", entry)
+		}
+
+		distros = append(distros, ConfiguredDistro{
+			Target: target,
+			Distro: Distro{Name: name, Version: version},
+		})
+	}
+
+	return distros, nil
+}
+
+// ResolveTargets returns one target per OS and architecture of the
+// buildpack targets, listing every configured distribution it supports. A
+// buildpack target that lists distros only supports those; a distro without a
+// version supports every version of that distribution. The dependency
+// manager of packit matches dependencies on stacks, OS and architecture but
+// not on distros, so a target per distro and architecture would give several
+// entries with the wildcard stack for one version, which it rejects. The target is named after the first distribution it supports
+// and its architecture, e.g. "jammy-amd64", which keeps the names of the
+// compiled artifacts apart.
+func ResolveTargets(buildpackTargets []BuildpackTarget, distros []ConfiguredDistro) ([]Target, error) {
+	var (
+		targets []Target
+		indices = map[[2]string]int{}
+	)
+	for _, buildpackTarget := range buildpackTargets {
+		var supported []ConfiguredDistro
+		for _, distro := range distros {
+			if supports(buildpackTarget, distro.Distro) {
+				supported = append(supported, distro)
+			}
+		}
+
+		if len(supported) == 0 {
+			return nil, fmt.Errorf("no configured distro matches target %s/%s", buildpackTarget.OS, buildpackTarget.Arch)
+		}
+
+		key := [2]string{buildpackTarget.OS, buildpackTarget.Arch}
+		i, ok := indices[key]
+		if !ok {
+			i = len(targets)
+			indices[key] = i
+			targets = append(targets, Target{
+				Name: supported[0].Target + "-" + buildpackTarget.Arch,
+				OS:   buildpackTarget.OS,
+				Arch: buildpackTarget.Arch,
+			})
+		}
+
+		for _, distro := range supported {
+			if !containsDistro(targets[i].Distros, distro.Distro) {
+				targets[i].Distros = append(targets[i].Distros, distro.Distro)
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+func supports(target BuildpackTarget, distro Distro) bool {
+	if len(target.Distros) == 0 {
+		return true
+	}
+
+	for _, d := range target.Distros {
+		if d.Name == distro.Name && (d.Version == "" || d.Version == distro.Version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsDistro(distros []Distro, distro Distro) bool {
+	for _, d := range distros {
+		if d == distro {
+			return true
+		}
+	}
+
+	return false
+}
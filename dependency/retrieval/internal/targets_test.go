@@ -0,0 +1,134 @@
+package internal_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testTargets(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("ParseDistros", func() {
+		it("parses the list of distros", func() {
+			distros, err := internal.ParseDistros("jammy=ubuntu:22.04, noble=ubuntu:24.04,")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(distros).To(Equal([]internal.ConfiguredDistro{
+				{Target: "jammy", Distro: internal.Distro{Name: "ubuntu", Version: "22.04"}},
+				{Target: "noble", Distro: internal.Distro{Name: "ubuntu", Version: "24.04"}},
+			}))
+		})
+
+		it("parses the default distros", func() {
+			distros, err := internal.ParseDistros(internal.DefaultDistros)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(distros).To(HaveLen(3))
+		})
+
+		context("failure cases", func() {
+			it("rejects malformed entries", func() {
+				for _, value := range []string{"jammy", "jammy=ubuntu", "=ubuntu:22.04", "jammy=:22.04", "jammy=ubuntu:"} {
+					_, err := internal.ParseDistros(value)
+					Expect(err).To(MatchError(ContainSubstring("must be of the form <target>=<name>:<version>")), value)
+				}
+			})
+		})
+	})
+
+	context("ResolveTargets", func() {
+		var distros []internal.ConfiguredDistro
+
+		it.Before(func() {
+			var err error
+			distros, err = internal.ParseDistros("jammy=ubuntu:22.04,noble=ubuntu:24.04")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("returns one target per OS and architecture with every distro", func() {
+			targets, err := internal.ResolveTargets([]internal.BuildpackTarget{
+				{OS: "linux", Arch: "amd64"},
+				{OS: "linux", Arch: "arm64"},
+			}, distros)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets).To(Equal([]internal.Target{
+				{Name: "jammy-amd64", OS: "linux", Arch: "amd64", Distros: []internal.Distro{{Name: "ubuntu", Version: "22.04"}, {Name: "ubuntu", Version: "24.04"}}},
+				{Name: "jammy-arm64", OS: "linux", Arch: "arm64", Distros: []internal.Distro{{Name: "ubuntu", Version: "22.04"}, {Name: "ubuntu", Version: "24.04"}}},
+			}))
+		})
+
+		context("when a target lists its distros", func() {
+			it("only includes those distros", func() {
+				targets, err := internal.ResolveTargets([]internal.BuildpackTarget{
+					{OS: "linux", Arch: "amd64", Distros: []internal.Distro{{Name: "ubuntu", Version: "24.04"}}},
+					{OS: "linux", Arch: "arm64", Distros: []internal.Distro{{Name: "ubuntu"}}},
+				}, distros)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(targets).To(Equal([]internal.Target{
+					{Name: "noble-amd64", OS: "linux", Arch: "amd64", Distros: []internal.Distro{{Name: "ubuntu", Version: "24.04"}}},
+					{Name: "jammy-arm64", OS: "linux", Arch: "arm64", Distros: []internal.Distro{{Name: "ubuntu", Version: "22.04"}, {Name: "ubuntu", Version: "24.04"}}},
+				}))
+			})
+		})
+
+		context("when several targets share an OS and architecture", func() {
+			it("merges them", func() {
+				targets, err := internal.ResolveTargets([]internal.BuildpackTarget{
+					{OS: "linux", Arch: "amd64", Distros: []internal.Distro{{Name: "ubuntu", Version: "22.04"}}},
+					{OS: "linux", Arch: "amd64", Distros: []internal.Distro{{Name: "ubuntu", Version: "24.04"}}},
+				}, distros)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(targets).To(Equal([]internal.Target{
+					{Name: "jammy-amd64", OS: "linux", Arch: "amd64", Distros: []internal.Distro{{Name: "ubuntu", Version: "22.04"}, {Name: "ubuntu", Version: "24.04"}}},
+				}))
+			})
+		})
+
+		// The compile workflow runs one job per metadata entry and names its
+		// artifact after the target, os and arch of the entry, so the targets
+		// of buildpack.toml must each resolve to exactly one uniquely named
+		// target that covers every default distro.
+		context("with the targets of the buildpack", func() {
+			it("lines the compile matrix up with buildpack.toml", func() {
+				var config struct {
+					Targets []internal.BuildpackTarget `toml:"targets"`
+				}
+				_, err := toml.DecodeFile(filepath.Join("..", "..", "..", "buildpack.toml"), &config)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Targets).NotTo(BeEmpty())
+
+				defaults, err := internal.ParseDistros(internal.DefaultDistros)
+				Expect(err).NotTo(HaveOccurred())
+
+				targets, err := internal.ResolveTargets(config.Targets, defaults)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(targets).To(HaveLen(len(config.Targets)))
+
+				names := map[string]bool{}
+				for i, target := range targets {
+					Expect(target.OS).To(Equal(config.Targets[i].OS))
+					Expect(target.Arch).To(Equal(config.Targets[i].Arch))
+					Expect(target.Distros).To(HaveLen(len(defaults)))
+
+					Expect(names).NotTo(HaveKey(target.Name))
+					names[target.Name] = true
+				}
+			})
+		})
+
+		context("failure cases", func() {
+			context("when no distro matches a target", func() {
+				it("returns an error", func() {
+					_, err := internal.ResolveTargets([]internal.BuildpackTarget{
+						{OS: "linux", Arch: "amd64", Distros: []internal.Distro{{Name: "alpine"}}},
+					}, distros)
+					Expect(err).To(MatchError("no configured distro matches target linux/amd64"))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// stripComponents is the number of leading path components that the
+// buildpack strips from the entries of an artifact.
+const stripComponents = 2
+
+var gemspecVersionPattern = regexp.MustCompile(`(?m)^\s*s\.version = "([^"]+)"`)
+
+// ArtifactValidator checks the structure of a compiled Bundler artifact
+// without unpacking it or running Ruby.
+type ArtifactValidator struct {
+	name string
+}
+
+func NewArtifactValidator() ArtifactValidator {
+	return ArtifactValidator{name: depID}
+}
+
+type artifactEntry struct {
+	header  *tar.Header
+	content []byte
+}
+
+// Validate opens a compiled artifact and checks that, once its first two path
+// components are stripped, it holds executable bundle and bundler binstubs
+// with portable shebangs and the specification of the version, and no cached
+// .gem files. All problems are reported together.
+func (v ArtifactValidator) Validate(artifactPath, version string) error {
+	file, err := os.Open(artifactPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries, problems, err := v.read(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", artifactPath, err)
+	}
+
+	for _, executable := range []string{"bin/bundle", "bin/bundler"} {
+		entry, ok := entries[executable]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Errorf("missing %s", executable))
+			continue
+		case entry.header.Typeflag != tar.TypeReg:
+			problems = append(problems, fmt.Errorf("%s is not a regular file", executable))
+			continue
+		case entry.header.Mode&0111 != 0111:
+			problems = append(problems, fmt.Errorf("%s is not executable (mode %04o)", executable, entry.header.Mode&07777))
+		}
+
+		shebang, _, _ := strings.Cut(string(entry.content), "\n")
+		if shebang != "#!/usr/bin/env ruby" {
+			problems = append(problems, fmt.Errorf("%s has shebang %q, expected %q", executable, shebang, "#!/usr/bin/env ruby"))
+		}
+	}
+
+	specification := fmt.Sprintf("specifications/%s-%s.gemspec", v.name, version)
+	if entry, ok := entries[specification]; !ok {
+		problems = append(problems, fmt.Errorf("missing %s", specification))
+	} else if match := gemspecVersionPattern.FindSubmatch(entry.content); match == nil {
+		problems = append(problems, fmt.Errorf("%s does not set a version", specification))
+	} else if string(match[1]) != version {
+		problems = append(problems, fmt.Errorf("%s has version %s, expected %s", specification, match[1], version))
+	}
+
+	gemDir := fmt.Sprintf("gems/%s-%s", v.name, version)
+	if entry, ok := entries[gemDir]; !ok || entry.header.Typeflag != tar.TypeDir {
+		if !hasPrefix(entries, gemDir+"/") {
+			problems = append(problems, fmt.Errorf("missing %s", gemDir))
+		}
+	}
+
+	for name := range entries {
+		if path.Dir(name) == "cache" && strings.HasSuffix(name, ".gem") {
+			problems = append(problems, fmt.Errorf("%s was left behind", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid artifact %s:\n%w", artifactPath, errors.Join(problems...))
+	}
+
+	return nil
+}
+
+// read returns the entries of the artifact by the path they have once
+// stripComponents are stripped, along with the problems of entries that do
+// not fit under a single root.
+func (v ArtifactValidator) read(artifact io.Reader) (map[string]artifactEntry, []error, error) {
+	gz, err := gzip.NewReader(bufio.NewReader(artifact))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gz.Close()
+
+	var (
+		entries  = map[string]artifactEntry{}
+		problems []error
+		root     string
+	)
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(header.Name) || name == ".." || strings.HasPrefix(name, "../") {
+			problems = append(problems, fmt.Errorf("%s is outside of the artifact", header.Name))
+			continue
+		}
+
+		components := strings.Split(name, "/")
+		if len(components) <= stripComponents {
+			if header.Typeflag != tar.TypeDir {
+				problems = append(problems, fmt.Errorf("%s is removed by strip-components = %d", header.Name, stripComponents))
+			}
+			continue
+		}
+
+		prefix := strings.Join(components[:stripComponents], "/")
+		if root == "" {
+			root = prefix
+		} else if prefix != root {
+			problems = append(problems, fmt.Errorf("%s is not under %s/", header.Name, root))
+			continue
+		}
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entries[strings.Join(components[stripComponents:], "/")] = artifactEntry{header: header, content: content}
+	}
+
+	return entries, problems, nil
+}
+
+func hasPrefix(entries map[string]artifactEntry, prefix string) bool {
+	for name := range entries {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
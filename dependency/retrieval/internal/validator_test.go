@@ -0,0 +1,189 @@
+package internal_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testArtifactValidator(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		artifactPath string
+		validator    internal.ArtifactValidator
+	)
+
+	it.Before(func() {
+		artifactPath = filepath.Join(t.TempDir(), "bundler.tgz")
+		validator = internal.NewArtifactValidator()
+	})
+
+	writeArtifact := func(entries []tarEntry) {
+		Expect(os.WriteFile(artifactPath, gzipBytes(t, writeTar(t, entries)), 0644)).To(Succeed())
+	}
+
+	validEntries := func() []tarEntry {
+		return []tarEntry{
+			{name: "input/", mode: 0755},
+			{name: "input/bundler/", mode: 0755},
+			{name: "input/bundler/bin/bundle", mode: 0755, content: "#!/usr/bin/env ruby\nload Gem.bin_path(\"bundler\", \"bundle\")\n"},
+			{name: "input/bundler/bin/bundler", mode: 0755, content: "#!/usr/bin/env ruby\nload Gem.bin_path(\"bundler\", \"bundler\")\n"},
+			{name: "input/bundler/cache/", mode: 0755},
+			{name: "input/bundler/gems/bundler-2.7.2/lib/bundler.rb", content: "module Bundler; end\n"},
+			{name: "input/bundler/specifications/bundler-2.7.2.gemspec", content: "# stub: bundler 2.7.2 ruby lib\n\nGem::Specification.new do |s|\n  s.name = \"bundler\".freeze\n  s.version = \"2.7.2\".freeze\nend\n"},
+		}
+	}
+
+	context("Validate", func() {
+		it("accepts an artifact packaged by the compiler", func() {
+			gem := buildGem(t, gemMetadata, map[string]string{
+				"exe/bundle":     "#!/usr/bin/env ruby\n",
+				"exe/bundler":    "#!/usr/bin/env ruby\n",
+				"lib/bundler.rb": "module Bundler; end\n",
+			}, true)
+
+			transport := &fakes.Transport{}
+			transport.DropCall.Stub = func(string, string) (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(gem)), nil
+			}
+
+			artifact, err := internal.NewGemCompiler(transport).Compile("2.7.2", "jammy", t.TempDir())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(validator.Validate(artifact.Path, "2.7.2")).To(Succeed())
+		})
+
+		it("accepts an artifact with the expected layout", func() {
+			writeArtifact(validEntries())
+
+			Expect(validator.Validate(artifactPath, "2.7.2")).To(Succeed())
+		})
+
+		context("when the executables are missing", func() {
+			it.Before(func() {
+				writeArtifact(validEntries()[4:])
+			})
+
+			it("reports both of them", func() {
+				err := validator.Validate(artifactPath, "2.7.2")
+				Expect(err).To(MatchError(ContainSubstring("missing bin/bundle\n")))
+				Expect(err).To(MatchError(ContainSubstring("missing bin/bundler")))
+			})
+		})
+
+		context("when an executable is not executable by everyone", func() {
+			it.Before(func() {
+				entries := validEntries()
+				entries[3].mode = 0744
+				writeArtifact(entries)
+			})
+
+			it("returns an error", func() {
+				err := validator.Validate(artifactPath, "2.7.2")
+				Expect(err).To(MatchError(ContainSubstring("bin/bundler is not executable (mode 0744)")))
+			})
+		})
+
+		context("when an executable does not use a portable shebang", func() {
+			it.Before(func() {
+				entries := validEntries()
+				entries[2].content = "#!/opt/ruby/bin/ruby\nload Gem.bin_path(\"bundler\", \"bundle\")\n"
+				writeArtifact(entries)
+			})
+
+			it("returns an error", func() {
+				err := validator.Validate(artifactPath, "2.7.2")
+				Expect(err).To(MatchError(ContainSubstring(`bin/bundle has shebang "#!/opt/ruby/bin/ruby", expected "#!/usr/bin/env ruby"`)))
+			})
+		})
+
+		context("when the specification is for another version", func() {
+			it("returns an error", func() {
+				writeArtifact(validEntries())
+
+				err := validator.Validate(artifactPath, "2.7.3")
+				Expect(err).To(MatchError(ContainSubstring("missing specifications/bundler-2.7.3.gemspec")))
+				Expect(err).To(MatchError(ContainSubstring("missing gems/bundler-2.7.3")))
+			})
+		})
+
+		context("when the specification sets another version", func() {
+			it.Before(func() {
+				entries := validEntries()
+				entries[6].content = "Gem::Specification.new do |s|\n  s.version = \"2.7.1\".freeze\nend\n"
+				writeArtifact(entries)
+			})
+
+			it("returns an error", func() {
+				err := validator.Validate(artifactPath, "2.7.2")
+				Expect(err).To(MatchError(ContainSubstring("specifications/bundler-2.7.2.gemspec has version 2.7.1, expected 2.7.2")))
+			})
+		})
+
+		context("when the gem is left in the cache", func() {
+			it.Before(func() {
+				writeArtifact(append(validEntries(), tarEntry{name: "input/bundler/cache/bundler-2.7.2.gem", content: "gem"}))
+			})
+
+			it("returns an error", func() {
+				err := validator.Validate(artifactPath, "2.7.2")
+				Expect(err).To(MatchError(ContainSubstring("cache/bundler-2.7.2.gem was left behind")))
+			})
+		})
+
+		context("when the entries are not under a single root", func() {
+			it.Before(func() {
+				writeArtifact(append(validEntries(),
+					tarEntry{name: "bundler/README.md", content: "readme"},
+					tarEntry{name: "input/other/bin/bundle", mode: 0755, content: "#!/usr/bin/env ruby\n"},
+				))
+			})
+
+			it("returns an error", func() {
+				err := validator.Validate(artifactPath, "2.7.2")
+				Expect(err).To(MatchError(ContainSubstring("bundler/README.md is removed by strip-components = 2")))
+				Expect(err).To(MatchError(ContainSubstring("input/other/bin/bundle is not under input/bundler/")))
+			})
+		})
+
+		context("when an entry escapes the artifact", func() {
+			it.Before(func() {
+				writeArtifact(append(validEntries(), tarEntry{name: "../etc/passwd", content: "root"}))
+			})
+
+			it("returns an error", func() {
+				err := validator.Validate(artifactPath, "2.7.2")
+				Expect(err).To(MatchError(ContainSubstring("../etc/passwd is outside of the artifact")))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the artifact does not exist", func() {
+				it("returns an error", func() {
+					err := validator.Validate(filepath.Join(t.TempDir(), "missing.tgz"), "2.7.2")
+					Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+				})
+			})
+
+			context("when the artifact is not gzipped", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(artifactPath, []byte("not a tarball"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := validator.Validate(artifactPath, "2.7.2")
+					Expect(err).To(MatchError(ContainSubstring("failed to read")))
+				})
+			})
+		})
+	})
+}
@@ -1,12 +1,57 @@
 package internal
 
 import (
+	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/paketo-buildpacks/packit/cargo"
 )
 
+// The statuses of a RemovedVersion.
+const (
+	StatusYanked   = "yanked"
+	StatusMissing  = "missing"
+	StatusUnlisted = "unlisted"
+)
+
+// RemovedVersion is a version in buildpack.toml that the release index no
+// longer offers. Status is "yanked" when the index reports the version as
+// yanked and "missing" when the index reports yanked versions but not this
+// one. Indexes that do not report yanked versions at all, such as the
+// RubyGems JSON API, just leave yanked versions out, so the versions they do
+// not list are "unlisted": they were most likely yanked, but the index cannot
+// tell.
+type RemovedVersion struct {
+	Version string `json:"version"`
+	Status  string `json:"status"`
+}
+
+// UncoveredMajor groups the releases of a major version that no dependency
+// constraint covers. Constraints holds the proposed constraint blocks, if
+// they were asked for.
+type UncoveredMajor struct {
+	Major       int64                                      `json:"major"`
+	Minors      []UncoveredMinor                           `json:"minors"`
+	Constraints []cargo.ConfigMetadataDependencyConstraint `json:"proposed_constraints,omitempty"`
+}
+
+// UncoveredMinor groups the releases of a minor version that no dependency
+// constraint covers.
+type UncoveredMinor struct {
+	Minor    string             `json:"minor"`
+	Releases []UncoveredRelease `json:"releases"`
+}
+
+// UncoveredRelease is a release that no dependency constraint covers.
+// ReleasedAt is nil when the release index does not publish release dates.
+type UncoveredRelease struct {
+	Version    string     `json:"version"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
 type VersionFinder struct {
 	depID string
 }
@@ -57,6 +102,185 @@ func (v VersionFinder) FindNewVersions(bpTOML cargo.Config, releases []Release)
 	return newVersions, nil
 }
 
+// FindRemovedVersions compares every dependency version in buildpack.toml
+// with the releases of the index and returns those that are no longer
+// available, in the order they appear in buildpack.toml. reportsYanked tells
+// whether yanked lists the versions the index reports as yanked.
+func (v VersionFinder) FindRemovedVersions(bpTOML cargo.Config, releases []Release, yanked []string, reportsYanked bool) []RemovedVersion {
+	available := map[string]bool{}
+	for _, release := range releases {
+		if release.Platform == "" || release.Platform == "ruby" {
+			available[release.Version] = true
+		}
+	}
+
+	yankedVersions := map[string]bool{}
+	for _, version := range yanked {
+		yankedVersions[version] = true
+	}
+
+	var removed []RemovedVersion
+	seen := map[string]bool{}
+	for _, dependency := range bpTOML.Metadata.Dependencies {
+		if dependency.ID != v.depID || seen[dependency.Version] || available[dependency.Version] {
+			continue
+		}
+		seen[dependency.Version] = true
+
+		status := StatusUnlisted
+		switch {
+		case yankedVersions[dependency.Version]:
+			status = StatusYanked
+		case reportsYanked:
+			status = StatusMissing
+		}
+
+		removed = append(removed, RemovedVersion{Version: dependency.Version, Status: status})
+	}
+
+	return removed
+}
+
+// FindNewUncoveredVersions returns the releases that are newer than the
+// newest release covered by a dependency constraint of buildpack.toml without
+// being covered by one themselves, grouped by major and minor version.
+// Releases older than the newest covered one, such as those of majors the
+// buildpack has dropped, are left out. With propose, each major carries the
+// constraint blocks that would cover it: a single block for a major that no
+// constraint touches and one block per minor otherwise. A proposed block
+// takes its patches count from the constraint it extends, i.e. the one that
+// covers the newest covered release of the major, or of any major for a new
+// major.
+func (v VersionFinder) FindNewUncoveredVersions(bpTOML cargo.Config, releases []Release, propose bool) ([]UncoveredMajor, error) {
+	type constraint struct {
+		svConstraint *semver.Constraints
+		patches      int
+	}
+
+	var constraints []constraint
+	for _, c := range bpTOML.Metadata.DependencyConstraints {
+		if c.ID != v.depID {
+			continue
+		}
+
+		svConstraint, err := semver.NewConstraint(c.Constraint)
+		if err != nil {
+			return nil, err
+		}
+
+		constraints = append(constraints, constraint{svConstraint: svConstraint, patches: c.Patches})
+	}
+
+	// The patches count of a proposal that extends nothing.
+	const defaultPatches = 2
+
+	var (
+		latestCovered *semver.Version
+		latestPatches = defaultPatches
+		uncovered     []Release
+		majorLatest   = map[int64]*semver.Version{}
+		majorPatches  = map[int64]int{}
+		seen          = map[string]bool{}
+	)
+	for _, release := range releases {
+		if (release.Platform != "" && release.Platform != "ruby") || release.Prerelease || seen[release.Version] {
+			continue
+		}
+		seen[release.Version] = true
+
+		svVersion, err := semver.NewVersion(release.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		covering := -1
+		for i, c := range constraints {
+			if c.svConstraint.Check(svVersion) {
+				covering = i
+				break
+			}
+		}
+
+		if covering < 0 {
+			uncovered = append(uncovered, release)
+			continue
+		}
+
+		if latest := majorLatest[svVersion.Major()]; latest == nil || svVersion.GreaterThan(latest) {
+			majorLatest[svVersion.Major()] = svVersion
+			majorPatches[svVersion.Major()] = constraints[covering].patches
+		}
+
+		if latestCovered == nil || svVersion.GreaterThan(latestCovered) {
+			latestCovered = svVersion
+			latestPatches = constraints[covering].patches
+		}
+	}
+
+	sort.Slice(uncovered, func(i, j int) bool {
+		return semver.MustParse(uncovered[i].Version).LessThan(semver.MustParse(uncovered[j].Version))
+	})
+
+	var majors []UncoveredMajor
+	for _, release := range uncovered {
+		svVersion := semver.MustParse(release.Version)
+		if latestCovered != nil && !svVersion.GreaterThan(latestCovered) {
+			continue
+		}
+
+		if len(majors) == 0 || majors[len(majors)-1].Major != svVersion.Major() {
+			majors = append(majors, UncoveredMajor{Major: svVersion.Major()})
+		}
+		major := &majors[len(majors)-1]
+
+		minor := fmt.Sprintf("%d.%d", svVersion.Major(), svVersion.Minor())
+		if len(major.Minors) == 0 || major.Minors[len(major.Minors)-1].Minor != minor {
+			major.Minors = append(major.Minors, UncoveredMinor{Minor: minor})
+		}
+		group := &major.Minors[len(major.Minors)-1]
+
+		group.Releases = append(group.Releases, UncoveredRelease{
+			Version:    release.Version,
+			ReleasedAt: release.CreatedAt,
+		})
+	}
+
+	if propose {
+		for i, major := range majors {
+			patches, covered := majorPatches[major.Major]
+			if !covered {
+				majors[i].Constraints = append(majors[i].Constraints, cargo.ConfigMetadataDependencyConstraint{
+					Constraint: fmt.Sprintf("%d.*.*", major.Major),
+					ID:         v.depID,
+					Patches:    latestPatches,
+				})
+				continue
+			}
+
+			for _, minor := range major.Minors {
+				majors[i].Constraints = append(majors[i].Constraints, cargo.ConfigMetadataDependencyConstraint{
+					Constraint: minor.Minor + ".*",
+					ID:         v.depID,
+					Patches:    patches,
+				})
+			}
+		}
+	}
+
+	return majors, nil
+}
+
+// FormatConstraints formats dependency constraints as they appear in
+// buildpack.toml.
+func FormatConstraints(constraints []cargo.ConfigMetadataDependencyConstraint) string {
+	var blocks []string
+	for _, constraint := range constraints {
+		blocks = append(blocks, fmt.Sprintf("  [[metadata.dependency-constraints]]\n    constraint = %q\n    id = %q\n    patches = %d\n", constraint.Constraint, constraint.ID, constraint.Patches))
+	}
+
+	return strings.Join(blocks, "\n")
+}
+
 func getLatestKnownVersion(deps []cargo.ConfigMetadataDependency, constraint *semver.Constraints) *semver.Version {
 	latestVersion := semver.MustParse("0.0.0")
 	for _, dependency := range deps {
@@ -2,6 +2,7 @@ package internal_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
 	"github.com/paketo-buildpacks/packit/cargo"
@@ -126,4 +127,187 @@ func testVersionFinder(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("FindRemovedVersions", func() {
+		it("reports the versions that the index no longer offers", func() {
+			result := vf.FindRemovedVersions(
+				cargo.Config{
+					Metadata: cargo.ConfigMetadata{
+						Dependencies: []cargo.ConfigMetadataDependency{
+							{ID: "bundler", Version: "2.7.1", Stacks: []string{"io.buildpacks.stacks.jammy"}},
+							{ID: "bundler", Version: "2.7.1", Stacks: []string{"io.buildpacks.stacks.noble"}},
+							{ID: "bundler", Version: "2.7.2"},
+							{ID: "bundler", Version: "2.6.9"},
+							{ID: "bundler", Version: "2.5.0"},
+							{ID: "something-else", Version: "1.0.0"},
+						},
+					},
+				},
+				[]internal.Release{
+					{Version: "2.7.2", Platform: "ruby"},
+					{Version: "2.6.9", Platform: "java"},
+					{Version: "2.5.0"},
+				},
+				[]string{"2.7.1"},
+				true,
+			)
+			Expect(result).To(Equal([]internal.RemovedVersion{
+				{Version: "2.7.1", Status: "yanked"},
+				{Version: "2.6.9", Status: "missing"},
+			}))
+		})
+
+		it("reports the versions as unlisted when the index does not report yanked versions", func() {
+			result := vf.FindRemovedVersions(
+				cargo.Config{
+					Metadata: cargo.ConfigMetadata{
+						Dependencies: []cargo.ConfigMetadataDependency{
+							{ID: "bundler", Version: "2.7.1"},
+							{ID: "bundler", Version: "2.7.2"},
+						},
+					},
+				},
+				[]internal.Release{{Version: "2.7.2", Platform: "ruby"}},
+				nil,
+				false,
+			)
+			Expect(result).To(Equal([]internal.RemovedVersion{
+				{Version: "2.7.1", Status: "unlisted"},
+			}))
+		})
+
+		it("returns nothing when every version is available", func() {
+			result := vf.FindRemovedVersions(
+				cargo.Config{
+					Metadata: cargo.ConfigMetadata{
+						Dependencies: []cargo.ConfigMetadataDependency{
+							{ID: "bundler", Version: "2.7.2"},
+						},
+					},
+				},
+				[]internal.Release{{Version: "2.7.2", Platform: "ruby"}},
+				nil,
+				true,
+			)
+			Expect(result).To(BeEmpty())
+		})
+	})
+
+	context("FindNewUncoveredVersions", func() {
+		var (
+			config     cargo.Config
+			releases   []internal.Release
+			releasedAt time.Time
+		)
+
+		it.Before(func() {
+			config = cargo.Config{
+				Metadata: cargo.ConfigMetadata{
+					DependencyConstraints: []cargo.ConfigMetadataDependencyConstraint{
+						{ID: "bundler", Constraint: "2.3.*", Patches: 3},
+						{ID: "bundler", Constraint: "1.*.*", Patches: 1},
+						{ID: "something-else", Constraint: "*", Patches: 1},
+					},
+				},
+			}
+
+			releasedAt = time.Date(2025, time.December, 3, 0, 0, 0, 0, time.UTC)
+			releases = []internal.Release{
+				{Version: "4.0.1", CreatedAt: &releasedAt},
+				{Version: "4.0.0"},
+				{Version: "4.1.0.pre.1", Prerelease: true},
+				{Version: "4.0.1", Platform: "java"},
+				{Version: "2.4.1"},
+				{Version: "2.3.9"},
+				{Version: "2.2.0"},
+				{Version: "1.17.3"},
+			}
+		})
+
+		it("groups the versions newer than the covered ones that no constraint covers by major and minor", func() {
+			result, err := vf.FindNewUncoveredVersions(config, releases, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal([]internal.UncoveredMajor{
+				{
+					Major: 2,
+					Minors: []internal.UncoveredMinor{
+						{Minor: "2.4", Releases: []internal.UncoveredRelease{{Version: "2.4.1"}}},
+					},
+				},
+				{
+					Major: 4,
+					Minors: []internal.UncoveredMinor{
+						{Minor: "4.0", Releases: []internal.UncoveredRelease{{Version: "4.0.0"}, {Version: "4.0.1", ReleasedAt: &releasedAt}}},
+					},
+				},
+			}))
+		})
+
+		context("when constraints are proposed", func() {
+			it("proposes a block for each uncovered major or minor with the patches of the constraint it extends", func() {
+				result, err := vf.FindNewUncoveredVersions(config, releases, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(HaveLen(2))
+				Expect(result[0].Constraints).To(Equal([]cargo.ConfigMetadataDependencyConstraint{
+					{ID: "bundler", Constraint: "2.4.*", Patches: 3},
+				}))
+				Expect(result[1].Constraints).To(Equal([]cargo.ConfigMetadataDependencyConstraint{
+					{ID: "bundler", Constraint: "4.*.*", Patches: 3},
+				}))
+
+				Expect(internal.FormatConstraints(result[1].Constraints)).To(Equal(`  [[metadata.dependency-constraints]]
+    constraint = "4.*.*"
+    id = "bundler"
+    patches = 3
+`))
+			})
+
+			context("when the releases belong to a covered major", func() {
+				it.Before(func() {
+					config.Metadata.DependencyConstraints = append(config.Metadata.DependencyConstraints,
+						cargo.ConfigMetadataDependencyConstraint{ID: "bundler", Constraint: "4.0.0", Patches: 5},
+					)
+					releases = append(releases, internal.Release{Version: "4.2.0"})
+				})
+
+				it("takes the patches from the constraint that covers the major", func() {
+					result, err := vf.FindNewUncoveredVersions(config, releases, true)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(HaveLen(1))
+					Expect(result[0].Major).To(Equal(int64(4)))
+					Expect(result[0].Constraints).To(Equal([]cargo.ConfigMetadataDependencyConstraint{
+						{ID: "bundler", Constraint: "4.0.*", Patches: 5},
+						{ID: "bundler", Constraint: "4.2.*", Patches: 5},
+					}))
+				})
+			})
+		})
+
+		context("when every version is covered", func() {
+			it.Before(func() {
+				config.Metadata.DependencyConstraints = append(config.Metadata.DependencyConstraints,
+					cargo.ConfigMetadataDependencyConstraint{ID: "bundler", Constraint: "2.4.*"},
+					cargo.ConfigMetadataDependencyConstraint{ID: "bundler", Constraint: "4.*.*"},
+				)
+			})
+
+			it("returns nothing", func() {
+				result, err := vf.FindNewUncoveredVersions(config, releases, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(BeEmpty())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a constraint is invalid", func() {
+				it.Before(func() {
+					config.Metadata.DependencyConstraints[0].Constraint = "not-a-constraint"
+				})
+
+				it("returns an error", func() {
+					_, err := vf.FindNewUncoveredVersions(config, releases, false)
+					Expect(err).To(MatchError(ContainSubstring("improper constraint")))
+				})
+			})
+		})
+	})
 }
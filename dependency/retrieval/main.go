@@ -17,7 +17,8 @@ import (
 const depID string = "bundler"
 
 type buildpackConfig struct {
-	Stacks []stackConfig `toml:"stacks"`
+	Stacks  []stackConfig              `toml:"stacks"`
+	Targets []internal.BuildpackTarget `toml:"targets"`
 }
 
 type stackConfig struct {
@@ -43,9 +44,34 @@ func resolveTarget(stackIDs []string) string {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "update-buildpack-toml":
+			updateBuildpackTOML(os.Args[2:])
+			return
+		case "compile":
+			compile(os.Args[2:])
+			return
+		case "verify-reproducible":
+			verifyReproducible(os.Args[2:])
+			return
+		case "validate":
+			validate(os.Args[2:])
+			return
+		}
+	}
+
 	var bpTOML = flag.String("buildpack-toml-path", "", "Path to buildpack.toml with existing dependencies")
 	var output = flag.String("output", "", "the path to a file into which an output metadata JSON will be written")
 	var releaseIndex = flag.String("release-index", "https://rubygems.org/api/v1/versions/bundler.json", "the release index to search for new versions")
+	var releaseIndexProtocol = flag.String("release-index-protocol", internal.ProtocolAuto, "the protocol of the release index: auto, json or compact-index")
+	var releaseDetailsIndex = flag.String("release-details-index", "", "optional release index in the RubyGems JSON API format to read the licenses and release dates from when --release-index is a compact index, which does not publish them")
+	var removedOutput = flag.String("removed-output", "", "optional path to a file into which the buildpack.toml versions that were yanked from or are missing in the release index will be written as JSON")
+	var uncoveredOutput = flag.String("uncovered-output", "", "optional path to a file into which the released versions that are newer than every covered version but match no dependency constraint will be written as JSON")
+	var proposeConstraints = flag.Bool("propose-constraints", false, "propose dependency constraints that would cover the versions written to --uncovered-output")
+	var downloadWorkers = flag.Int("download-workers", 4, "the number of release sources to download and verify concurrently")
+	var eolData = flag.String("eol-data", "", "optional path to a file of end-of-life dates in the endoflife.date JSON format")
+	var distros = flag.String("distros", internal.DefaultDistros, "comma-separated <target>=<name>:<version> distributions to generate metadata for on each buildpack.toml target")
 
 	flag.Parse()
 
@@ -57,7 +83,15 @@ func main() {
 		log.Fatal("output is required")
 	}
 
-	fetcher := internal.NewReleaseFetcher(*releaseIndex)
+	fetcher, err := internal.NewReleaseIndex(*releaseIndex, *releaseIndexProtocol)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if compactIndex, ok := fetcher.(internal.CompactIndexFetcher); ok && *releaseDetailsIndex != "" {
+		fetcher = compactIndex.WithDetailsIndex(*releaseDetailsIndex)
+	}
+
 	availableVersions, err := fetcher.Get()
 	if err != nil {
 		log.Fatal(err)
@@ -78,23 +112,101 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *removedOutput != "" {
+		var yanked []string
+		lister, reportsYanked := fetcher.(internal.YankedVersionLister)
+		if reportsYanked {
+			yanked, err = lister.Yanked()
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		removed := finder.FindRemovedVersions(config, availableVersions, yanked, reportsYanked)
+		log.Printf("Removed versions: %+v", removed)
+
+		if removed == nil {
+			removed = []internal.RemovedVersion{}
+		}
+
+		bytes, err := json.Marshal(removed)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err = os.WriteFile(*removedOutput, bytes, os.ModePerm); err != nil {
+			log.Fatal(fmt.Errorf("cannot write to %s: %w", *removedOutput, err))
+		}
+
+		log.Printf("Wrote removed versions to %s\n", *removedOutput)
+	}
+
+	if *uncoveredOutput != "" {
+		uncovered, err := finder.FindNewUncoveredVersions(config, availableVersions, *proposeConstraints)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, major := range uncovered {
+			log.Printf("No dependency constraint covers Bundler %d: %+v", major.Major, major.Minors)
+			if len(major.Constraints) > 0 {
+				log.Printf("Proposed dependency constraints:\n%s", internal.FormatConstraints(major.Constraints))
+			}
+		}
+
+		if uncovered == nil {
+			uncovered = []internal.UncoveredMajor{}
+		}
+
+		bytes, err := json.Marshal(uncovered)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err = os.WriteFile(*uncoveredOutput, bytes, os.ModePerm); err != nil {
+			log.Fatal(fmt.Errorf("cannot write to %s: %w", *uncoveredOutput, err))
+		}
+
+		log.Printf("Wrote uncovered versions to %s\n", *uncoveredOutput)
+	}
+
 	log.Printf("New versions: %+v", newVersions)
 
+	if *eolData != "" {
+		schedule, err := internal.LoadEOLSchedule(*eolData)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for i, v := range newVersions {
+			newVersions[i].DeprecationDate = schedule.DeprecationDate(v.Version)
+		}
+	}
+
 	var stackIDs []string
 	for _, stack := range bpConfig.Stacks {
 		stackIDs = append(stackIDs, stack.ID)
 	}
 
-	target := resolveTarget(stackIDs)
+	// Buildpacks without a [[targets]] table get a single target named after
+	// their last stack.
+	targets := []internal.Target{{Name: resolveTarget(stackIDs)}}
+	if len(bpConfig.Targets) > 0 {
+		configuredDistros, err := internal.ParseDistros(*distros)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	var allMetadata []internal.ReleaseMetadata
-	generator := internal.NewMetadataGenerator(fs.NewChecksumCalculator(), internal.NewPURLGenerator())
-	for _, v := range newVersions {
-		metadata, err := generator.Generate(v, stackIDs, target)
+		targets, err = internal.ResolveTargets(bpConfig.Targets, configuredDistros)
 		if err != nil {
 			log.Fatal(err)
 		}
-		allMetadata = append(allMetadata, metadata)
+	}
+
+	generator := internal.NewMetadataGenerator(cargo.NewTransport(), fs.NewChecksumCalculator(), internal.NewPURLGenerator())
+	allMetadata, err := generator.GenerateAll(newVersions, stackIDs, targets, *downloadWorkers)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	bytes, err := json.Marshal(allMetadata)
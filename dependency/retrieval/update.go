@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+)
+
+// updateBuildpackTOML implements the update-buildpack-toml subcommand, which
+// adds the metadata written by the retrieval, and the artifacts compiled
+// from it, to buildpack.toml.
+func updateBuildpackTOML(args []string) {
+	flags := flag.NewFlagSet("update-buildpack-toml", flag.ExitOnError)
+
+	var bpTOML = flags.String("buildpack-toml-path", "", "Path to the buildpack.toml to update")
+	var metadataPath = flags.String("metadata", "", "the path to the metadata JSON written by the retrieval")
+	var artifactsPath = flags.String("artifacts", "", "optional path to a JSON array of the compiled artifacts, with the version, target, os, arch, uri and checksum of each, and the provenance-uri and provenance-checksum of its provenance statement")
+	var defaultVersion = flags.String("default-version", "", "optional default version to set in [metadata.default-versions]")
+	var removedPath = flags.String("removed", "", "optional path to the JSON written to --removed-output by the retrieval, whose yanked versions are added to [metadata.yanked-versions]")
+
+	err := flags.Parse(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *bpTOML == "" {
+		log.Fatal("buildpack-toml-path is required")
+	}
+
+	if *metadataPath == "" {
+		log.Fatal("metadata is required")
+	}
+
+	var update internal.BuildpackTOMLUpdate
+	update.DefaultVersion = *defaultVersion
+
+	err = readJSON(*metadataPath, &update.Metadata)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *artifactsPath != "" {
+		err = readJSON(*artifactsPath, &update.Artifacts)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *removedPath != "" {
+		err = readJSON(*removedPath, &update.Removed)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	content, err := os.ReadFile(*bpTOML)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	updated, err := internal.NewBuildpackTOMLUpdater().Update(string(content), update)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	info, err := os.Stat(*bpTOML)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err = os.WriteFile(*bpTOML, []byte(updated), info.Mode()); err != nil {
+		log.Fatal(fmt.Errorf("cannot write to %s: %w", *bpTOML, err))
+	}
+
+	log.Printf("Updated %s with %d dependencies\n", *bpTOML, len(update.Metadata))
+}
+
+func readJSON(path string, v interface{}) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(content, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/paketo-buildpacks/bundler/dependency/retrieval/internal"
+)
+
+// validate implements the validate subcommand, which checks the layout of a
+// compiled artifact without Docker or Ruby.
+func validate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+
+	var artifact = flags.String("artifact", "", "the path to the compiled artifact")
+	var version = flags.String("version", "", "the version of Bundler the artifact contains")
+
+	err := flags.Parse(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *artifact == "" {
+		log.Fatal("artifact is required")
+	}
+
+	if *version == "" {
+		log.Fatal("version is required")
+	}
+
+	err = internal.NewArtifactValidator().Validate(*artifact, *version)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("%s is a valid artifact for bundler %s\n", *artifact, *version)
+}
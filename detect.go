@@ -12,12 +12,24 @@ type VersionParser interface {
 	ParseVersion(path string) (version string, err error)
 }
 
+//go:generate faux --interface EngineParser --output fakes/engine_parser.go
+type EngineParser interface {
+	ParseEngine(path string) (engine RubyEngine, err error)
+}
+
+// rubyProviders maps a RUBY_ENGINE other than MRI onto the name of the build
+// plan entry provided by the buildpack that installs it.
+var rubyProviders = map[string]string{
+	"jruby":       "jruby",
+	"truffleruby": "truffleruby",
+}
+
 type BuildPlanMetadata struct {
 	VersionSource string `toml:"version-source"`
 	Version       string `toml:"version"`
 }
 
-func Detect(buildpackYMLParser, gemfileLockParser VersionParser) packit.DetectFunc {
+func Detect(buildpackYMLParser, gemfileLockParser VersionParser, engineParser EngineParser) packit.DetectFunc {
 	return func(context packit.DetectContext) (packit.DetectResult, error) {
 		var requirements []packit.BuildPlanRequirement
 
@@ -39,12 +51,20 @@ func Detect(buildpackYMLParser, gemfileLockParser VersionParser) packit.DetectFu
 			})
 		}
 
-		// check buildpack.yml
-		version, err := buildpackYMLParser.ParseVersion(filepath.Join(context.WorkingDir, BuildpackYMLSource))
+		// check buildpack.yml, unless it has been disabled as a version source
+		disableBuildpackYML, err := lookupBool("BP_BUNDLER_DISABLE_BUILDPACK_YML")
 		if err != nil {
 			return packit.DetectResult{}, err
 		}
 
+		version = ""
+		if !disableBuildpackYML {
+			version, err = buildpackYMLParser.ParseVersion(filepath.Join(context.WorkingDir, BuildpackYMLSource))
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+		}
+
 		if version != "" {
 			requirements = append(requirements, packit.BuildPlanRequirement{
 				Name: Bundler,
@@ -71,6 +91,38 @@ func Detect(buildpackYMLParser, gemfileLockParser VersionParser) packit.DetectFu
 			})
 		}
 
+		// check the Ruby engine of Gemfile.lock. MRI is left to whichever
+		// buildpack the builder orders in, so that .ruby-version and
+		// $BP_MRI_VERSION keep selecting the Ruby version. Other engines are
+		// required with the version they were locked with, when it is known.
+		engine, err := engineParser.ParseEngine(filepath.Join(context.WorkingDir, GemfileLockSource))
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		provider := os.Getenv("BP_BUNDLER_RUBY_PROVIDER")
+		if provider == "" && engine.Name != "" && engine.Name != "ruby" {
+			provider = engine.Name
+			if name, ok := rubyProviders[engine.Name]; ok {
+				provider = name
+			}
+		}
+
+		if provider != "" {
+			requirement := packit.BuildPlanRequirement{
+				Name: provider,
+			}
+
+			if engine.Name != "ruby" && engine.Version != "" {
+				requirement.Metadata = BuildPlanMetadata{
+					VersionSource: GemfileLockSource,
+					Version:       engine.Version,
+				}
+			}
+
+			requirements = append(requirements, requirement)
+		}
+
 		return packit.DetectResult{
 			Plan: packit.BuildPlan{
 				Provides: []packit.BuildPlanProvision{
@@ -18,14 +18,16 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 
 		buildpackYMLParser *fakes.VersionParser
 		gemfileLockParser  *fakes.VersionParser
+		engineParser       *fakes.EngineParser
 		detect             packit.DetectFunc
 	)
 
 	it.Before(func() {
 		buildpackYMLParser = &fakes.VersionParser{}
 		gemfileLockParser = &fakes.VersionParser{}
+		engineParser = &fakes.EngineParser{}
 
-		detect = bundler.Detect(buildpackYMLParser, gemfileLockParser)
+		detect = bundler.Detect(buildpackYMLParser, gemfileLockParser, engineParser)
 	})
 
 	it("returns a plan that provides bundler", func() {
@@ -103,6 +105,22 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 
 			Expect(buildpackYMLParser.ParseVersionCall.Receives.Path).To(Equal("/working-dir/buildpack.yml"))
 		})
+
+		context("when $BP_BUNDLER_DISABLE_BUILDPACK_YML is true", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_DISABLE_BUILDPACK_YML", "true")
+			})
+
+			it("does not read the buildpack.yml", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(BeEmpty())
+
+				Expect(buildpackYMLParser.ParseVersionCall.CallCount).To(Equal(0))
+			})
+		})
 	})
 
 	context("when the source code contains a Gemfile.lock file", func() {
@@ -134,6 +152,112 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("when the Gemfile.lock records the Ruby engine", func() {
+		it.Before(func() {
+			gemfileLockParser.ParseVersionCall.Returns.Version = "2.*.*"
+			engineParser.ParseEngineCall.Returns.Engine = bundler.RubyEngine{Name: "ruby", Version: "3.2.2"}
+		})
+
+		it("does not require MRI", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: "/working-dir",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: bundler.Bundler,
+					Metadata: bundler.BuildPlanMetadata{
+						VersionSource: "Gemfile.lock",
+						Version:       "2.*.*",
+					},
+				},
+			}))
+
+			Expect(engineParser.ParseEngineCall.Receives.Path).To(Equal("/working-dir/Gemfile.lock"))
+		})
+
+		context("when the engine is JRuby", func() {
+			it.Before(func() {
+				engineParser.ParseEngineCall.Returns.Engine = bundler.RubyEngine{Name: "jruby", Version: "9.4.5.0"}
+			})
+
+			it("requires the locked version of JRuby", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+					{
+						Name: bundler.Bundler,
+						Metadata: bundler.BuildPlanMetadata{
+							VersionSource: "Gemfile.lock",
+							Version:       "2.*.*",
+						},
+					},
+					{
+						Name: "jruby",
+						Metadata: bundler.BuildPlanMetadata{
+							VersionSource: "Gemfile.lock",
+							Version:       "9.4.5.0",
+						},
+					},
+				}))
+			})
+
+			context("when the Gemfile.lock records no engine version", func() {
+				it.Before(func() {
+					engineParser.ParseEngineCall.Returns.Engine = bundler.RubyEngine{Name: "jruby"}
+				})
+
+				it("requires JRuby without a version", func() {
+					result, err := detect(packit.DetectContext{
+						WorkingDir: "/working-dir",
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+						Name: "jruby",
+					}))
+				})
+			})
+		})
+
+		context("when the engine is TruffleRuby", func() {
+			it.Before(func() {
+				engineParser.ParseEngineCall.Returns.Engine = bundler.RubyEngine{Name: "truffleruby", Version: "23.1.2"}
+			})
+
+			it("requires the locked version of TruffleRuby", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "truffleruby",
+					Metadata: bundler.BuildPlanMetadata{
+						VersionSource: "Gemfile.lock",
+						Version:       "23.1.2",
+					},
+				}))
+			})
+		})
+
+		context("when $BP_BUNDLER_RUBY_PROVIDER is set", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_RUBY_PROVIDER", "ruby-runtime")
+			})
+
+			it("requires the configured provider", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "ruby-runtime",
+				}))
+			})
+		})
+	})
+
 	context("failure cases", func() {
 		context("when the buildpack.yml parser fails", func() {
 			it.Before(func() {
@@ -148,6 +272,19 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when $BP_BUNDLER_DISABLE_BUILDPACK_YML is invalid", func() {
+			it.Before(func() {
+				t.Setenv("BP_BUNDLER_DISABLE_BUILDPACK_YML", "sometimes")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).To(MatchError(`invalid value for BP_BUNDLER_DISABLE_BUILDPACK_YML: "sometimes" (must be "true" or "false")`))
+			})
+		})
+
 		context("when the Gemfile.lock parser fails", func() {
 			it.Before(func() {
 				gemfileLockParser.ParseVersionCall.Returns.Err = errors.New("failed to parse Gemfile.lock")
@@ -160,5 +297,18 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				Expect(err).To(MatchError("failed to parse Gemfile.lock"))
 			})
 		})
+
+		context("when the Ruby engine cannot be parsed", func() {
+			it.Before(func() {
+				engineParser.ParseEngineCall.Returns.Err = errors.New("failed to parse Gemfile.lock")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).To(MatchError("failed to parse Gemfile.lock"))
+			})
+		})
 	})
 }
@@ -0,0 +1,182 @@
+package bundler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// Emitter is the logger of the buildpack. It writes the text output of a
+// scribe.Emitter, unless it was created by NewJSONEmitter, in which case the
+// steps that carry data of their own, such as the candidate version sources,
+// the selected dependency, timings, warnings and environment variables, are
+// written as typed LogEvents.
+type Emitter struct {
+	scribe.Emitter
+
+	events *jsonLog
+}
+
+// NewEmitter returns an emitter that writes text to the given output.
+func NewEmitter(output io.Writer) Emitter {
+	return Emitter{Emitter: scribe.NewEmitter(output)}
+}
+
+// WithLevel configures the log level of a text emitter. To enable debug
+// logging the level must be "DEBUG".
+func (e Emitter) WithLevel(level string) Emitter {
+	e.Emitter = e.Emitter.WithLevel(level)
+	return e
+}
+
+// Candidates lists the version sources of the given build plan entries in
+// priority order, without duplicates.
+func (e Emitter) Candidates(entries []packit.BuildpackPlanEntry) {
+	if e.events == nil {
+		e.Emitter.Candidates(entries)
+		return
+	}
+
+	seen := map[[2]string]bool{}
+	for _, entry := range entries {
+		source, ok := entry.Metadata["version-source"].(string)
+		if !ok {
+			source = "<unknown>"
+		}
+		version, _ := entry.Metadata["version"].(string)
+
+		if seen[[2]string{source, version}] {
+			continue
+		}
+		seen[[2]string{source, version}] = true
+
+		e.events.emit(LogEvent{
+			Level:   "info",
+			Event:   LogEventCandidate,
+			Message: fmt.Sprintf("%s -> %q", source, version),
+			Depth:   3,
+			Source:  source,
+			Version: version,
+		})
+	}
+}
+
+// SelectedDependency reports the dependency that was selected for the build
+// plan entry and warns when it is, or is about to be, deprecated.
+func (e Emitter) SelectedDependency(entry packit.BuildpackPlanEntry, dependency postal.Dependency, now time.Time) {
+	if e.events == nil {
+		e.Emitter.SelectedDependency(entry, dependency, now)
+		return
+	}
+
+	source, ok := entry.Metadata["version-source"].(string)
+	if !ok {
+		source = "<unknown>"
+	}
+
+	e.events.emit(LogEvent{
+		Level:   "info",
+		Event:   LogEventSelected,
+		Message: fmt.Sprintf("Selected %s version (using %s): %s", dependency.Name, source, dependency.Version),
+		Depth:   2,
+		Name:    dependency.Name,
+		Source:  source,
+		Version: dependency.Version,
+	})
+
+	deprecationDate := dependency.DeprecationDate
+	switch {
+	case deprecationDate.IsZero():
+	case deprecationDate.Add(-30*24*time.Hour).Before(now) && deprecationDate.After(now):
+		e.Warning("Version %s of %s will be deprecated after %s. Migrate your application to a supported version of %s before this time.",
+			dependency.Version, dependency.Name, deprecationDate.Format("2006-01-02"), dependency.Name)
+	case !deprecationDate.After(now):
+		e.Warning("Version %s of %s is deprecated. Migrate your application to a supported version of %s.", dependency.Version, dependency.Name, dependency.Name)
+	}
+}
+
+// EnvironmentVariables lists the build and launch environment variables of
+// the layer.
+func (e Emitter) EnvironmentVariables(layer packit.Layer) {
+	if e.events == nil {
+		e.Emitter.EnvironmentVariables(layer)
+		return
+	}
+
+	buildEnv, launchEnv := packit.Environment{}, packit.Environment{}
+	for key, value := range layer.BuildEnv {
+		buildEnv[key] = value
+	}
+	for key, value := range layer.LaunchEnv {
+		launchEnv[key] = value
+	}
+	for key, value := range layer.SharedEnv {
+		buildEnv[key] = value
+		launchEnv[key] = value
+	}
+
+	for _, scope := range []struct {
+		name string
+		env  packit.Environment
+	}{{"build", buildEnv}, {"launch", launchEnv}} {
+		variables := scribe.NewFormattedMapFromEnvironment(scope.env)
+
+		var names []string
+		for name := range variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			value := fmt.Sprint(variables[name])
+			e.events.emit(LogEvent{
+				Level:   "info",
+				Event:   LogEventEnv,
+				Message: fmt.Sprintf("%s -> %q", name, value),
+				Depth:   2,
+				Scope:   scope.name,
+				Name:    name,
+				Value:   value,
+			})
+		}
+	}
+}
+
+// Warning reports a problem that does not fail the build. Further details can
+// follow as steps.
+func (e Emitter) Warning(format string, v ...interface{}) {
+	if e.events == nil {
+		e.Subprocess("WARNING: "+format, v...)
+		return
+	}
+
+	e.events.emit(LogEvent{
+		Level:   LogEventWarning,
+		Event:   LogEventWarning,
+		Message: fmt.Sprintf(format, v...),
+		Depth:   2,
+	})
+}
+
+// Completed reports how long the preceding step took.
+func (e Emitter) Completed(duration time.Duration) {
+	duration = duration.Round(time.Millisecond)
+	if e.events == nil {
+		e.Action("Completed in %s", duration)
+		return
+	}
+
+	millis := duration.Milliseconds()
+	e.events.emit(LogEvent{
+		Level:          "info",
+		Event:          LogEventTiming,
+		Message:        fmt.Sprintf("Completed in %s", duration),
+		Depth:          3,
+		DurationMillis: &millis,
+	})
+}
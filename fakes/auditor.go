@@ -0,0 +1,37 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/bundler"
+)
+
+type Auditor struct {
+	AuditCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir     string
+			PlatformPath   string
+			BundlerVersion string
+		}
+		Returns struct {
+			AdvisorySlice []bundler.Advisory
+			Error         error
+		}
+		Stub func(string, string, string) ([]bundler.Advisory, error)
+	}
+}
+
+func (f *Auditor) Audit(param1 string, param2 string, param3 string) ([]bundler.Advisory, error) {
+	f.AuditCall.mutex.Lock()
+	defer f.AuditCall.mutex.Unlock()
+	f.AuditCall.CallCount++
+	f.AuditCall.Receives.WorkingDir = param1
+	f.AuditCall.Receives.PlatformPath = param2
+	f.AuditCall.Receives.BundlerVersion = param3
+	if f.AuditCall.Stub != nil {
+		return f.AuditCall.Stub(param1, param2, param3)
+	}
+	return f.AuditCall.Returns.AdvisorySlice, f.AuditCall.Returns.Error
+}
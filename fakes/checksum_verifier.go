@@ -0,0 +1,38 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+type ChecksumVerifier struct {
+	VerifyCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Target     packit.TargetInfo
+			Distro     packit.TargetDistro
+		}
+		Returns struct {
+			ChecksumReport bundler.ChecksumReport
+			Error          error
+		}
+		Stub func(string, packit.TargetInfo, packit.TargetDistro) (bundler.ChecksumReport, error)
+	}
+}
+
+func (f *ChecksumVerifier) Verify(param1 string, param2 packit.TargetInfo, param3 packit.TargetDistro) (bundler.ChecksumReport, error) {
+	f.VerifyCall.mutex.Lock()
+	defer f.VerifyCall.mutex.Unlock()
+	f.VerifyCall.CallCount++
+	f.VerifyCall.Receives.WorkingDir = param1
+	f.VerifyCall.Receives.Target = param2
+	f.VerifyCall.Receives.Distro = param3
+	if f.VerifyCall.Stub != nil {
+		return f.VerifyCall.Stub(param1, param2, param3)
+	}
+	return f.VerifyCall.Returns.ChecksumReport, f.VerifyCall.Returns.Error
+}
@@ -0,0 +1,35 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/bundler"
+)
+
+type CompatibilityChecker struct {
+	CheckCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			BuildpackTOMLPath string
+			WorkingDir        string
+		}
+		Returns struct {
+			RubyCompatibility bundler.RubyCompatibility
+			Error             error
+		}
+		Stub func(string, string) (bundler.RubyCompatibility, error)
+	}
+}
+
+func (f *CompatibilityChecker) Check(param1 string, param2 string) (bundler.RubyCompatibility, error) {
+	f.CheckCall.mutex.Lock()
+	defer f.CheckCall.mutex.Unlock()
+	f.CheckCall.CallCount++
+	f.CheckCall.Receives.BuildpackTOMLPath = param1
+	f.CheckCall.Receives.WorkingDir = param2
+	if f.CheckCall.Stub != nil {
+		return f.CheckCall.Stub(param1, param2)
+	}
+	return f.CheckCall.Returns.RubyCompatibility, f.CheckCall.Returns.Error
+}
@@ -0,0 +1,34 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/bundler"
+)
+
+type ConfigParser struct {
+	ParseConfigCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			Config bundler.Config
+			Ok     bool
+			Err    error
+		}
+		Stub func(string) (bundler.Config, bool, error)
+	}
+}
+
+func (f *ConfigParser) ParseConfig(param1 string) (bundler.Config, bool, error) {
+	f.ParseConfigCall.mutex.Lock()
+	defer f.ParseConfigCall.mutex.Unlock()
+	f.ParseConfigCall.CallCount++
+	f.ParseConfigCall.Receives.Path = param1
+	if f.ParseConfigCall.Stub != nil {
+		return f.ParseConfigCall.Stub(param1)
+	}
+	return f.ParseConfigCall.Returns.Config, f.ParseConfigCall.Returns.Ok, f.ParseConfigCall.Returns.Err
+}
@@ -0,0 +1,33 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/bundler"
+)
+
+type EngineParser struct {
+	ParseEngineCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			Engine bundler.RubyEngine
+			Err    error
+		}
+		Stub func(string) (bundler.RubyEngine, error)
+	}
+}
+
+func (f *EngineParser) ParseEngine(param1 string) (bundler.RubyEngine, error) {
+	f.ParseEngineCall.mutex.Lock()
+	defer f.ParseEngineCall.mutex.Unlock()
+	f.ParseEngineCall.CallCount++
+	f.ParseEngineCall.Receives.Path = param1
+	if f.ParseEngineCall.Stub != nil {
+		return f.ParseEngineCall.Stub(param1)
+	}
+	return f.ParseEngineCall.Returns.Engine, f.ParseEngineCall.Returns.Err
+}
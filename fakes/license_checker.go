@@ -0,0 +1,38 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+type LicenseChecker struct {
+	CheckCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Dependency postal.Dependency
+			Policy     bundler.LicensePolicy
+		}
+		Returns struct {
+			LicenseResultSlice []bundler.LicenseResult
+			Error              error
+		}
+		Stub func(string, postal.Dependency, bundler.LicensePolicy) ([]bundler.LicenseResult, error)
+	}
+}
+
+func (f *LicenseChecker) Check(param1 string, param2 postal.Dependency, param3 bundler.LicensePolicy) ([]bundler.LicenseResult, error) {
+	f.CheckCall.mutex.Lock()
+	defer f.CheckCall.mutex.Unlock()
+	f.CheckCall.CallCount++
+	f.CheckCall.Receives.WorkingDir = param1
+	f.CheckCall.Receives.Dependency = param2
+	f.CheckCall.Receives.Policy = param3
+	if f.CheckCall.Stub != nil {
+		return f.CheckCall.Stub(param1, param2, param3)
+	}
+	return f.CheckCall.Returns.LicenseResultSlice, f.CheckCall.Returns.Error
+}
@@ -0,0 +1,38 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+type PlatformChecker struct {
+	CheckCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Target     packit.TargetInfo
+			Distro     packit.TargetDistro
+		}
+		Returns struct {
+			PlatformReport bundler.PlatformReport
+			Error          error
+		}
+		Stub func(string, packit.TargetInfo, packit.TargetDistro) (bundler.PlatformReport, error)
+	}
+}
+
+func (f *PlatformChecker) Check(param1 string, param2 packit.TargetInfo, param3 packit.TargetDistro) (bundler.PlatformReport, error) {
+	f.CheckCall.mutex.Lock()
+	defer f.CheckCall.mutex.Unlock()
+	f.CheckCall.CallCount++
+	f.CheckCall.Receives.WorkingDir = param1
+	f.CheckCall.Receives.Target = param2
+	f.CheckCall.Receives.Distro = param3
+	if f.CheckCall.Stub != nil {
+		return f.CheckCall.Stub(param1, param2, param3)
+	}
+	return f.CheckCall.Returns.PlatformReport, f.CheckCall.Returns.Error
+}
@@ -0,0 +1,40 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+type ProvenanceVerifier struct {
+	VerifyCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Dependency   postal.Dependency
+			CnbPath      string
+			PlatformPath string
+			Stack        string
+		}
+		Returns struct {
+			ProvenanceReport bundler.ProvenanceReport
+			Error            error
+		}
+		Stub func(postal.Dependency, string, string, string) (bundler.ProvenanceReport, error)
+	}
+}
+
+func (f *ProvenanceVerifier) Verify(param1 postal.Dependency, param2 string, param3 string, param4 string) (bundler.ProvenanceReport, error) {
+	f.VerifyCall.mutex.Lock()
+	defer f.VerifyCall.mutex.Unlock()
+	f.VerifyCall.CallCount++
+	f.VerifyCall.Receives.Dependency = param1
+	f.VerifyCall.Receives.CnbPath = param2
+	f.VerifyCall.Receives.PlatformPath = param3
+	f.VerifyCall.Receives.Stack = param4
+	if f.VerifyCall.Stub != nil {
+		return f.VerifyCall.Stub(param1, param2, param3, param4)
+	}
+	return f.VerifyCall.Returns.ProvenanceReport, f.VerifyCall.Returns.Error
+}
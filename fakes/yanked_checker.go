@@ -0,0 +1,35 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+type YankedChecker struct {
+	CheckCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			BuildpackTOMLPath string
+			Dependency        postal.Dependency
+		}
+		Returns struct {
+			Bool  bool
+			Error error
+		}
+		Stub func(string, postal.Dependency) (bool, error)
+	}
+}
+
+func (f *YankedChecker) Check(param1 string, param2 postal.Dependency) (bool, error) {
+	f.CheckCall.mutex.Lock()
+	defer f.CheckCall.mutex.Unlock()
+	f.CheckCall.CallCount++
+	f.CheckCall.Receives.BuildpackTOMLPath = param1
+	f.CheckCall.Receives.Dependency = param2
+	if f.CheckCall.Stub != nil {
+		return f.CheckCall.Stub(param1, param2)
+	}
+	return f.CheckCall.Returns.Bool, f.CheckCall.Returns.Error
+}
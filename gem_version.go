@@ -0,0 +1,139 @@
+package bundler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RubyGems versions do not follow semver: they may have any number of
+// segments and prerelease markers are letters appearing anywhere (e.g.
+// 2.3.0.rc1 or 1.0.a). The helpers below implement the comparison and
+// requirement rules of Gem::Version and Gem::Requirement closely enough to
+// evaluate advisory and compatibility data.
+
+var gemSegmentPattern = regexp.MustCompile(`[0-9]+|[a-zA-Z]+`)
+
+var gemRequirementPattern = regexp.MustCompile(`^\s*(=|!=|>=|<=|>|<|~>)?\s*([0-9][0-9a-zA-Z.\-]*)\s*$`)
+
+func gemVersionSegments(version string) []string {
+	return gemSegmentPattern.FindAllString(strings.ReplaceAll(version, "-", ".pre."), -1)
+}
+
+// compareGemVersions returns -1, 0 or 1 when a is respectively lower than,
+// equal to or greater than b.
+func compareGemVersions(a, b string) int {
+	aSegments, bSegments := trimGemSegments(gemVersionSegments(a)), trimGemSegments(gemVersionSegments(b))
+
+	for i := 0; i < len(aSegments) || i < len(bSegments); i++ {
+		aSegment, bSegment := "0", "0"
+		if i < len(aSegments) {
+			aSegment = aSegments[i]
+		}
+		if i < len(bSegments) {
+			bSegment = bSegments[i]
+		}
+
+		aNumber, aErr := strconv.Atoi(aSegment)
+		bNumber, bErr := strconv.Atoi(bSegment)
+
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNumber != bNumber {
+				return sign(aNumber - bNumber)
+			}
+		case aErr != nil && bErr != nil:
+			if aSegment != bSegment {
+				return sign(strings.Compare(aSegment, bSegment))
+			}
+		case aErr != nil:
+			// A letter segment marks a prerelease, which sorts before any number.
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// trimGemSegments drops trailing zero segments so that 1.0 and 1.0.0
+// compare as equal, matching Gem::Version#canonical_segments.
+func trimGemSegments(segments []string) []string {
+	for len(segments) > 0 && segments[len(segments)-1] == "0" {
+		segments = segments[:len(segments)-1]
+	}
+
+	return segments
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// gemRequirementSatisfied reports whether version matches every clause of a
+// comma separated Gem::Requirement such as ">= 2.6.0, < 4".
+func gemRequirementSatisfied(requirement, version string) (bool, error) {
+	for _, clause := range strings.Split(requirement, ",") {
+		matches := gemRequirementPattern.FindStringSubmatch(clause)
+		if matches == nil {
+			return false, fmt.Errorf("invalid gem requirement %q", requirement)
+		}
+
+		operator, target := matches[1], matches[2]
+		comparison := compareGemVersions(version, target)
+
+		var ok bool
+		switch operator {
+		case "", "=":
+			ok = comparison == 0
+		case "!=":
+			ok = comparison != 0
+		case ">":
+			ok = comparison > 0
+		case ">=":
+			ok = comparison >= 0
+		case "<":
+			ok = comparison < 0
+		case "<=":
+			ok = comparison <= 0
+		case "~>":
+			ok = comparison >= 0 && compareGemVersions(version, pessimisticUpperBound(target)) < 0
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// pessimisticUpperBound returns the exclusive upper bound of a "~>"
+// requirement: ~> 2.6.3 allows versions below 2.7 and ~> 2.6 below 3.
+func pessimisticUpperBound(version string) string {
+	var segments []string
+	for _, segment := range gemVersionSegments(version) {
+		if _, err := strconv.Atoi(segment); err != nil {
+			break
+		}
+		segments = append(segments, segment)
+	}
+
+	if len(segments) > 1 {
+		segments = segments[:len(segments)-1]
+	}
+
+	last, _ := strconv.Atoi(segments[len(segments)-1])
+	segments[len(segments)-1] = strconv.Itoa(last + 1)
+
+	return strings.Join(segments, ".")
+}
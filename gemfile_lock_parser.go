@@ -4,11 +4,108 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/Masterminds/semver"
 )
 
+var lockedSpecPattern = regexp.MustCompile(`^(\S+) \(([^)]+)\)$`)
+
+// GemfileLock is a representation of the sections of a Gemfile.lock that are
+// of interest to this buildpack.
+type GemfileLock struct {
+	Sources     []LockfileSource
+	Platforms   []string
+	Checksums   []LockedGem
+	RubyVersion string
+	BundledWith string
+}
+
+// LockfileSource is a GEM, GIT or PATH section of a Gemfile.lock along with
+// the specs that were resolved from it.
+type LockfileSource struct {
+	Type     string
+	Remote   string
+	Revision string
+	Specs    []LockedGem
+}
+
+// LockedGem is a single spec entry of a Gemfile.lock source. Platform is
+// empty for gems that are not platform specific. Checksum is only populated
+// for entries of the CHECKSUMS section and has the form "sha256:<hex>".
+type LockedGem struct {
+	Name     string
+	Version  string
+	Platform string
+	Checksum string
+}
+
+// Filename returns the name of the .gem package for the locked gem, as it
+// would be stored in vendor/cache.
+func (g LockedGem) Filename() string {
+	if g.Platform != "" && g.Platform != "ruby" {
+		return fmt.Sprintf("%s-%s-%s.gem", g.Name, g.Version, g.Platform)
+	}
+
+	return fmt.Sprintf("%s-%s.gem", g.Name, g.Version)
+}
+
+// Gems returns the specs of every source in the order they appear in the
+// Gemfile.lock.
+func (l GemfileLock) Gems() []LockedGem {
+	var gems []LockedGem
+	for _, source := range l.Sources {
+		gems = append(gems, source.Specs...)
+	}
+
+	return gems
+}
+
+// RubyEngine is the Ruby implementation an application is locked to. Name is
+// the value of RUBY_ENGINE, i.e. "ruby" for MRI.
+type RubyEngine struct {
+	Name    string
+	Version string
+}
+
+// RubyEngine returns the engine recorded in the RUBY VERSION section, such as
+// "ruby 3.1.4p0 (jruby 9.4.5.0)". Lockfiles without that section are only
+// known to target JRuby when they are locked for java platforms and, at
+// most, the generic ruby platform.
+func (l GemfileLock) RubyEngine() RubyEngine {
+	fields := strings.Fields(l.RubyVersion)
+	if len(fields) > 1 && fields[0] == "ruby" {
+		if len(fields) > 3 && strings.HasPrefix(fields[2], "(") {
+			return RubyEngine{
+				Name:    strings.TrimPrefix(fields[2], "("),
+				Version: strings.TrimSuffix(fields[3], ")"),
+			}
+		}
+
+		return RubyEngine{Name: "ruby", Version: rubyPatchLevelPattern.ReplaceAllString(fields[1], "")}
+	}
+
+	// Applications that also run on MRI list its platforms alongside java,
+	// so JRuby is only inferred when every specific platform is a java one.
+	var java bool
+	for _, platform := range l.Platforms {
+		switch {
+		case platform == "ruby":
+		case platform == "java" || platform == "universal-java" || strings.HasPrefix(platform, "universal-java-"):
+			java = true
+		default:
+			return RubyEngine{}
+		}
+	}
+
+	if java {
+		return RubyEngine{Name: "jruby"}
+	}
+
+	return RubyEngine{}
+}
+
 type GemfileLockParser struct{}
 
 func NewGemfileLockParser() GemfileLockParser {
@@ -16,13 +113,44 @@ func NewGemfileLockParser() GemfileLockParser {
 }
 
 func (p GemfileLockParser) ParseVersion(path string) (string, error) {
+	lockfile, err := p.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	if lockfile.BundledWith == "" {
+		return "", nil
+	}
+
+	version, err := semver.NewVersion(lockfile.BundledWith)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Gemfile.lock: %w", err)
+	}
+
+	return fmt.Sprintf("%d.*.*", version.Major()), nil
+}
+
+// ParseEngine returns the Ruby engine the Gemfile.lock at the given path was
+// locked with. The engine is empty when it cannot be determined.
+func (p GemfileLockParser) ParseEngine(path string) (RubyEngine, error) {
+	lockfile, err := p.Parse(path)
+	if err != nil {
+		return RubyEngine{}, err
+	}
+
+	return lockfile.RubyEngine(), nil
+}
+
+// Parse reads the Gemfile.lock at the given path. A missing file results in
+// an empty GemfileLock.
+func (p GemfileLockParser) Parse(path string) (GemfileLock, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", nil
+			return GemfileLock{}, nil
 		}
 
-		return "", fmt.Errorf("failed to parse Gemfile.lock: %w", err)
+		return GemfileLock{}, fmt.Errorf("failed to parse Gemfile.lock: %w", err)
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
@@ -30,23 +158,106 @@ func (p GemfileLockParser) ParseVersion(path string) (string, error) {
 		}
 	}()
 
+	var (
+		lockfile GemfileLock
+		section  string
+		source   *LockfileSource
+	)
+
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		if strings.TrimSpace(scanner.Text()) == "BUNDLED WITH" {
-			if scanner.Scan() {
-				version, err := semver.NewVersion(strings.TrimSpace(scanner.Text()))
-				if err != nil {
-					return "", fmt.Errorf("failed to parse Gemfile.lock: %w", err)
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			section = line
+			source = nil
+
+			switch section {
+			case "GEM", "GIT", "PATH":
+				lockfile.Sources = append(lockfile.Sources, LockfileSource{Type: section})
+				source = &lockfile.Sources[len(lockfile.Sources)-1]
+			}
+
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch section {
+		case "GEM", "GIT", "PATH":
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+			switch {
+			case indent == 2 && strings.HasPrefix(trimmed, "remote:"):
+				source.Remote = strings.TrimSpace(strings.TrimPrefix(trimmed, "remote:"))
+			case indent == 2 && strings.HasPrefix(trimmed, "revision:"):
+				source.Revision = strings.TrimSpace(strings.TrimPrefix(trimmed, "revision:"))
+			case indent == 4:
+				if gem, ok := parseLockedSpec(trimmed); ok {
+					source.Specs = append(source.Specs, gem)
 				}
+			}
 
-				return fmt.Sprintf("%d.*.*", version.Major()), nil
+		case "PLATFORMS":
+			lockfile.Platforms = append(lockfile.Platforms, trimmed)
+
+		case "CHECKSUMS":
+			if gem, ok := parseLockedChecksum(trimmed); ok {
+				lockfile.Checksums = append(lockfile.Checksums, gem)
 			}
+
+		case "RUBY VERSION":
+			lockfile.RubyVersion = trimmed
+
+		case "BUNDLED WITH":
+			lockfile.BundledWith = trimmed
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("failed to parse Gemfile.lock: %w", err)
+		return GemfileLock{}, fmt.Errorf("failed to parse Gemfile.lock: %w", err)
+	}
+
+	return lockfile, nil
+}
+
+// parseLockedChecksum parses a CHECKSUMS entry such as
+// "rack (2.2.8) sha256=<hex>". Entries may omit the checksum, for example
+// for gems sourced from a path.
+func parseLockedChecksum(line string) (LockedGem, bool) {
+	spec, digests, _ := strings.Cut(line, ") ")
+	if digests != "" {
+		spec += ")"
+	}
+
+	gem, ok := parseLockedSpec(spec)
+	if !ok {
+		return LockedGem{}, false
+	}
+
+	for _, digest := range strings.Split(digests, ",") {
+		if algorithm, value, found := strings.Cut(strings.TrimSpace(digest), "="); found && algorithm == "sha256" {
+			gem.Checksum = fmt.Sprintf("sha256:%s", value)
+		}
+	}
+
+	return gem, true
+}
+
+func parseLockedSpec(line string) (LockedGem, bool) {
+	matches := lockedSpecPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return LockedGem{}, false
+	}
+
+	gem := LockedGem{Name: matches[1], Version: matches[2]}
+
+	// RubyGems versions never contain a dash, so anything following the first
+	// one is the platform the spec was locked for (e.g. 1.13.10-x86_64-linux).
+	if version, platform, found := strings.Cut(gem.Version, "-"); found {
+		gem.Version, gem.Platform = version, platform
 	}
 
-	return "", nil
+	return gem, true
 }
@@ -50,6 +50,208 @@ BUNDLED WITH
 		Expect(os.RemoveAll(path)).To(Succeed())
 	})
 
+	context("Parse", func() {
+		it.Before(func() {
+			err := os.WriteFile(path, []byte(`GIT
+  remote: https://github.com/rails/rails.git
+  revision: 5b6c1e8e2b1c
+  branch: main
+  specs:
+    rails (7.1.0.alpha)
+      actionpack (= 7.1.0.alpha)
+
+PATH
+  remote: engines/admin
+  specs:
+    admin (0.1.0)
+
+GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.13.10-x86_64-linux)
+      racc (~> 1.4)
+    racc (1.6.2)
+
+PLATFORMS
+  ruby
+  x86_64-linux
+
+DEPENDENCIES
+  admin!
+  nokogiri
+  rails!
+
+CHECKSUMS
+  admin (0.1.0)
+  nokogiri (1.13.10-x86_64-linux) sha256=0b6ddd7a5ef2d1e8c1d79b4cd2c5a0d1a0d04bce4ce2b7b64dc2a1dde4dbc0c3
+  racc (1.6.2) sha256=58d26b3666382396fea84d33dc0639b7ee8d704156a52ceb180c9dd7ff5f5a6e,sha512=abc
+
+RUBY VERSION
+   ruby 3.1.4p0
+
+BUNDLED WITH
+   2.4.22
+`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("parses the sections of a Gemfile.lock file", func() {
+			lockfile, err := parser.Parse(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lockfile).To(Equal(bundler.GemfileLock{
+				Sources: []bundler.LockfileSource{
+					{
+						Type:     "GIT",
+						Remote:   "https://github.com/rails/rails.git",
+						Revision: "5b6c1e8e2b1c",
+						Specs: []bundler.LockedGem{
+							{Name: "rails", Version: "7.1.0.alpha"},
+						},
+					},
+					{
+						Type:   "PATH",
+						Remote: "engines/admin",
+						Specs: []bundler.LockedGem{
+							{Name: "admin", Version: "0.1.0"},
+						},
+					},
+					{
+						Type:   "GEM",
+						Remote: "https://rubygems.org/",
+						Specs: []bundler.LockedGem{
+							{Name: "nokogiri", Version: "1.13.10", Platform: "x86_64-linux"},
+							{Name: "racc", Version: "1.6.2"},
+						},
+					},
+				},
+				Platforms: []string{"ruby", "x86_64-linux"},
+				Checksums: []bundler.LockedGem{
+					{Name: "admin", Version: "0.1.0"},
+					{Name: "nokogiri", Version: "1.13.10", Platform: "x86_64-linux", Checksum: "sha256:0b6ddd7a5ef2d1e8c1d79b4cd2c5a0d1a0d04bce4ce2b7b64dc2a1dde4dbc0c3"},
+					{Name: "racc", Version: "1.6.2", Checksum: "sha256:58d26b3666382396fea84d33dc0639b7ee8d704156a52ceb180c9dd7ff5f5a6e"},
+				},
+				RubyVersion: "ruby 3.1.4p0",
+				BundledWith: "2.4.22",
+			}))
+
+			Expect(lockfile.Gems()).To(Equal([]bundler.LockedGem{
+				{Name: "rails", Version: "7.1.0.alpha"},
+				{Name: "admin", Version: "0.1.0"},
+				{Name: "nokogiri", Version: "1.13.10", Platform: "x86_64-linux"},
+				{Name: "racc", Version: "1.6.2"},
+			}))
+		})
+
+		context("when the Gemfile.lock file does not exist", func() {
+			it.Before(func() {
+				Expect(os.Remove(path)).To(Succeed())
+			})
+
+			it("returns an empty lockfile", func() {
+				lockfile, err := parser.Parse(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lockfile).To(Equal(bundler.GemfileLock{}))
+			})
+		})
+	})
+
+	context("ParseEngine", func() {
+		it("parses MRI from the RUBY VERSION section", func() {
+			engine, err := parser.ParseEngine(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(engine).To(Equal(bundler.RubyEngine{Name: "ruby", Version: "2.6.3"}))
+		})
+
+		context("when the RUBY VERSION section names another engine", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`PLATFORMS
+  universal-java-11
+
+RUBY VERSION
+   ruby 3.1.4p0 (jruby 9.4.5.0)
+`), 0600)).To(Succeed())
+			})
+
+			it("returns that engine", func() {
+				engine, err := parser.ParseEngine(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(engine).To(Equal(bundler.RubyEngine{Name: "jruby", Version: "9.4.5.0"}))
+			})
+		})
+
+		context("when only the java platform is locked", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`PLATFORMS
+  java
+`), 0600)).To(Succeed())
+			})
+
+			it("returns JRuby without a version", func() {
+				engine, err := parser.ParseEngine(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(engine).To(Equal(bundler.RubyEngine{Name: "jruby"}))
+			})
+		})
+
+		context("when java is locked alongside the generic ruby platform", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`PLATFORMS
+  ruby
+  universal-java-17
+`), 0600)).To(Succeed())
+			})
+
+			it("returns JRuby without a version", func() {
+				engine, err := parser.ParseEngine(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(engine).To(Equal(bundler.RubyEngine{Name: "jruby"}))
+			})
+		})
+
+		context("when java is locked alongside MRI platforms", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`PLATFORMS
+  java
+  ruby
+  x86_64-linux
+`), 0600)).To(Succeed())
+			})
+
+			it("returns an empty engine", func() {
+				engine, err := parser.ParseEngine(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(engine).To(Equal(bundler.RubyEngine{}))
+			})
+		})
+
+		context("when the engine cannot be determined", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`PLATFORMS
+  x86_64-linux
+`), 0600)).To(Succeed())
+			})
+
+			it("returns an empty engine", func() {
+				engine, err := parser.ParseEngine(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(engine).To(Equal(bundler.RubyEngine{}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the Gemfile.lock cannot be opened", func() {
+				it.Before(func() {
+					Expect(os.Chmod(path, 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseEngine(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse Gemfile.lock:")))
+				})
+			})
+		})
+	})
+
 	context("ParseVersion", func() {
 		it("parses the bundler major version from a Gemfile.lock file", func() {
 			version, err := parser.ParseVersion(path)
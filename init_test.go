@@ -9,10 +9,20 @@ import (
 
 func TestUnitBundler(t *testing.T) {
 	suite := spec.New("bundler", spec.Report(report.Terminal{}))
+	suite("AdvisoryAuditor", testAdvisoryAuditor)
 	suite("Build", testBuild)
+	suite("GemChecksumVerifier", testGemChecksumVerifier)
 	suite("BuildpackYMLParser", testBuildpackYMLParser)
+	suite("DependencyProvenanceVerifier", testDependencyProvenanceVerifier)
 	suite("Detect", testDetect)
 	suite("GemfileLockParser", testGemfileLockParser)
+	suite("JSONEmitter", testJSONEmitter)
+	suite("LicensePolicyChecker", testLicensePolicyChecker)
+	suite("LockfilePlatformChecker", testLockfilePlatformChecker)
+	suite("ResolutionExplainer", testResolutionExplainer)
+	suite("RubyCompatibilityChecker", testRubyCompatibilityChecker)
+	suite("VendorCacheChecker", testVendorCacheChecker)
 	suite("VersionShimmer", testVersionShimmer)
+	suite("YankedVersionChecker", testYankedVersionChecker)
 	suite.Run(t)
 }
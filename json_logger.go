@@ -0,0 +1,188 @@
+package bundler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// The event types of a LogEvent.
+const (
+	LogEventTitle     = "title"
+	LogEventStep      = "step"
+	LogEventCandidate = "candidate"
+	LogEventSelected  = "selected"
+	LogEventTiming    = "timing"
+	LogEventEnv       = "env"
+	LogEventWarning   = "warning"
+	LogEventError     = "error"
+)
+
+// LogEvent is a single line of JSON log output. Time, Level, Event and
+// Message are always present, the remaining fields depend on the event type.
+type LogEvent struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Event   string `json:"event"`
+	Message string `json:"message"`
+
+	// Depth is the indentation level of a step, starting at 1.
+	Depth int `json:"depth,omitempty"`
+
+	// Name is the dependency of a selected event or the variable of an env
+	// event.
+	Name string `json:"name,omitempty"`
+
+	// Source and Version describe candidate and selected events.
+	Source  string `json:"source,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// Scope is "build" or "launch" for env events and Value is the value of the
+	// variable.
+	Scope string `json:"scope,omitempty"`
+	Value string `json:"value,omitempty"`
+
+	DurationMillis *int64 `json:"duration_ms,omitempty"`
+}
+
+// jsonLog writes LogEvents as lines of JSON. The typed events are emitted by
+// the methods of Emitter, while the lines written through the scribe.Logger
+// become title and step events.
+type jsonLog struct {
+	mutex  sync.Mutex
+	output io.Writer
+	clock  chronos.Clock
+}
+
+func (l *jsonLog) emit(event LogEvent) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	event.Time = l.clock.Now().UTC().Format(time.RFC3339Nano)
+
+	content, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	_, _ = l.output.Write(append(content, '\n'))
+}
+
+func (l *jsonLog) write(level string, depth int, message string) {
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		event := LogEvent{Level: level, Event: LogEventStep, Message: line, Depth: depth}
+		if depth == 0 {
+			event.Event = LogEventTitle
+		}
+
+		l.emit(event)
+	}
+}
+
+type jsonLogWriter struct {
+	log   *jsonLog
+	level string
+	depth int
+}
+
+func (w jsonLogWriter) Write(p []byte) (int, error) {
+	w.log.write(w.level, w.depth, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func newJSONLeveledLogger(log *jsonLog, level string) scribe.LeveledLogger {
+	return scribe.LeveledLogger{
+		TitleWriter:      jsonLogWriter{log: log, level: level, depth: 0},
+		ProcessWriter:    jsonLogWriter{log: log, level: level, depth: 1},
+		SubprocessWriter: jsonLogWriter{log: log, level: level, depth: 2},
+		ActionWriter:     jsonLogWriter{log: log, level: level, depth: 3},
+		DetailWriter:     jsonLogWriter{log: log, level: level, depth: 4},
+		SubdetailWriter:  jsonLogWriter{log: log, level: level, depth: 5},
+	}
+}
+
+// NewJSONEmitter returns an emitter that writes one JSON encoded LogEvent per
+// step or line of log output. Debug events are only written when the level is
+// "DEBUG".
+func NewJSONEmitter(output io.Writer, level string, clock chronos.Clock) Emitter {
+	log := &jsonLog{output: output, clock: clock}
+
+	emitter := scribe.NewEmitter(io.Discard)
+	emitter.LeveledLogger = newJSONLeveledLogger(log, "info")
+	if strings.EqualFold(level, "DEBUG") {
+		emitter.Debug = newJSONLeveledLogger(log, "debug")
+	}
+
+	return Emitter{Emitter: emitter, events: log}
+}
+
+// JSONExitHandler reports the error that ends a build or detection as an
+// error LogEvent and exits with the code packit would use.
+type JSONExitHandler struct {
+	output   io.Writer
+	clock    chronos.Clock
+	exitFunc func(int)
+}
+
+func NewJSONExitHandler(output io.Writer, clock chronos.Clock) JSONExitHandler {
+	return JSONExitHandler{
+		output:   output,
+		clock:    clock,
+		exitFunc: os.Exit,
+	}
+}
+
+// WithExitFunc returns a copy of the handler that calls the given function
+// instead of os.Exit.
+func (h JSONExitHandler) WithExitFunc(exitFunc func(int)) JSONExitHandler {
+	h.exitFunc = exitFunc
+	return h
+}
+
+func (h JSONExitHandler) Error(err error) {
+	if err == nil {
+		h.exitFunc(0)
+		return
+	}
+
+	log := &jsonLog{output: h.output, clock: h.clock}
+	log.emit(LogEvent{Level: LogEventError, Event: LogEventError, Message: err.Error()})
+
+	// packit.Fail, with or without a message, is the only error that signals
+	// a failed detection rather than an error.
+	fail := packit.Fail
+	if errors.As(err, &fail) {
+		h.exitFunc(100)
+		return
+	}
+
+	h.exitFunc(1)
+}
+
+// LookupLogFormat reads $BP_LOG_FORMAT, which is either "text" (the default)
+// or "json".
+func LookupLogFormat() (string, error) {
+	format := strings.ToLower(os.Getenv("BP_LOG_FORMAT"))
+	switch format {
+	case "", "text":
+		return "text", nil
+	case "json":
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid value for BP_LOG_FORMAT: %q (must be \"text\" or \"json\")", os.Getenv("BP_LOG_FORMAT"))
+	}
+}
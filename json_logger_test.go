@@ -0,0 +1,202 @@
+package bundler_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testJSONEmitter(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buffer *bytes.Buffer
+		clock  chronos.Clock
+
+		events func() []bundler.LogEvent
+	)
+
+	it.Before(func() {
+		buffer = bytes.NewBuffer(nil)
+		clock = chronos.NewClock(func() time.Time {
+			return time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+		})
+
+		events = func() []bundler.LogEvent {
+			var result []bundler.LogEvent
+			scanner := bufio.NewScanner(bytes.NewReader(buffer.Bytes()))
+			for scanner.Scan() {
+				var event bundler.LogEvent
+				Expect(json.Unmarshal(scanner.Bytes(), &event)).To(Succeed())
+				result = append(result, event)
+			}
+			Expect(scanner.Err()).NotTo(HaveOccurred())
+
+			return result
+		}
+	})
+
+	context("NewJSONEmitter", func() {
+		var logger bundler.Emitter
+
+		it.Before(func() {
+			logger = bundler.NewJSONEmitter(buffer, "INFO", clock)
+		})
+
+		it("writes one event per line of output", func() {
+			logger.Title("Some Buildpack %s", "1.2.3")
+			logger.Process("Resolving Bundler version")
+			logger.Break()
+			logger.Debug.Process("not written")
+
+			Expect(events()).To(Equal([]bundler.LogEvent{
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "title", Message: "Some Buildpack 1.2.3"},
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "step", Message: "Resolving Bundler version", Depth: 1},
+			}))
+		})
+
+		it("describes candidates and the selected dependency", func() {
+			entries := []packit.BuildpackPlanEntry{
+				{Name: "bundler", Metadata: map[string]interface{}{"version-source": "BP_BUNDLER_VERSION", "version": "2.7.*"}},
+				{Name: "bundler", Metadata: map[string]interface{}{"version-source": "Gemfile.lock", "version": "2.6.2"}},
+			}
+			logger.Candidates(entries)
+			logger.SelectedDependency(entries[0], postal.Dependency{Name: "Bundler", Version: "2.7.2"}, clock.Now())
+
+			Expect(events()).To(Equal([]bundler.LogEvent{
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "candidate", Message: `BP_BUNDLER_VERSION -> "2.7.*"`, Depth: 3, Source: "BP_BUNDLER_VERSION", Version: "2.7.*"},
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "candidate", Message: `Gemfile.lock -> "2.6.2"`, Depth: 3, Source: "Gemfile.lock", Version: "2.6.2"},
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "selected", Message: "Selected Bundler version (using BP_BUNDLER_VERSION): 2.7.2", Depth: 2, Name: "Bundler", Source: "BP_BUNDLER_VERSION", Version: "2.7.2"},
+			}))
+		})
+
+		it("warns about a deprecated dependency", func() {
+			logger.SelectedDependency(
+				packit.BuildpackPlanEntry{Name: "bundler", Metadata: map[string]interface{}{"version-source": "Gemfile.lock"}},
+				postal.Dependency{Name: "Bundler", Version: "2.6.2", DeprecationDate: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+				clock.Now(),
+			)
+
+			Expect(events()).To(Equal([]bundler.LogEvent{
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "selected", Message: "Selected Bundler version (using Gemfile.lock): 2.6.2", Depth: 2, Name: "Bundler", Source: "Gemfile.lock", Version: "2.6.2"},
+				{Time: "2024-03-01T12:00:00Z", Level: "warning", Event: "warning", Message: "Version 2.6.2 of Bundler is deprecated. Migrate your application to a supported version of Bundler.", Depth: 2},
+			}))
+		})
+
+		it("describes timings, warnings and environment variables", func() {
+			logger.Completed(1500 * time.Millisecond)
+			logger.Warning("something is %s", "off")
+			logger.EnvironmentVariables(packit.Layer{
+				SharedEnv: packit.Environment{"GEM_PATH.append": "/layers/bundler", "GEM_PATH.delim": ":"},
+			})
+
+			millis := int64(1500)
+			Expect(events()).To(Equal([]bundler.LogEvent{
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "timing", Message: "Completed in 1.5s", Depth: 3, DurationMillis: &millis},
+				{Time: "2024-03-01T12:00:00Z", Level: "warning", Event: "warning", Message: "something is off", Depth: 2},
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "env", Message: `GEM_PATH -> "$GEM_PATH:/layers/bundler"`, Depth: 2, Scope: "build", Name: "GEM_PATH", Value: "$GEM_PATH:/layers/bundler"},
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "env", Message: `GEM_PATH -> "$GEM_PATH:/layers/bundler"`, Depth: 2, Scope: "launch", Name: "GEM_PATH", Value: "$GEM_PATH:/layers/bundler"},
+			}))
+		})
+
+		it("does not interpret the text of steps", func() {
+			logger.Subprocess("WARNING: written as a step")
+
+			Expect(events()).To(Equal([]bundler.LogEvent{
+				{Time: "2024-03-01T12:00:00Z", Level: "info", Event: "step", Message: "WARNING: written as a step", Depth: 2},
+			}))
+		})
+
+		context("when the level is DEBUG", func() {
+			it.Before(func() {
+				logger = bundler.NewJSONEmitter(buffer, "DEBUG", clock)
+			})
+
+			it("writes debug events", func() {
+				logger.Debug.Subprocess("some debug output")
+
+				Expect(events()).To(Equal([]bundler.LogEvent{
+					{Time: "2024-03-01T12:00:00Z", Level: "debug", Event: "step", Message: "some debug output", Depth: 2},
+				}))
+			})
+		})
+	})
+
+	context("JSONExitHandler", func() {
+		var (
+			handler  bundler.JSONExitHandler
+			exitCode int
+		)
+
+		it.Before(func() {
+			exitCode = -1
+			handler = bundler.NewJSONExitHandler(buffer, clock).WithExitFunc(func(code int) {
+				exitCode = code
+			})
+		})
+
+		it("writes an error event and exits with 1", func() {
+			handler.Error(errors.New("failed to resolve dependency"))
+
+			Expect(events()).To(Equal([]bundler.LogEvent{
+				{Time: "2024-03-01T12:00:00Z", Level: "error", Event: "error", Message: "failed to resolve dependency"},
+			}))
+			Expect(exitCode).To(Equal(1))
+		})
+
+		it("exits with 100 when detection fails", func() {
+			handler.Error(packit.Fail.WithMessage("no Gemfile"))
+
+			Expect(events()).To(HaveLen(1))
+			Expect(exitCode).To(Equal(100))
+		})
+
+		it("exits with 100 when a wrapped detection failure is returned", func() {
+			handler.Error(fmt.Errorf("detect: %w", packit.Fail))
+
+			Expect(exitCode).To(Equal(100))
+		})
+
+		it("exits with 0 without an error", func() {
+			handler.Error(nil)
+
+			Expect(buffer.String()).To(BeEmpty())
+			Expect(exitCode).To(Equal(0))
+		})
+	})
+
+	context("LookupLogFormat", func() {
+		it("defaults to text", func() {
+			format, err := bundler.LookupLogFormat()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(format).To(Equal("text"))
+		})
+
+		it("accepts json", func() {
+			t.Setenv("BP_LOG_FORMAT", "JSON")
+
+			format, err := bundler.LookupLogFormat()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(format).To(Equal("json"))
+		})
+
+		it("rejects other formats", func() {
+			t.Setenv("BP_LOG_FORMAT", "yaml")
+
+			_, err := bundler.LookupLogFormat()
+			Expect(err).To(MatchError(`invalid value for BP_LOG_FORMAT: "yaml" (must be "text" or "json")`))
+		})
+	})
+}
@@ -0,0 +1,391 @@
+package bundler
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	gemspecLicensePattern = regexp.MustCompile(`\.licenses?\s*=\s*(.+)$`)
+	quotedStringPattern   = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+)
+
+// LicensePolicy lists SPDX license identifiers that components are allowed
+// or denied to use. An empty Allowed list allows every license that is not
+// denied.
+type LicensePolicy struct {
+	Allowed []string
+	Denied  []string
+}
+
+// ParseLicensePolicy builds a LicensePolicy from comma separated lists of
+// SPDX license identifiers.
+func ParseLicensePolicy(allowed, denied string) LicensePolicy {
+	split := func(list string) []string {
+		var ids []string
+		for _, id := range strings.Split(list, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	return LicensePolicy{
+		Allowed: split(allowed),
+		Denied:  split(denied),
+	}
+}
+
+// IsEmpty reports whether the policy has no rules and therefore needs no
+// enforcement.
+func (p LicensePolicy) IsEmpty() bool {
+	return len(p.Allowed) == 0 && len(p.Denied) == 0
+}
+
+func (p LicensePolicy) permits(id string) bool {
+	for _, denied := range p.Denied {
+		if strings.EqualFold(denied, id) {
+			return false
+		}
+	}
+
+	if len(p.Allowed) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.Allowed {
+		if strings.EqualFold(allowed, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LicenseResult is the outcome of evaluating a single component against a
+// LicensePolicy. Expression is empty when no license could be determined.
+type LicenseResult struct {
+	Component  string
+	Version    string
+	Source     string
+	Expression string
+	Permitted  bool
+}
+
+type LicensePolicyChecker struct {
+	lockfileParser GemfileLockParser
+}
+
+func NewLicensePolicyChecker() LicensePolicyChecker {
+	return LicensePolicyChecker{
+		lockfileParser: NewGemfileLockParser(),
+	}
+}
+
+// Check evaluates the delivered Bundler dependency, and every gem locked in
+// the application's Gemfile.lock whose license can be read from a vendored
+// gemspec or .gem package, against the given policy.
+func (c LicensePolicyChecker) Check(workingDir string, dependency postal.Dependency, policy LicensePolicy) ([]LicenseResult, error) {
+	results := []LicenseResult{
+		evaluateLicenses(policy, LicenseResult{
+			Component:  Bundler,
+			Version:    dependency.Version,
+			Source:     "buildpack.toml",
+			Expression: joinLicenses(dependency.Licenses),
+		}),
+	}
+
+	lockfile, err := c.lockfileParser.Parse(filepath.Join(workingDir, GemfileLockSource))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gem := range lockfile.Gems() {
+		path, licenses, err := findGemspecLicenses(workingDir, gem)
+		if err != nil {
+			return nil, err
+		}
+
+		if path == "" {
+			continue
+		}
+
+		source, err := filepath.Rel(workingDir, path)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, evaluateLicenses(policy, LicenseResult{
+			Component:  gem.Name,
+			Version:    gem.Version,
+			Source:     source,
+			Expression: joinLicenses(licenses),
+		}))
+	}
+
+	return results, nil
+}
+
+func evaluateLicenses(policy LicensePolicy, result LicenseResult) LicenseResult {
+	if result.Expression == "" {
+		// A component without a known license can only satisfy a policy that
+		// does not restrict licenses to an allowed list.
+		result.Permitted = len(policy.Allowed) == 0
+		return result
+	}
+
+	result.Permitted = parseLicenseExpression(result.Expression).satisfiedBy(policy.permits)
+	return result
+}
+
+// joinLicenses combines a list of licenses, as found in buildpack.toml or a
+// gemspec, into a single expression. RubyGems treats multiple licenses as a
+// choice, so they are joined with OR.
+func joinLicenses(licenses []string) string {
+	var expressions []string
+	for _, license := range licenses {
+		if license = strings.TrimSpace(license); license == "" {
+			continue
+		}
+
+		if strings.ContainsAny(license, " ()") && len(licenses) > 1 {
+			license = fmt.Sprintf("(%s)", license)
+		}
+		expressions = append(expressions, license)
+	}
+
+	return strings.Join(expressions, " OR ")
+}
+
+func findGemspecLicenses(workingDir string, gem LockedGem) (string, []string, error) {
+	patterns := []string{
+		filepath.Join(workingDir, "vendor", "bundle", "ruby", "*", "specifications", fmt.Sprintf("%s-%s.gemspec", gem.Name, gem.Version)),
+		filepath.Join(workingDir, "vendor", "bundle", "ruby", "*", "specifications", fmt.Sprintf("%s-%s-*.gemspec", gem.Name, gem.Version)),
+		filepath.Join(workingDir, "vendor", "bundle", "ruby", "*", "bundler", "gems", "*", fmt.Sprintf("%s.gemspec", gem.Name)),
+		filepath.Join(workingDir, "vendor", "cache", "*", fmt.Sprintf("%s.gemspec", gem.Name)),
+		filepath.Join(workingDir, "vendor", "cache", fmt.Sprintf("%s-%s.gem", gem.Name, gem.Version)),
+		filepath.Join(workingDir, "vendor", "cache", fmt.Sprintf("%s-%s-*.gem", gem.Name, gem.Version)),
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to search for gemspecs: %w", err)
+		}
+
+		for _, match := range matches {
+			parse := parseGemspecLicenses
+			if filepath.Ext(match) == ".gem" {
+				parse = parseGemLicenses
+			}
+
+			licenses, err := parse(match)
+			if err != nil {
+				return "", nil, err
+			}
+
+			if len(licenses) > 0 {
+				return match, licenses, nil
+			}
+		}
+	}
+
+	return "", nil, nil
+}
+
+func parseGemspecLicenses(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gemspec: %w", err)
+	}
+
+	var licenses []string
+	for _, line := range strings.Split(string(content), "\n") {
+		matches := gemspecLicensePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if matches == nil {
+			continue
+		}
+
+		for _, quoted := range quotedStringPattern.FindAllStringSubmatch(matches[1], -1) {
+			licenses = append(licenses, quoted[1]+quoted[2])
+		}
+	}
+
+	return licenses, nil
+}
+
+// parseGemLicenses reads the licenses from the specification in the
+// metadata.gz of a .gem package, which is a tar archive.
+func parseGemLicenses(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gem: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close file: %v\n", err)
+		}
+	}()
+
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gem %s: %w", filepath.Base(path), err)
+		}
+
+		if header.Name != "metadata.gz" {
+			continue
+		}
+
+		metadata, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata of gem %s: %w", filepath.Base(path), err)
+		}
+
+		content, err := io.ReadAll(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata of gem %s: %w", filepath.Base(path), err)
+		}
+
+		var spec struct {
+			Licenses []string `yaml:"licenses"`
+		}
+		err = yaml.Unmarshal(content, &spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse metadata of gem %s: %w", filepath.Base(path), err)
+		}
+
+		return spec.Licenses, nil
+	}
+}
+
+// licenseExpression is a parsed SPDX license expression. Leaves hold a
+// license identifier; inner nodes combine their operands with AND or OR.
+type licenseExpression struct {
+	id       string
+	operator string
+	operands []licenseExpression
+}
+
+// satisfiedBy reports whether the expression can be fulfilled using only
+// licenses accepted by the given predicate.
+func (e licenseExpression) satisfiedBy(accept func(id string) bool) bool {
+	switch e.operator {
+	case "AND":
+		for _, operand := range e.operands {
+			if !operand.satisfiedBy(accept) {
+				return false
+			}
+		}
+		return true
+	case "OR":
+		for _, operand := range e.operands {
+			if operand.satisfiedBy(accept) {
+				return true
+			}
+		}
+		return false
+	default:
+		return accept(e.id)
+	}
+}
+
+// parseLicenseExpression parses an SPDX license expression. WITH exceptions
+// are dropped so that policies match on the license identifier. Expressions
+// that cannot be parsed are treated as a single identifier.
+func parseLicenseExpression(expression string) licenseExpression {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression))
+
+	parser := licenseExpressionParser{tokens: tokens}
+	result, ok := parser.parseOr()
+	if !ok || parser.position != len(tokens) {
+		return licenseExpression{id: strings.TrimSpace(expression)}
+	}
+
+	return result
+}
+
+type licenseExpressionParser struct {
+	tokens   []string
+	position int
+}
+
+func (p *licenseExpressionParser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *licenseExpressionParser) parseOr() (licenseExpression, bool) {
+	return p.parseBinary("OR", p.parseAnd)
+}
+
+func (p *licenseExpressionParser) parseAnd() (licenseExpression, bool) {
+	return p.parseBinary("AND", p.parseTerm)
+}
+
+func (p *licenseExpressionParser) parseBinary(operator string, operand func() (licenseExpression, bool)) (licenseExpression, bool) {
+	first, ok := operand()
+	if !ok {
+		return licenseExpression{}, false
+	}
+
+	operands := []licenseExpression{first}
+	for strings.EqualFold(p.peek(), operator) {
+		p.position++
+
+		next, ok := operand()
+		if !ok {
+			return licenseExpression{}, false
+		}
+		operands = append(operands, next)
+	}
+
+	if len(operands) == 1 {
+		return first, true
+	}
+
+	return licenseExpression{operator: operator, operands: operands}, true
+}
+
+func (p *licenseExpressionParser) parseTerm() (licenseExpression, bool) {
+	token := p.peek()
+	switch {
+	case token == "(":
+		p.position++
+		expression, ok := p.parseOr()
+		if !ok || p.peek() != ")" {
+			return licenseExpression{}, false
+		}
+		p.position++
+		return expression, true
+
+	case token == "", token == ")", strings.EqualFold(token, "AND"), strings.EqualFold(token, "OR"), strings.EqualFold(token, "WITH"):
+		return licenseExpression{}, false
+	}
+
+	p.position++
+	if strings.EqualFold(p.peek(), "WITH") {
+		p.position += 2
+		if p.position > len(p.tokens) {
+			return licenseExpression{}, false
+		}
+	}
+
+	return licenseExpression{id: token}, true
+}
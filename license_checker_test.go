@@ -0,0 +1,243 @@
+package bundler_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLicensePolicyChecker(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		dependency postal.Dependency
+
+		checker bundler.LicensePolicyChecker
+	)
+
+	writeFile := func(path, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+	}
+
+	// writeGem writes a .gem package holding only the metadata.gz with the
+	// given specification YAML.
+	writeGem := func(path, metadata string) {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		_, err := gz.Write([]byte(metadata))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gz.Close()).To(Succeed())
+
+		var gem bytes.Buffer
+		writer := tar.NewWriter(&gem)
+		Expect(writer.WriteHeader(&tar.Header{Name: "metadata.gz", Mode: 0644, Size: int64(compressed.Len())})).To(Succeed())
+		_, err = writer.Write(compressed.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+
+		writeFile(path, gem.String())
+	}
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+
+		writeFile(filepath.Join(workingDir, "Gemfile.lock"), `GIT
+  remote: https://github.com/example/internal-tools.git
+  revision: 0123456789abcdef
+  specs:
+    internal-tools (0.3.0)
+
+GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.13.10-x86_64-linux)
+    puma (6.4.0)
+    rack (2.2.3)
+    readline (0.0.4)
+    undocumented (1.0.0)
+
+PLATFORMS
+  x86_64-linux
+
+BUNDLED WITH
+   2.4.22
+`)
+
+		specifications := filepath.Join(workingDir, "vendor", "bundle", "ruby", "3.1.0", "specifications")
+		writeFile(filepath.Join(specifications, "rack-2.2.3.gemspec"), `Gem::Specification.new do |s|
+  s.name = "rack".freeze
+  s.version = "2.2.3"
+  s.licenses = ["MIT".freeze]
+end
+`)
+		writeFile(filepath.Join(specifications, "nokogiri-1.13.10-x86_64-linux.gemspec"), `Gem::Specification.new do |s|
+  s.name = "nokogiri".freeze
+  s.licenses = ["MIT".freeze, "Apache-2.0".freeze]
+end
+`)
+		writeFile(filepath.Join(specifications, "readline-0.0.4.gemspec"), `Gem::Specification.new do |s|
+  s.name = "readline".freeze
+  s.license = 'GPL-3.0-only WITH Classpath-exception-2.0'
+end
+`)
+		writeFile(filepath.Join(specifications, "undocumented-1.0.0.gemspec"), `Gem::Specification.new do |s|
+  s.name = "undocumented".freeze
+end
+`)
+		writeFile(filepath.Join(workingDir, "vendor", "cache", "internal-tools-0123456789ab", "internal-tools.gemspec"), `Gem::Specification.new do |spec|
+  spec.name = "internal-tools"
+  spec.license = "(MIT AND BSD-2-Clause) OR LicenseRef-Proprietary"
+end
+`)
+
+		writeGem(filepath.Join(workingDir, "vendor", "cache", "puma-6.4.0.gem"), `--- !ruby/object:Gem::Specification
+name: puma
+version: !ruby/object:Gem::Version
+  version: 6.4.0
+platform: ruby
+licenses:
+- BSD-3-Clause
+`)
+
+		dependency = postal.Dependency{
+			ID:       "bundler",
+			Version:  "2.4.22",
+			Licenses: []string{"MIT"},
+		}
+
+		checker = bundler.NewLicensePolicyChecker()
+	})
+
+	context("Check", func() {
+		it("evaluates bundler and every gem with a vendored gemspec or .gem license", func() {
+			results, err := checker.Check(workingDir, dependency, bundler.ParseLicensePolicy("MIT, Apache-2.0", ""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(Equal([]bundler.LicenseResult{
+				{
+					Component:  "bundler",
+					Version:    "2.4.22",
+					Source:     "buildpack.toml",
+					Expression: "MIT",
+					Permitted:  true,
+				},
+				{
+					Component:  "internal-tools",
+					Version:    "0.3.0",
+					Source:     filepath.Join("vendor", "cache", "internal-tools-0123456789ab", "internal-tools.gemspec"),
+					Expression: "(MIT AND BSD-2-Clause) OR LicenseRef-Proprietary",
+					Permitted:  false,
+				},
+				{
+					Component:  "nokogiri",
+					Version:    "1.13.10",
+					Source:     filepath.Join("vendor", "bundle", "ruby", "3.1.0", "specifications", "nokogiri-1.13.10-x86_64-linux.gemspec"),
+					Expression: "MIT OR Apache-2.0",
+					Permitted:  true,
+				},
+				{
+					Component:  "puma",
+					Version:    "6.4.0",
+					Source:     filepath.Join("vendor", "cache", "puma-6.4.0.gem"),
+					Expression: "BSD-3-Clause",
+					Permitted:  false,
+				},
+				{
+					Component:  "rack",
+					Version:    "2.2.3",
+					Source:     filepath.Join("vendor", "bundle", "ruby", "3.1.0", "specifications", "rack-2.2.3.gemspec"),
+					Expression: "MIT",
+					Permitted:  true,
+				},
+				{
+					Component:  "readline",
+					Version:    "0.0.4",
+					Source:     filepath.Join("vendor", "bundle", "ruby", "3.1.0", "specifications", "readline-0.0.4.gemspec"),
+					Expression: "GPL-3.0-only WITH Classpath-exception-2.0",
+					Permitted:  false,
+				},
+			}))
+		})
+
+		context("when only denied licenses are given", func() {
+			it("permits everything else, including a choice that avoids the denied license", func() {
+				results, err := checker.Check(workingDir, dependency, bundler.ParseLicensePolicy("", "gpl-3.0-only,LicenseRef-Proprietary"))
+				Expect(err).NotTo(HaveOccurred())
+
+				permitted := map[string]bool{}
+				for _, result := range results {
+					permitted[result.Component] = result.Permitted
+				}
+
+				Expect(permitted).To(Equal(map[string]bool{
+					"bundler":        true,
+					"internal-tools": true,
+					"nokogiri":       true,
+					"puma":           true,
+					"rack":           true,
+					"readline":       false,
+				}))
+			})
+		})
+
+		context("when every alternative of an AND expression must be allowed", func() {
+			it("permits the expression once each operand is allowed", func() {
+				results, err := checker.Check(workingDir, dependency, bundler.ParseLicensePolicy("MIT,BSD-2-Clause", ""))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results[1].Component).To(Equal("internal-tools"))
+				Expect(results[1].Permitted).To(BeTrue())
+			})
+		})
+
+		context("when the bundler dependency has no licenses", func() {
+			it.Before(func() {
+				dependency.Licenses = nil
+			})
+
+			it("fails only when an allowed list is given", func() {
+				results, err := checker.Check(workingDir, dependency, bundler.ParseLicensePolicy("MIT", ""))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results[0].Expression).To(BeEmpty())
+				Expect(results[0].Permitted).To(BeFalse())
+
+				results, err = checker.Check(workingDir, dependency, bundler.ParseLicensePolicy("", "GPL-3.0-only"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results[0].Permitted).To(BeTrue())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a vendored .gem is not a gem package", func() {
+				it.Before(func() {
+					writeFile(filepath.Join(workingDir, "vendor", "cache", "puma-6.4.0.gem"), "not a tarball, but long enough to be read as a tar header block")
+				})
+
+				it("returns an error", func() {
+					_, err := checker.Check(workingDir, dependency, bundler.ParseLicensePolicy("MIT", ""))
+					Expect(err).To(MatchError(ContainSubstring("failed to read gem puma-6.4.0.gem")))
+				})
+			})
+
+			context("when the Gemfile.lock cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.Chmod(filepath.Join(workingDir, "Gemfile.lock"), 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := checker.Check(workingDir, dependency, bundler.ParseLicensePolicy("MIT", ""))
+					Expect(err).To(MatchError(ContainSubstring("failed to parse Gemfile.lock")))
+				})
+			})
+		})
+	})
+}
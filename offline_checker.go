@@ -0,0 +1,137 @@
+package bundler
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+// UnsatisfiableSource is a GIT or PATH source of Gemfile.lock that cannot be
+// installed without network access or files outside the application.
+type UnsatisfiableSource struct {
+	Type   string
+	Remote string
+	Reason string
+}
+
+// OfflineReport is the outcome of cross-referencing Gemfile.lock with the
+// contents of vendor/cache. LockfileFound is false when the application has
+// no Gemfile.lock, in which case the check does not apply. Target is the
+// build target in os/arch form and Missing lists the .gem packages that an
+// offline `bundle install` on it needs but that are absent from vendor/cache.
+type OfflineReport struct {
+	LockfileFound bool
+	CacheFound    bool
+	Target        string
+	Missing       []string
+	Unsatisfiable []UnsatisfiableSource
+}
+
+// Ready reports whether `bundle install --local` can succeed with the
+// vendored gems.
+func (r OfflineReport) Ready() bool {
+	return !r.LockfileFound || (r.CacheFound && len(r.Missing) == 0 && len(r.Unsatisfiable) == 0)
+}
+
+type VendorCacheChecker struct {
+	lockfileParser GemfileLockParser
+}
+
+func NewVendorCacheChecker() VendorCacheChecker {
+	return VendorCacheChecker{
+		lockfileParser: NewGemfileLockParser(),
+	}
+}
+
+// Check determines whether every GEM spec has a package in vendor/cache for
+// the variant that is installed on the build target, and whether GIT and
+// PATH sources can be satisfied without network access.
+func (c VendorCacheChecker) Check(workingDir string, target packit.TargetInfo, distro packit.TargetDistro) (OfflineReport, error) {
+	lockfilePath := filepath.Join(workingDir, GemfileLockSource)
+	if !fileExists(lockfilePath) {
+		return OfflineReport{}, nil
+	}
+
+	lockfile, err := c.lockfileParser.Parse(lockfilePath)
+	if err != nil {
+		return OfflineReport{}, err
+	}
+
+	cacheDir := filepath.Join(workingDir, "vendor", "cache")
+
+	report := OfflineReport{LockfileFound: true}
+	info, err := os.Stat(cacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return OfflineReport{}, fmt.Errorf("failed to read vendor/cache: %w", err)
+	}
+	report.CacheFound = err == nil && info.IsDir()
+
+	var candidates []string
+	report.Target, candidates = targetPlatforms(target, distro)
+
+	for _, source := range lockfile.Sources {
+		switch source.Type {
+		case "GEM":
+			for _, spec := range installedVariants(source.Specs, candidates) {
+				if !fileExists(filepath.Join(cacheDir, spec.Filename())) {
+					report.Missing = append(report.Missing, spec.Filename())
+				}
+			}
+
+		case "GIT":
+			name := strings.TrimSuffix(path.Base(source.Remote), ".git")
+			revision := source.Revision
+			if len(revision) > 12 {
+				revision = revision[:12]
+			}
+
+			if !fileExists(filepath.Join(cacheDir, fmt.Sprintf("%s-%s", name, revision))) {
+				report.Unsatisfiable = append(report.Unsatisfiable, UnsatisfiableSource{
+					Type:   source.Type,
+					Remote: source.Remote,
+					Reason: fmt.Sprintf("revision %s is not cached in vendor/cache", source.Revision),
+				})
+			}
+
+		case "PATH":
+			if reason := pathSourceProblem(workingDir, source.Remote); reason != "" {
+				report.Unsatisfiable = append(report.Unsatisfiable, UnsatisfiableSource{
+					Type:   source.Type,
+					Remote: source.Remote,
+					Reason: reason,
+				})
+			}
+		}
+	}
+
+	sort.Strings(report.Missing)
+
+	return report, nil
+}
+
+func pathSourceProblem(workingDir, remote string) string {
+	if filepath.IsAbs(remote) {
+		return "absolute paths are not available inside the build container"
+	}
+
+	relative := filepath.Clean(remote)
+	if relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "path is outside of the application directory"
+	}
+
+	if !fileExists(filepath.Join(workingDir, relative)) {
+		return "path does not exist in the application directory"
+	}
+
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
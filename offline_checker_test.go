@@ -0,0 +1,230 @@
+package bundler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testVendorCacheChecker(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		cacheDir   string
+		target     packit.TargetInfo
+		checker    bundler.VendorCacheChecker
+	)
+
+	touch := func(path string) {
+		Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(path, nil, 0600)).To(Succeed())
+	}
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+		cacheDir = filepath.Join(workingDir, "vendor", "cache")
+
+		Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GIT
+  remote: https://github.com/example/tools.git
+  revision: 0123456789abcdef0123
+  specs:
+    tools (0.3.0)
+
+GIT
+  remote: https://github.com/example/cached.git
+  revision: fedcba9876543210fedc
+  specs:
+    cached (1.0.0)
+
+PATH
+  remote: engines/admin
+  specs:
+    admin (0.1.0)
+
+PATH
+  remote: ../shared
+  specs:
+    shared (0.2.0)
+
+GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.16.0-aarch64-linux)
+      racc (~> 1.4)
+    nokogiri (1.16.0-x86_64-linux)
+      racc (~> 1.4)
+    racc (1.7.3)
+    rack (2.2.8)
+
+PLATFORMS
+  aarch64-linux
+  x86_64-linux
+
+BUNDLED WITH
+   2.5.6
+`), 0600)).To(Succeed())
+
+		touch(filepath.Join(cacheDir, "nokogiri-1.16.0-x86_64-linux.gem"))
+		touch(filepath.Join(cacheDir, "racc-1.7.3.gem"))
+		touch(filepath.Join(cacheDir, "cached-fedcba987654", "cached.gemspec"))
+		touch(filepath.Join(workingDir, "engines", "admin", "admin.gemspec"))
+
+		target = packit.TargetInfo{OS: "linux", Arch: "arm64"}
+		checker = bundler.NewVendorCacheChecker()
+	})
+
+	context("Check", func() {
+		it("reports gems and sources that cannot be installed offline", func() {
+			report, err := checker.Check(workingDir, target, packit.TargetDistro{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report).To(Equal(bundler.OfflineReport{
+				LockfileFound: true,
+				CacheFound:    true,
+				Target:        "linux/arm64",
+				Missing:       []string{"nokogiri-1.16.0-aarch64-linux.gem", "rack-2.2.8.gem"},
+				Unsatisfiable: []bundler.UnsatisfiableSource{
+					{
+						Type:   "GIT",
+						Remote: "https://github.com/example/tools.git",
+						Reason: "revision 0123456789abcdef0123 is not cached in vendor/cache",
+					},
+					{
+						Type:   "PATH",
+						Remote: "../shared",
+						Reason: "path is outside of the application directory",
+					},
+				},
+			}))
+			Expect(report.Ready()).To(BeFalse())
+		})
+
+		context("when vendor/cache is complete", func() {
+			it.Before(func() {
+				touch(filepath.Join(cacheDir, "nokogiri-1.16.0-aarch64-linux.gem"))
+				touch(filepath.Join(cacheDir, "rack-2.2.8.gem"))
+				touch(filepath.Join(cacheDir, "tools-0123456789ab", "tools.gemspec"))
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GIT
+  remote: https://github.com/example/tools.git
+  revision: 0123456789abcdef0123
+  specs:
+    tools (0.3.0)
+
+GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.16.0-aarch64-linux)
+    nokogiri (1.16.0-x86_64-linux)
+    rack (2.2.8)
+
+PLATFORMS
+  aarch64-linux
+  x86_64-linux
+`), 0600)).To(Succeed())
+			})
+
+			it("reports the application as ready", func() {
+				report, err := checker.Check(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(bundler.OfflineReport{LockfileFound: true, CacheFound: true, Target: "linux/arm64"}))
+				Expect(report.Ready()).To(BeTrue())
+			})
+		})
+
+		context("when the lockfile covers platforms other than the build target", func() {
+			it.Before(func() {
+				target = packit.TargetInfo{OS: "linux", Arch: "amd64"}
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.16.0-x86_64-darwin)
+    nokogiri (1.16.0-x86_64-linux)
+    racc (1.7.3)
+
+PLATFORMS
+  x86_64-darwin
+  x86_64-linux
+`), 0600)).To(Succeed())
+			})
+
+			it("only requires the variants installed on the build target", func() {
+				report, err := checker.Check(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Missing).To(BeEmpty())
+				Expect(report.Ready()).To(BeTrue())
+			})
+		})
+
+		context("when the application has no Gemfile.lock", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "Gemfile.lock"))).To(Succeed())
+			})
+
+			it("reports that the check does not apply", func() {
+				report, err := checker.Check(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(bundler.OfflineReport{}))
+				Expect(report.Ready()).To(BeTrue())
+			})
+		})
+
+		context("when vendor/cache does not exist", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(cacheDir)).To(Succeed())
+			})
+
+			it("reports that the cache is missing", func() {
+				report, err := checker.Check(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.CacheFound).To(BeFalse())
+				Expect(report.Missing).To(Equal([]string{"nokogiri-1.16.0-aarch64-linux.gem", "racc-1.7.3.gem", "rack-2.2.8.gem"}))
+				Expect(report.Ready()).To(BeFalse())
+			})
+		})
+
+		context("when a PATH source is absolute or does not exist", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`PATH
+  remote: /opt/gems/internal
+  specs:
+    internal (0.1.0)
+
+PATH
+  remote: engines/missing
+  specs:
+    missing (0.1.0)
+`), 0600)).To(Succeed())
+			})
+
+			it("reports the sources as unsatisfiable", func() {
+				report, err := checker.Check(workingDir, target, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Unsatisfiable).To(Equal([]bundler.UnsatisfiableSource{
+					{Type: "PATH", Remote: "/opt/gems/internal", Reason: "absolute paths are not available inside the build container"},
+					{Type: "PATH", Remote: "engines/missing", Reason: "path does not exist in the application directory"},
+				}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the Gemfile.lock cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.Chmod(filepath.Join(workingDir, "Gemfile.lock"), 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := checker.Check(workingDir, target, packit.TargetDistro{})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse Gemfile.lock")))
+				})
+			})
+		})
+	})
+}
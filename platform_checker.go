@@ -0,0 +1,166 @@
+package bundler
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+// rubyArchitectures maps the architectures used by buildpack targets onto the
+// CPU names RubyGems uses in platform strings.
+var rubyArchitectures = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+	"386":   "x86",
+}
+
+// PlatformReport describes whether the PLATFORMS section of Gemfile.lock
+// allows Bundler to resolve the bundle on the build target.
+type PlatformReport struct {
+	// Target is the build target in os/arch form, e.g. linux/amd64.
+	Target string
+
+	// Candidates are the RubyGems platforms that match the build target.
+	Candidates []string
+
+	// Platforms are the entries of the Gemfile.lock PLATFORMS section. It is
+	// empty when there is no Gemfile.lock.
+	Platforms []string
+
+	// Matched is the PLATFORMS entry that matches the build target, if any.
+	Matched string
+
+	// Generic is true when the build target is not locked but the generic
+	// "ruby" platform is, which causes native gems to be compiled from source.
+	Generic bool
+}
+
+// Resolvable reports whether Bundler can use the lockfile on the build
+// target without adding a platform.
+func (r PlatformReport) Resolvable() bool {
+	return len(r.Platforms) == 0 || r.Matched != "" || r.Generic
+}
+
+// AddPlatformCommand returns the command that adds the build target to the
+// lockfile.
+func (r PlatformReport) AddPlatformCommand() string {
+	return fmt.Sprintf("bundle lock --add-platform %s", r.Candidates[0])
+}
+
+type LockfilePlatformChecker struct {
+	lockfileParser GemfileLockParser
+}
+
+func NewLockfilePlatformChecker() LockfilePlatformChecker {
+	return LockfilePlatformChecker{
+		lockfileParser: NewGemfileLockParser(),
+	}
+}
+
+// Check compares the PLATFORMS section of the application's Gemfile.lock
+// with the RubyGems platforms of the build target. Targets default to the
+// platform the buildpack runs on when unset.
+func (c LockfilePlatformChecker) Check(workingDir string, target packit.TargetInfo, distro packit.TargetDistro) (PlatformReport, error) {
+	lockfile, err := c.lockfileParser.Parse(filepath.Join(workingDir, GemfileLockSource))
+	if err != nil {
+		return PlatformReport{}, err
+	}
+
+	buildTarget, candidates := targetPlatforms(target, distro)
+	report := PlatformReport{
+		Target:     buildTarget,
+		Candidates: candidates,
+		Platforms:  lockfile.Platforms,
+	}
+
+	for _, platform := range lockfile.Platforms {
+		for _, candidate := range report.Candidates {
+			if platform == candidate {
+				report.Matched = platform
+				return report, nil
+			}
+		}
+	}
+
+	for _, platform := range lockfile.Platforms {
+		if platform == "ruby" {
+			report.Generic = true
+		}
+	}
+
+	return report, nil
+}
+
+// targetPlatforms returns the build target in os/arch form along with the
+// RubyGems platforms that match it. Targets default to the platform the
+// buildpack runs on when unset.
+func targetPlatforms(target packit.TargetInfo, distro packit.TargetDistro) (string, []string) {
+	os, arch := target.OS, target.Arch
+	if os == "" {
+		os = runtime.GOOS
+	}
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+
+	return fmt.Sprintf("%s/%s", os, arch), rubyPlatforms(os, arch, distro)
+}
+
+// installedVariants returns the variant of each locked gem that Bundler
+// installs on a target matching the candidate platforms: the locked variant
+// for the most preferred candidate, or else the generic ruby variant. Gems
+// that are only locked for other platforms are left out.
+func installedVariants(gems []LockedGem, candidates []string) []LockedGem {
+	type release struct{ name, version string }
+
+	var (
+		order    []release
+		variants = map[release]map[string]LockedGem{}
+	)
+	for _, gem := range gems {
+		key := release{gem.Name, gem.Version}
+		if _, ok := variants[key]; !ok {
+			order = append(order, key)
+			variants[key] = map[string]LockedGem{}
+		}
+
+		platform := gem.Platform
+		if platform == "" {
+			platform = "ruby"
+		}
+		variants[key][platform] = gem
+	}
+
+	preferred := append(append([]string{}, candidates...), "ruby")
+
+	var installed []LockedGem
+	for _, key := range order {
+		for _, platform := range preferred {
+			if gem, ok := variants[key][platform]; ok {
+				installed = append(installed, gem)
+				break
+			}
+		}
+	}
+
+	return installed
+}
+
+// rubyPlatforms returns the RubyGems platforms that Bundler accepts for the
+// given target, most preferred first. Alpine based targets use musl libc and
+// therefore need the -musl variants.
+func rubyPlatforms(os, arch string, distro packit.TargetDistro) []string {
+	cpu, ok := rubyArchitectures[arch]
+	if !ok {
+		cpu = arch
+	}
+
+	if strings.EqualFold(distro.Name, "alpine") {
+		return []string{fmt.Sprintf("%s-%s-musl", cpu, os)}
+	}
+
+	return []string{fmt.Sprintf("%s-%s", cpu, os), fmt.Sprintf("%s-%s-gnu", cpu, os)}
+}
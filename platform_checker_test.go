@@ -0,0 +1,125 @@
+package bundler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLockfilePlatformChecker(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		checker    bundler.LockfilePlatformChecker
+	)
+
+	writeLockfile := func(platforms string) {
+		Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.16.0-arm64-darwin)
+    rack (2.2.8)
+
+PLATFORMS
+`+platforms+`
+BUNDLED WITH
+   2.5.6
+`), 0600)).To(Succeed())
+	}
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+		checker = bundler.NewLockfilePlatformChecker()
+	})
+
+	context("Check", func() {
+		context("when the lockfile only covers another platform", func() {
+			it.Before(func() {
+				writeLockfile("  arm64-darwin\n")
+			})
+
+			it("reports that the lockfile cannot be resolved", func() {
+				report, err := checker.Check(workingDir, packit.TargetInfo{OS: "linux", Arch: "amd64"}, packit.TargetDistro{Name: "ubuntu"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(bundler.PlatformReport{
+					Target:     "linux/amd64",
+					Candidates: []string{"x86_64-linux", "x86_64-linux-gnu"},
+					Platforms:  []string{"arm64-darwin"},
+				}))
+				Expect(report.Resolvable()).To(BeFalse())
+				Expect(report.AddPlatformCommand()).To(Equal("bundle lock --add-platform x86_64-linux"))
+			})
+		})
+
+		context("when the lockfile covers the target", func() {
+			it.Before(func() {
+				writeLockfile("  arm64-darwin\n  aarch64-linux-gnu\n")
+			})
+
+			it("reports the matching platform", func() {
+				report, err := checker.Check(workingDir, packit.TargetInfo{OS: "linux", Arch: "arm64"}, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Matched).To(Equal("aarch64-linux-gnu"))
+				Expect(report.Resolvable()).To(BeTrue())
+			})
+		})
+
+		context("when the target is musl based", func() {
+			it.Before(func() {
+				writeLockfile("  x86_64-linux\n")
+			})
+
+			it("requires the musl platform", func() {
+				report, err := checker.Check(workingDir, packit.TargetInfo{OS: "linux", Arch: "amd64"}, packit.TargetDistro{Name: "alpine"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Candidates).To(Equal([]string{"x86_64-linux-musl"}))
+				Expect(report.Resolvable()).To(BeFalse())
+				Expect(report.AddPlatformCommand()).To(Equal("bundle lock --add-platform x86_64-linux-musl"))
+			})
+		})
+
+		context("when only the generic ruby platform matches", func() {
+			it.Before(func() {
+				writeLockfile("  arm64-darwin\n  ruby\n")
+			})
+
+			it("reports the lockfile as resolvable from source", func() {
+				report, err := checker.Check(workingDir, packit.TargetInfo{OS: "linux", Arch: "amd64"}, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Matched).To(BeEmpty())
+				Expect(report.Generic).To(BeTrue())
+				Expect(report.Resolvable()).To(BeTrue())
+			})
+		})
+
+		context("when there is no Gemfile.lock", func() {
+			it("reports nothing to check", func() {
+				report, err := checker.Check(workingDir, packit.TargetInfo{OS: "linux", Arch: "amd64"}, packit.TargetDistro{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Platforms).To(BeEmpty())
+				Expect(report.Resolvable()).To(BeTrue())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the Gemfile.lock cannot be parsed", func() {
+				it.Before(func() {
+					writeLockfile("  ruby\n")
+					Expect(os.Chmod(filepath.Join(workingDir, "Gemfile.lock"), 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := checker.Check(workingDir, packit.TargetInfo{}, packit.TargetDistro{})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse Gemfile.lock")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package bundler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+const (
+	// ProvenanceStatementFile is the name of the statement in the tarball that
+	// the bundler-provenance dependency delivers.
+	ProvenanceStatementFile = "statement.intoto.json"
+
+	StatementType      = "https://in-toto.io/Statement/v1"
+	SLSAProvenanceType = "https://slsa.dev/provenance/v1"
+)
+
+// ProvenanceReport is the outcome of checking the provenance statement of a
+// dependency against its buildpack.toml entry. Builder is the builder the
+// statement names, and Problems lists every way in which the statement does
+// not vouch for the dependency.
+type ProvenanceReport struct {
+	URI      string
+	Builder  string
+	Problems []string
+}
+
+// Failed reports whether the statement is missing or does not match the
+// dependency.
+func (r ProvenanceReport) Failed() bool {
+	return len(r.Problems) > 0
+}
+
+type provenanceStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []resourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     struct {
+		BuildDefinition struct {
+			ResolvedDependencies []resourceDescriptor `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+		RunDetails struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+		} `json:"runDetails"`
+	} `json:"predicate"`
+}
+
+type resourceDescriptor struct {
+	Name   string            `json:"name"`
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+type DependencyProvenanceVerifier struct {
+	dependencies DependencyManager
+}
+
+func NewDependencyProvenanceVerifier(dependencies DependencyManager) DependencyProvenanceVerifier {
+	return DependencyProvenanceVerifier{
+		dependencies: dependencies,
+	}
+}
+
+// Verify delivers the in-toto statement of the dependency, which
+// buildpack.toml lists as the bundler-provenance dependency of the same
+// version, and checks that its SLSA provenance records the artifact with the
+// checksum of the dependency, built from the source and source-checksum
+// listed in buildpack.toml. The statement is delivered like the dependency,
+// so it honours dependency mappings and mirrors and is packaged in offline
+// buildpacks. Build verifies the dependency once it has been delivered, which
+// validates the artifact against that checksum. The statement is not signed,
+// so this ties the artifact to its source but does not authenticate the
+// builder.
+func (v DependencyProvenanceVerifier) Verify(dependency postal.Dependency, cnbPath, platformPath, stack string) (ProvenanceReport, error) {
+	var report ProvenanceReport
+
+	provenance, err := v.dependencies.Resolve(filepath.Join(cnbPath, "buildpack.toml"), BundlerProvenance, dependency.Version, stack)
+	if err != nil {
+		var noDeps *postal.ErrNoDeps
+		if errors.As(err, &noDeps) {
+			report.Problems = append(report.Problems, fmt.Sprintf("buildpack.toml lists no provenance statement for %s %s", dependency.ID, dependency.Version))
+			return report, nil
+		}
+
+		return ProvenanceReport{}, fmt.Errorf("failed to resolve provenance statement: %w", err)
+	}
+	report.URI = provenance.URI
+
+	dir, err := os.MkdirTemp("", "provenance")
+	if err != nil {
+		return ProvenanceReport{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	err = v.dependencies.Deliver(provenance, cnbPath, dir, platformPath)
+	if err != nil {
+		report.Problems = append(report.Problems, fmt.Sprintf("provenance statement is not available: %s", err))
+		return report, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, ProvenanceStatementFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			report.Problems = append(report.Problems, fmt.Sprintf("provenance tarball does not hold %s", ProvenanceStatementFile))
+			return report, nil
+		}
+
+		return ProvenanceReport{}, fmt.Errorf("failed to read provenance statement %s: %w", report.URI, err)
+	}
+
+	var statement provenanceStatement
+	err = json.Unmarshal(content, &statement)
+	if err != nil {
+		report.Problems = append(report.Problems, fmt.Sprintf("provenance statement is not valid JSON: %s", err))
+		return report, nil
+	}
+
+	if statement.Type != StatementType {
+		report.Problems = append(report.Problems, fmt.Sprintf("statement type is %q, expected %q", statement.Type, StatementType))
+	}
+
+	if statement.PredicateType != SLSAProvenanceType {
+		report.Problems = append(report.Problems, fmt.Sprintf("predicate type is %q, expected %q", statement.PredicateType, SLSAProvenanceType))
+	}
+
+	report.Builder = statement.Predicate.RunDetails.Builder.ID
+	if report.Builder == "" {
+		report.Problems = append(report.Problems, "statement does not identify its builder")
+	}
+
+	checksum := dependency.Checksum
+	if checksum == "" && dependency.SHA256 != "" {
+		checksum = "sha256:" + dependency.SHA256
+	}
+
+	var subjectFound bool
+	for _, subject := range statement.Subject {
+		subjectFound = subjectFound || digestMatches(subject.Digest, checksum)
+	}
+
+	if !subjectFound {
+		report.Problems = append(report.Problems, fmt.Sprintf("no subject has the checksum of the dependency %s", checksum))
+	}
+
+	sourceChecksum := dependency.SourceChecksum
+	if sourceChecksum == "" && dependency.SourceSHA256 != "" {
+		sourceChecksum = "sha256:" + dependency.SourceSHA256
+	}
+
+	var sourceFound bool
+	for _, resolved := range statement.Predicate.BuildDefinition.ResolvedDependencies {
+		if resolved.URI != dependency.Source {
+			continue
+		}
+
+		sourceFound = true
+		if !digestMatches(resolved.Digest, sourceChecksum) {
+			report.Problems = append(report.Problems, fmt.Sprintf("source %s was resolved with a different checksum than %s", dependency.Source, sourceChecksum))
+		}
+	}
+
+	if !sourceFound {
+		report.Problems = append(report.Problems, fmt.Sprintf("source %s is not among the resolved dependencies", dependency.Source))
+	}
+
+	return report, nil
+}
+
+// digestMatches reports whether an in-toto digest set holds a checksum of the
+// form <algorithm>:<hex>.
+func digestMatches(digest map[string]string, checksum string) bool {
+	algorithm, hash, ok := strings.Cut(checksum, ":")
+	if !ok || hash == "" {
+		return false
+	}
+
+	return strings.EqualFold(digest[algorithm], hash)
+}
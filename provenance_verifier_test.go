@@ -0,0 +1,292 @@
+package bundler_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testDependencyProvenanceVerifier(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		statement     string
+		statementFile string
+		servedPath    string
+		provenanceURI string
+		tarball       []byte
+		cnbPath       string
+		platformPath  string
+		server        *httptest.Server
+		dependency    postal.Dependency
+		verifier      bundler.DependencyProvenanceVerifier
+
+		packageStatement func() []byte
+		verify           func() (bundler.ProvenanceReport, error)
+	)
+
+	it.Before(func() {
+		statement = `{
+  "_type": "https://in-toto.io/Statement/v1",
+  "subject": [
+    {
+      "name": "bundler-jammy-2.7.2-01234567.tgz",
+      "digest": { "sha256": "0123456789ABCDEF" }
+    }
+  ],
+  "predicateType": "https://slsa.dev/provenance/v1",
+  "predicate": {
+    "buildDefinition": {
+      "buildType": "https://github.com/paketo-buildpacks/bundler/dependency/compile/v1",
+      "externalParameters": { "target": "jammy", "version": "2.7.2" },
+      "resolvedDependencies": [
+        {
+          "uri": "https://rubygems.org/downloads/bundler-2.7.2.gem",
+          "digest": { "sha256": "fedcba9876543210" }
+        }
+      ]
+    },
+    "runDetails": {
+      "builder": { "id": "https://github.com/some-org/bundler/.github/workflows/compile.yml@refs/heads/main" }
+    }
+  }
+}`
+		statementFile = "statement.intoto.json"
+		servedPath = "/bundler-jammy-2.7.2-01234567.tgz.intoto.tar.gz"
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != servedPath {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			_, _ = w.Write(tarball)
+		}))
+		t.Cleanup(server.Close)
+
+		provenanceURI = server.URL + servedPath
+		cnbPath = t.TempDir()
+		platformPath = t.TempDir()
+
+		dependency = postal.Dependency{
+			ID:             "bundler",
+			Version:        "2.7.2",
+			Checksum:       "sha256:0123456789abcdef",
+			URI:            server.URL + "/bundler-jammy-2.7.2-01234567.tgz",
+			Source:         "https://rubygems.org/downloads/bundler-2.7.2.gem",
+			SourceChecksum: "sha256:fedcba9876543210",
+		}
+
+		verifier = bundler.NewDependencyProvenanceVerifier(postal.NewService(cargo.NewTransport()))
+
+		packageStatement = func() []byte {
+			var buffer bytes.Buffer
+			gz := gzip.NewWriter(&buffer)
+			tw := tar.NewWriter(gz)
+			Expect(tw.WriteHeader(&tar.Header{Name: statementFile, Mode: 0644, Size: int64(len(statement))})).To(Succeed())
+			_, err := tw.Write([]byte(statement))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tw.Close()).To(Succeed())
+			Expect(gz.Close()).To(Succeed())
+
+			return buffer.Bytes()
+		}
+
+		// verify packages the statement and lists it in buildpack.toml as
+		// the retrieval does.
+		verify = func() (bundler.ProvenanceReport, error) {
+			tarball = packageStatement()
+
+			sum := sha256.Sum256(tarball)
+			err := os.WriteFile(filepath.Join(cnbPath, "buildpack.toml"), []byte(fmt.Sprintf(`api = "0.7"
+
+[buildpack]
+  id = "paketo-buildpacks/bundler"
+
+[metadata]
+  [[metadata.dependencies]]
+    checksum = "sha256:%s"
+    id = "bundler-provenance"
+    stacks = ["*"]
+    uri = %q
+    version = "2.7.2"
+`, hex.EncodeToString(sum[:]), provenanceURI)), 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			return verifier.Verify(dependency, cnbPath, platformPath, "some-stack")
+		}
+	})
+
+	context("Verify", func() {
+		it("accepts a statement that matches the dependency", func() {
+			report, err := verify()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report).To(Equal(bundler.ProvenanceReport{
+				URI:     server.URL + "/bundler-jammy-2.7.2-01234567.tgz.intoto.tar.gz",
+				Builder: "https://github.com/some-org/bundler/.github/workflows/compile.yml@refs/heads/main",
+			}))
+			Expect(report.Failed()).To(BeFalse())
+		})
+
+		context("when a dependency mapping binding maps the statement", func() {
+			it.Before(func() {
+				servedPath = "/mapped/statement.intoto.tar.gz"
+				provenanceURI = "https://unreachable.example.com/bundler-jammy-2.7.2-01234567.tgz.intoto.tar.gz"
+			})
+
+			it("delivers the statement from the mapped location", func() {
+				sum := sha256.Sum256(packageStatement())
+
+				binding := filepath.Join(platformPath, "bindings", "dependencies")
+				Expect(os.MkdirAll(binding, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(binding, "type"), []byte("dependency-mapping"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(binding, hex.EncodeToString(sum[:])), []byte(server.URL+servedPath), 0600)).To(Succeed())
+
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.URI).To(Equal(provenanceURI))
+				Expect(report.Problems).To(BeEmpty())
+			})
+		})
+
+		context("when the dependency only lists legacy SHA256 fields", func() {
+			it.Before(func() {
+				dependency.Checksum = ""
+				dependency.SHA256 = "0123456789abcdef"
+				dependency.SourceChecksum = ""
+				dependency.SourceSHA256 = "fedcba9876543210"
+			})
+
+			it("accepts the statement", func() {
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Problems).To(BeEmpty())
+			})
+		})
+
+		context("when the artifact was not built from the listed source", func() {
+			it.Before(func() {
+				dependency.Checksum = "sha256:aaaa"
+				dependency.SourceChecksum = "sha256:bbbb"
+			})
+
+			it("reports every mismatch", func() {
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Problems).To(Equal([]string{
+					"no subject has the checksum of the dependency sha256:aaaa",
+					"source https://rubygems.org/downloads/bundler-2.7.2.gem was resolved with a different checksum than sha256:bbbb",
+				}))
+				Expect(report.Failed()).To(BeTrue())
+			})
+		})
+
+		context("when the statement names another source", func() {
+			it.Before(func() {
+				dependency.Source = "https://example.com/bundler-2.7.2.gem"
+			})
+
+			it("reports the missing source", func() {
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Problems).To(Equal([]string{
+					"source https://example.com/bundler-2.7.2.gem is not among the resolved dependencies",
+				}))
+			})
+		})
+
+		context("when the statement is not a SLSA provenance", func() {
+			it.Before(func() {
+				statement = `{"_type": "https://in-toto.io/Statement/v0.1", "predicateType": "https://spdx.dev/Document"}`
+			})
+
+			it("reports every problem", func() {
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Problems).To(Equal([]string{
+					`statement type is "https://in-toto.io/Statement/v0.1", expected "https://in-toto.io/Statement/v1"`,
+					`predicate type is "https://spdx.dev/Document", expected "https://slsa.dev/provenance/v1"`,
+					"statement does not identify its builder",
+					"no subject has the checksum of the dependency sha256:0123456789abcdef",
+					"source https://rubygems.org/downloads/bundler-2.7.2.gem is not among the resolved dependencies",
+				}))
+			})
+		})
+
+		context("when the statement is not JSON", func() {
+			it.Before(func() {
+				statement = "not json"
+			})
+
+			it("reports the problem", func() {
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Problems).To(ConsistOf(ContainSubstring("provenance statement is not valid JSON")))
+			})
+		})
+
+		context("when buildpack.toml lists no statement for the version", func() {
+			it.Before(func() {
+				dependency.Version = "2.7.1"
+			})
+
+			it("reports the problem", func() {
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.URI).To(BeEmpty())
+				Expect(report.Problems).To(Equal([]string{"buildpack.toml lists no provenance statement for bundler 2.7.1"}))
+			})
+		})
+
+		context("when the statement cannot be delivered", func() {
+			it.Before(func() {
+				provenanceURI = server.URL + "/missing.intoto.tar.gz"
+			})
+
+			it("reports the problem", func() {
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.URI).To(Equal(server.URL + "/missing.intoto.tar.gz"))
+				Expect(report.Problems).To(ConsistOf(ContainSubstring("provenance statement is not available")))
+			})
+		})
+
+		context("when the tarball does not hold the statement", func() {
+			it.Before(func() {
+				statementFile = "other.json"
+			})
+
+			it("reports the problem", func() {
+				report, err := verify()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Problems).To(Equal([]string{"provenance tarball does not hold statement.intoto.json"}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when buildpack.toml cannot be parsed", func() {
+				it("returns an error", func() {
+					Expect(os.WriteFile(filepath.Join(cnbPath, "buildpack.toml"), []byte("%%%"), 0600)).To(Succeed())
+
+					_, err := verifier.Verify(dependency, cnbPath, platformPath, "some-stack")
+					Expect(err).To(MatchError(ContainSubstring("failed to resolve provenance statement")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package bundler
+
+import (
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/draft"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+// VersionCandidate is a Bundler version requested by one of the version
+// sources of an application. File is the file the version was read from and
+// is empty for environment variables.
+type VersionCandidate struct {
+	Source  string `json:"source"         toml:"source"`
+	Version string `json:"version"        toml:"version"`
+	File    string `json:"file,omitempty" toml:"file,omitempty"`
+}
+
+// ResolvedDependency is the Bundler artifact that a build would deliver.
+type ResolvedDependency struct {
+	Version         string `json:"version"                    toml:"version"`
+	URI             string `json:"uri"                        toml:"uri"`
+	Checksum        string `json:"checksum"                   toml:"checksum"`
+	DeprecationDate string `json:"deprecation_date,omitempty" toml:"deprecation_date,omitempty"`
+}
+
+// Resolution explains how a Bundler version was chosen for an application.
+// Candidates are listed in priority order. Selected is empty when the
+// application does not request a version and the buildpack default is used.
+type Resolution struct {
+	Candidates []VersionCandidate `json:"candidates" toml:"candidates"`
+	Selected   VersionCandidate   `json:"selected"   toml:"selected"`
+	Dependency ResolvedDependency `json:"dependency" toml:"dependency"`
+}
+
+// ResolutionReportFile is the name of the file in the Bundler layer that
+// holds the BuildReport of the build that installed it.
+const ResolutionReportFile = "resolution-report.json"
+
+// BuildReport extends a Resolution with what the build did with the selected
+// dependency. Durations are zero when the cached layer was reused.
+type BuildReport struct {
+	Resolution
+
+	Reused        bool   `json:"reused"        toml:"reused"`
+	InstallMillis int64  `json:"install_ms"    toml:"install_ms"`
+	SBOMMillis    int64  `json:"sbom_ms"       toml:"sbom_ms"`
+	ShimStrategy  string `json:"shim_strategy" toml:"shim_strategy"`
+}
+
+type ResolutionExplainer struct {
+	detect               packit.DetectFunc
+	dependencies         DependencyManager
+	compatibilityChecker CompatibilityChecker
+	logger               Emitter
+}
+
+func NewResolutionExplainer(detect packit.DetectFunc, dependencies DependencyManager, compatibilityChecker CompatibilityChecker, logger Emitter) ResolutionExplainer {
+	return ResolutionExplainer{
+		detect:               detect,
+		dependencies:         dependencies,
+		compatibilityChecker: compatibilityChecker,
+		logger:               logger,
+	}
+}
+
+// Explain runs detection for the application, applies the version source
+// priorities used during the build and resolves the Bundler dependency from
+// the given buildpack.toml without downloading it.
+func (e ResolutionExplainer) Explain(workingDir, buildpackTOMLPath, stack string) (Resolution, error) {
+	result, err := e.detect(packit.DetectContext{
+		WorkingDir: workingDir,
+		CNBPath:    filepath.Dir(buildpackTOMLPath),
+		Stack:      stack,
+	})
+	if err != nil {
+		return Resolution{}, err
+	}
+
+	var entries []packit.BuildpackPlanEntry
+	for _, requirement := range result.Plan.Requires {
+		metadata, ok := requirement.Metadata.(BuildPlanMetadata)
+		if requirement.Name != Bundler || !ok {
+			continue
+		}
+
+		entries = append(entries, packit.BuildpackPlanEntry{
+			Name: requirement.Name,
+			Metadata: map[string]interface{}{
+				"version-source": metadata.VersionSource,
+				"version":        metadata.Version,
+			},
+		})
+	}
+
+	entry, allEntries := draft.NewPlanner().Resolve(Bundler, entries, VersionSourcePriorities)
+
+	version, _ := entry.Metadata["version"].(string)
+	source, _ := entry.Metadata["version-source"].(string)
+	dependency, err := resolveCompatible(e.dependencies, e.compatibilityChecker, buildpackTOMLPath, workingDir, stack, Bundler, version, source, e.logger)
+	if err != nil {
+		return Resolution{}, err
+	}
+
+	return newResolution(entry, allEntries, dependency, workingDir), nil
+}
+
+func newResolution(entry packit.BuildpackPlanEntry, allEntries []packit.BuildpackPlanEntry, dependency postal.Dependency, workingDir string) Resolution {
+	var resolution Resolution
+	for _, e := range allEntries {
+		resolution.Candidates = append(resolution.Candidates, versionCandidate(e, workingDir))
+	}
+
+	if source, _ := entry.Metadata["version-source"].(string); source != "" {
+		resolution.Selected = versionCandidate(entry, workingDir)
+	}
+
+	resolution.Dependency = ResolvedDependency{
+		Version:  dependency.Version,
+		URI:      dependency.URI,
+		Checksum: dependency.Checksum,
+	}
+
+	if !dependency.DeprecationDate.IsZero() {
+		resolution.Dependency.DeprecationDate = dependency.DeprecationDate.Format("2006-01-02")
+	}
+
+	return resolution
+}
+
+func versionCandidate(entry packit.BuildpackPlanEntry, workingDir string) VersionCandidate {
+	source, _ := entry.Metadata["version-source"].(string)
+	version, _ := entry.Metadata["version"].(string)
+
+	candidate := VersionCandidate{Source: source, Version: version}
+	switch source {
+	case BuildpackYMLSource, GemfileLockSource:
+		candidate.File = filepath.Join(workingDir, source)
+	}
+
+	return candidate
+}
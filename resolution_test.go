@@ -0,0 +1,134 @@
+package bundler_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/bundler/fakes"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testResolutionExplainer(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		detectContext     packit.DetectContext
+		requirements      []packit.BuildPlanRequirement
+		dependencyManager *fakes.DependencyManager
+		compatibility     *fakes.CompatibilityChecker
+		explainer         bundler.ResolutionExplainer
+	)
+
+	it.Before(func() {
+		requirements = []packit.BuildPlanRequirement{
+			{
+				Name:     bundler.Bundler,
+				Metadata: bundler.BuildPlanMetadata{VersionSource: "Gemfile.lock", Version: "2.*.*"},
+			},
+			{
+				Name:     bundler.Bundler,
+				Metadata: bundler.BuildPlanMetadata{VersionSource: "BP_BUNDLER_VERSION", Version: "2.7.1"},
+			},
+			{
+				Name:     "mri",
+				Metadata: bundler.BuildPlanMetadata{VersionSource: "Gemfile.lock", Version: "3.2.*"},
+			},
+		}
+
+		detect := func(context packit.DetectContext) (packit.DetectResult, error) {
+			detectContext = context
+			return packit.DetectResult{Plan: packit.BuildPlan{Requires: requirements}}, nil
+		}
+
+		dependencyManager = &fakes.DependencyManager{}
+		dependencyManager.ResolveCall.Returns.Dependency = postal.Dependency{
+			Version:         "2.7.1",
+			URI:             "some-uri",
+			Checksum:        "sha256:some-checksum",
+			DeprecationDate: time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC),
+		}
+
+		compatibility = &fakes.CompatibilityChecker{}
+
+		explainer = bundler.NewResolutionExplainer(detect, dependencyManager, compatibility, bundler.NewEmitter(bytes.NewBuffer(nil)))
+	})
+
+	context("Explain", func() {
+		it("lists the candidates, the winner and the dependency that would be delivered", func() {
+			resolution, err := explainer.Explain("/app", "/buildpack/buildpack.toml", "some-stack")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resolution).To(Equal(bundler.Resolution{
+				Candidates: []bundler.VersionCandidate{
+					{Source: "BP_BUNDLER_VERSION", Version: "2.7.1"},
+					{Source: "Gemfile.lock", Version: "2.*.*", File: "/app/Gemfile.lock"},
+				},
+				Selected: bundler.VersionCandidate{Source: "BP_BUNDLER_VERSION", Version: "2.7.1"},
+				Dependency: bundler.ResolvedDependency{
+					Version:         "2.7.1",
+					URI:             "some-uri",
+					Checksum:        "sha256:some-checksum",
+					DeprecationDate: "2026-03-31",
+				},
+			}))
+
+			Expect(detectContext.WorkingDir).To(Equal("/app"))
+			Expect(detectContext.CNBPath).To(Equal("/buildpack"))
+			Expect(detectContext.Stack).To(Equal("some-stack"))
+
+			Expect(dependencyManager.ResolveCall.Receives.Path).To(Equal("/buildpack/buildpack.toml"))
+			Expect(dependencyManager.ResolveCall.Receives.Id).To(Equal("bundler"))
+			Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.7.1"))
+			Expect(dependencyManager.ResolveCall.Receives.Stack).To(Equal("some-stack"))
+			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
+		})
+
+		context("when the application does not request a version", func() {
+			it.Before(func() {
+				requirements = nil
+			})
+
+			it("resolves the default version", func() {
+				resolution, err := explainer.Explain("/app", "/buildpack/buildpack.toml", "some-stack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resolution.Candidates).To(BeEmpty())
+				Expect(resolution.Selected).To(Equal(bundler.VersionCandidate{}))
+				Expect(resolution.Dependency.Version).To(Equal("2.7.1"))
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(BeEmpty())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when detection fails", func() {
+				it.Before(func() {
+					explainer = bundler.NewResolutionExplainer(func(packit.DetectContext) (packit.DetectResult, error) {
+						return packit.DetectResult{}, errors.New("failed to detect")
+					}, dependencyManager, compatibility, bundler.NewEmitter(bytes.NewBuffer(nil)))
+				})
+
+				it("returns an error", func() {
+					_, err := explainer.Explain("/app", "/buildpack/buildpack.toml", "some-stack")
+					Expect(err).To(MatchError("failed to detect"))
+				})
+			})
+
+			context("when the dependency cannot be resolved", func() {
+				it.Before(func() {
+					dependencyManager.ResolveCall.Returns.Error = errors.New("failed to resolve")
+				})
+
+				it("returns an error", func() {
+					_, err := explainer.Explain("/app", "/buildpack/buildpack.toml", "some-stack")
+					Expect(err).To(MatchError("failed to resolve"))
+				})
+			})
+		})
+	})
+}
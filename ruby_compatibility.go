@@ -0,0 +1,186 @@
+package bundler
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+var (
+	rubyPatchLevelPattern = regexp.MustCompile(`p\d+$`)
+	rubyVersionPattern    = regexp.MustCompile(`^\d+(\.[0-9A-Za-z]+)*$`)
+)
+
+// RubyVersion is the version of Ruby an application runs on and the place it
+// was read from.
+type RubyVersion struct {
+	Version string
+	Source  string
+}
+
+// IncompatibleBundler is a Bundler version from buildpack.toml whose
+// required Ruby version excludes the application's Ruby.
+type IncompatibleBundler struct {
+	Version             string
+	RequiredRubyVersion string
+}
+
+// RubyCompatibility lists the Bundler versions of buildpack.toml and those
+// that cannot run on the application's Ruby. Incompatible is empty when the
+// Ruby version is unknown.
+type RubyCompatibility struct {
+	Ruby           RubyVersion
+	DefaultVersion string
+	Versions       []string
+	Incompatible   []IncompatibleBundler
+}
+
+type RubyCompatibilityChecker struct {
+	lockfileParser GemfileLockParser
+}
+
+func NewRubyCompatibilityChecker() RubyCompatibilityChecker {
+	return RubyCompatibilityChecker{
+		lockfileParser: NewGemfileLockParser(),
+	}
+}
+
+// Check determines the application's Ruby version and compares it with the
+// Ruby version each Bundler dependency in buildpack.toml requires, as listed
+// in the [metadata.ruby-compatibility] table. Versions missing from the table
+// are considered compatible.
+func (c RubyCompatibilityChecker) Check(buildpackTOMLPath, workingDir string) (RubyCompatibility, error) {
+	var config struct {
+		Metadata struct {
+			DefaultVersions   map[string]string `toml:"default-versions"`
+			RubyCompatibility map[string]string `toml:"ruby-compatibility"`
+			Dependencies      []struct {
+				ID      string `toml:"id"`
+				Version string `toml:"version"`
+			} `toml:"dependencies"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.DecodeFile(buildpackTOMLPath, &config)
+	if err != nil {
+		return RubyCompatibility{}, fmt.Errorf("failed to parse buildpack.toml: %w", err)
+	}
+
+	ruby, err := c.rubyVersion(workingDir)
+	if err != nil {
+		return RubyCompatibility{}, err
+	}
+
+	compatibility := RubyCompatibility{
+		Ruby:           ruby,
+		DefaultVersion: config.Metadata.DefaultVersions[Bundler],
+	}
+
+	seen := map[string]bool{}
+	for _, dependency := range config.Metadata.Dependencies {
+		if dependency.ID != Bundler || seen[dependency.Version] {
+			continue
+		}
+		seen[dependency.Version] = true
+		compatibility.Versions = append(compatibility.Versions, dependency.Version)
+
+		requirement := config.Metadata.RubyCompatibility[dependency.Version]
+		if ruby.Version == "" || requirement == "" {
+			continue
+		}
+
+		ok, err := gemRequirementSatisfied(requirement, ruby.Version)
+		if err != nil {
+			return RubyCompatibility{}, fmt.Errorf("failed to parse the required Ruby version of bundler %s: %w", dependency.Version, err)
+		}
+
+		if !ok {
+			compatibility.Incompatible = append(compatibility.Incompatible, IncompatibleBundler{
+				Version:             dependency.Version,
+				RequiredRubyVersion: requirement,
+			})
+		}
+	}
+
+	return compatibility, nil
+}
+
+// rubyVersion reads the application's Ruby version from, in order of
+// precedence, $BP_MRI_VERSION, the RUBY VERSION section of Gemfile.lock and
+// .ruby-version. Wildcard versions such as "3.2.*" are reduced to their
+// lower bound, while ranges such as ">= 3.0" are skipped as they do not tell
+// which Ruby is installed.
+func (c RubyCompatibilityChecker) rubyVersion(workingDir string) (RubyVersion, error) {
+	if version := normalizeRubyVersion(os.Getenv("BP_MRI_VERSION")); version != "" {
+		return RubyVersion{Version: version, Source: "BP_MRI_VERSION"}, nil
+	}
+
+	lockfile, err := c.lockfileParser.Parse(filepath.Join(workingDir, GemfileLockSource))
+	if err != nil {
+		return RubyVersion{}, err
+	}
+
+	// The RUBY VERSION section has the form "ruby 3.2.2p53", optionally
+	// followed by the engine, e.g. "ruby 3.1.4p0 (jruby 9.4.5.0)".
+	if fields := strings.Fields(lockfile.RubyVersion); len(fields) > 1 && fields[0] == "ruby" {
+		if version := normalizeRubyVersion(rubyPatchLevelPattern.ReplaceAllString(fields[1], "")); version != "" {
+			return RubyVersion{Version: version, Source: GemfileLockSource}, nil
+		}
+	}
+
+	file, err := os.Open(filepath.Join(workingDir, ".ruby-version"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return RubyVersion{}, nil
+		}
+
+		return RubyVersion{}, fmt.Errorf("failed to read .ruby-version: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close file: %v\n", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	if scanner.Scan() {
+		if version := normalizeRubyVersion(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "ruby-")); version != "" {
+			return RubyVersion{Version: version, Source: ".ruby-version"}, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return RubyVersion{}, fmt.Errorf("failed to read .ruby-version: %w", err)
+	}
+
+	return RubyVersion{}, nil
+}
+
+// normalizeRubyVersion strips a leading "=" and trailing wildcard segments
+// from a version. Anything that is not an exact or wildcard version, for
+// example a range such as "~> 3.1" or another engine such as "jruby-9.4",
+// results in an empty string.
+func normalizeRubyVersion(version string) string {
+	version = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(version), "="))
+
+	segments := strings.Split(version, ".")
+	for len(segments) > 0 {
+		last := segments[len(segments)-1]
+		if last != "*" && last != "x" && last != "X" {
+			break
+		}
+		segments = segments[:len(segments)-1]
+	}
+	version = strings.Join(segments, ".")
+
+	if !rubyVersionPattern.MatchString(version) {
+		return ""
+	}
+
+	return version
+}
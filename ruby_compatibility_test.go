@@ -0,0 +1,219 @@
+package bundler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRubyCompatibilityChecker(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir        string
+		buildpackTOMLPath string
+		checker           bundler.RubyCompatibilityChecker
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+		buildpackTOMLPath = filepath.Join(t.TempDir(), "buildpack.toml")
+
+		Expect(os.WriteFile(buildpackTOMLPath, []byte(`
+[metadata]
+  [metadata.default-versions]
+    bundler = "2.x.x"
+
+  [metadata.ruby-compatibility]
+    "2.7.2" = ">= 3.1.0"
+    "4.0.18" = ">= 3.2.0"
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    version = "2.7.2"
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    version = "4.0.18"
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    version = "4.0.18"
+
+  [[metadata.dependencies]]
+    id = "bundler"
+    version = "4.0.19"
+`), 0600)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(workingDir, "Gemfile.lock"), []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    rack (2.2.8)
+
+RUBY VERSION
+   ruby 3.1.4p223
+
+BUNDLED WITH
+   2.5.6
+`), 0600)).To(Succeed())
+
+		checker = bundler.NewRubyCompatibilityChecker()
+	})
+
+	context("Check", func() {
+		it("lists the Bundler versions that do not support the Ruby from Gemfile.lock", func() {
+			compatibility, err := checker.Check(buildpackTOMLPath, workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compatibility).To(Equal(bundler.RubyCompatibility{
+				Ruby:           bundler.RubyVersion{Version: "3.1.4", Source: "Gemfile.lock"},
+				DefaultVersion: "2.x.x",
+				Versions:       []string{"2.7.2", "4.0.18", "4.0.19"},
+				Incompatible: []bundler.IncompatibleBundler{
+					{Version: "4.0.18", RequiredRubyVersion: ">= 3.2.0"},
+				},
+			}))
+		})
+
+		context("when $BP_MRI_VERSION is set", func() {
+			it.Before(func() {
+				t.Setenv("BP_MRI_VERSION", "3.0.*")
+			})
+
+			it("uses the lower bound of the version", func() {
+				compatibility, err := checker.Check(buildpackTOMLPath, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compatibility.Ruby).To(Equal(bundler.RubyVersion{Version: "3.0", Source: "BP_MRI_VERSION"}))
+				Expect(compatibility.Incompatible).To(HaveLen(2))
+			})
+		})
+
+		context("when $BP_MRI_VERSION is an exact version", func() {
+			it.Before(func() {
+				t.Setenv("BP_MRI_VERSION", "= 3.2.2")
+			})
+
+			it("uses the version", func() {
+				compatibility, err := checker.Check(buildpackTOMLPath, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compatibility.Ruby).To(Equal(bundler.RubyVersion{Version: "3.2.2", Source: "BP_MRI_VERSION"}))
+				Expect(compatibility.Incompatible).To(BeEmpty())
+			})
+		})
+
+		context("when $BP_MRI_VERSION is an open range", func() {
+			it.Before(func() {
+				t.Setenv("BP_MRI_VERSION", ">= 3.0")
+			})
+
+			it("falls back to Gemfile.lock", func() {
+				compatibility, err := checker.Check(buildpackTOMLPath, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compatibility.Ruby).To(Equal(bundler.RubyVersion{Version: "3.1.4", Source: "Gemfile.lock"}))
+			})
+		})
+
+		context("when $BP_MRI_VERSION is a pessimistic range", func() {
+			it.Before(func() {
+				t.Setenv("BP_MRI_VERSION", "~> 3.1")
+				Expect(os.Remove(filepath.Join(workingDir, "Gemfile.lock"))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, ".ruby-version"), []byte("3.3.0\n"), 0600)).To(Succeed())
+			})
+
+			it("falls back to .ruby-version", func() {
+				compatibility, err := checker.Check(buildpackTOMLPath, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compatibility.Ruby).To(Equal(bundler.RubyVersion{Version: "3.3.0", Source: ".ruby-version"}))
+			})
+		})
+
+		context("when the version comes from .ruby-version", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "Gemfile.lock"))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, ".ruby-version"), []byte("ruby-3.3.0\n"), 0600)).To(Succeed())
+			})
+
+			it("reads the version", func() {
+				compatibility, err := checker.Check(buildpackTOMLPath, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compatibility.Ruby).To(Equal(bundler.RubyVersion{Version: "3.3.0", Source: ".ruby-version"}))
+				Expect(compatibility.Incompatible).To(BeEmpty())
+			})
+		})
+
+		context("when .ruby-version names another engine", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "Gemfile.lock"))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, ".ruby-version"), []byte("jruby-9.4.5.0\n"), 0600)).To(Succeed())
+			})
+
+			it("does not report a Ruby version", func() {
+				compatibility, err := checker.Check(buildpackTOMLPath, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compatibility.Ruby).To(Equal(bundler.RubyVersion{}))
+				Expect(compatibility.Incompatible).To(BeEmpty())
+			})
+		})
+
+		context("when the Ruby version is unknown", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "Gemfile.lock"))).To(Succeed())
+			})
+
+			it("considers every version compatible", func() {
+				compatibility, err := checker.Check(buildpackTOMLPath, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compatibility).To(Equal(bundler.RubyCompatibility{
+					DefaultVersion: "2.x.x",
+					Versions:       []string{"2.7.2", "4.0.18", "4.0.19"},
+				}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when buildpack.toml cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(buildpackTOMLPath, []byte("%%%"), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := checker.Check(buildpackTOMLPath, workingDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse buildpack.toml")))
+				})
+			})
+
+			context("when a required Ruby version is invalid", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(buildpackTOMLPath, []byte(`
+[metadata.ruby-compatibility]
+  "4.0.18" = "newer than 3"
+
+[[metadata.dependencies]]
+  id = "bundler"
+  version = "4.0.18"
+`), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := checker.Check(buildpackTOMLPath, workingDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse the required Ruby version of bundler 4.0.18")))
+				})
+			})
+
+			context("when the Gemfile.lock cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.Chmod(filepath.Join(workingDir, "Gemfile.lock"), 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := checker.Check(buildpackTOMLPath, workingDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse Gemfile.lock")))
+				})
+			})
+		})
+	})
+}
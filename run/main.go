@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/paketo-buildpacks/bundler"
@@ -9,7 +10,7 @@ import (
 	"github.com/paketo-buildpacks/packit/v2/chronos"
 	"github.com/paketo-buildpacks/packit/v2/postal"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
-	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 )
 
 type Generator struct{}
@@ -19,19 +20,42 @@ func (f Generator) GenerateFromDependency(dependency postal.Dependency, path str
 }
 
 func main() {
-	logger := scribe.NewEmitter(os.Stdout).WithLevel(os.Getenv("BP_LOG_LEVEL"))
+	logger := bundler.NewEmitter(os.Stdout).WithLevel(os.Getenv("BP_LOG_LEVEL"))
+
+	var options []packit.Option
+	format, err := bundler.LookupLogFormat()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if format == "json" {
+		logger = bundler.NewJSONEmitter(os.Stdout, os.Getenv("BP_LOG_LEVEL"), chronos.DefaultClock)
+		options = append(options, packit.WithExitHandler(bundler.NewJSONExitHandler(os.Stdout, chronos.DefaultClock)))
+	}
 
 	packit.Run(
 		bundler.Detect(
 			bundler.NewBuildpackYMLParser(),
 			bundler.NewGemfileLockParser(),
+			bundler.NewGemfileLockParser(),
 		),
 		bundler.Build(
 			postal.NewService(cargo.NewTransport()),
 			bundler.NewVersionShimmer(),
 			Generator{},
+			bundler.NewAdvisoryAuditor(servicebindings.NewResolver()),
+			bundler.NewLicensePolicyChecker(),
+			bundler.NewGemChecksumVerifier(),
+			bundler.NewVendorCacheChecker(),
+			bundler.NewLockfilePlatformChecker(),
+			bundler.NewRubyCompatibilityChecker(),
+			bundler.NewYankedVersionChecker(),
+			bundler.NewDependencyProvenanceVerifier(postal.NewService(cargo.NewTransport())),
+			bundler.NewBuildpackYMLParser(),
 			logger,
 			chronos.DefaultClock,
 		),
+		options...,
 	)
 }
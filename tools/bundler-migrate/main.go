@@ -0,0 +1,59 @@
+// bundler-migrate moves the Bundler version of an application's buildpack.yml
+// into the BP_BUNDLER_VERSION build environment variable of its project.toml.
+//
+//	bundler-migrate --app . --drop-bundler-section --dry-run
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	var (
+		app                = flag.String("app", ".", "path to the application directory")
+		dropBundlerSection = flag.Bool("drop-bundler-section", false, "remove the bundler section from buildpack.yml")
+		dryRun             = flag.Bool("dry-run", false, "print a diff of the changes instead of writing them")
+	)
+
+	flag.Parse()
+
+	migration, err := NewBuildpackYMLMigrator().Plan(*app, *dropBundlerSection)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(migration.Files) == 0 {
+		fmt.Println("Nothing to migrate")
+		return
+	}
+
+	if *dryRun {
+		for _, file := range migration.Files {
+			fmt.Print(file.Diff())
+		}
+		return
+	}
+
+	err = migration.Apply()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, file := range migration.Files {
+		switch {
+		case file.Removed:
+			fmt.Fprintf(os.Stdout, "Removed %s\n", file.Path)
+		case file.Created:
+			fmt.Fprintf(os.Stdout, "Created %s\n", file.Path)
+		default:
+			fmt.Fprintf(os.Stdout, "Updated %s\n", file.Path)
+		}
+	}
+
+	if migration.Version != "" {
+		fmt.Fprintf(os.Stdout, "Set BP_BUNDLER_VERSION=%q\n", migration.Version)
+	}
+}
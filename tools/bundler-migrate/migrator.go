@@ -0,0 +1,412 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/bundler"
+	"gopkg.in/yaml.v2"
+)
+
+// ProjectTOMLSource is the project descriptor that replaces buildpack.yml.
+const ProjectTOMLSource = "project.toml"
+
+var (
+	tomlTableHeaderPattern    = regexp.MustCompile(`^\s*\[\[?\s*([^\[\]]+?)\s*\]\]?\s*(#.*)?$`)
+	yamlBundlerSectionPattern = regexp.MustCompile(`^bundler\s*:`)
+)
+
+// MigrationFile is a file changed by a Migration. Original is empty when the
+// file does not exist yet and Updated is empty when the file is removed.
+type MigrationFile struct {
+	Path     string
+	Original string
+	Updated  string
+	Created  bool
+	Removed  bool
+}
+
+// Diff returns the change to the file as a unified diff with three lines of
+// context around each hunk.
+func (f MigrationFile) Diff() string {
+	from, to := "a/"+filepath.Base(f.Path), "b/"+filepath.Base(f.Path)
+	if f.Created {
+		from = "/dev/null"
+	}
+	if f.Removed {
+		to = "/dev/null"
+	}
+
+	return unifiedDiff(from, to, f.Original, f.Updated)
+}
+
+// Migration moves the Bundler version of a buildpack.yml into project.toml.
+// Files only lists the files that change.
+type Migration struct {
+	Version string
+	Files   []MigrationFile
+}
+
+// Apply writes the changed files.
+func (m Migration) Apply() error {
+	for _, file := range m.Files {
+		if file.Removed {
+			err := os.Remove(file.Path)
+			if err != nil {
+				return fmt.Errorf("failed to remove %s: %w", file.Path, err)
+			}
+
+			continue
+		}
+
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(file.Path); err == nil {
+			mode = info.Mode().Perm()
+		}
+
+		err := os.WriteFile(file.Path, []byte(file.Updated), mode)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+
+	return nil
+}
+
+type BuildpackYMLMigrator struct {
+	parser bundler.BuildpackYMLParser
+}
+
+func NewBuildpackYMLMigrator() BuildpackYMLMigrator {
+	return BuildpackYMLMigrator{
+		parser: bundler.NewBuildpackYMLParser(),
+	}
+}
+
+// Plan works out the changes that set $BP_BUNDLER_VERSION in the project.toml
+// of an application to the version in its buildpack.yml. Existing
+// BP_BUNDLER_VERSION entries are updated in place and every other part of
+// both files is left as it is. When dropBundlerSection is true the bundler
+// section of buildpack.yml is removed, and so is the file if nothing else is
+// left in it.
+func (m BuildpackYMLMigrator) Plan(workingDir string, dropBundlerSection bool) (Migration, error) {
+	buildpackYMLPath := filepath.Join(workingDir, bundler.BuildpackYMLSource)
+	config, ok, err := m.parser.ParseConfig(buildpackYMLPath)
+	if err != nil {
+		return Migration{}, fmt.Errorf("failed to parse buildpack.yml: %w", err)
+	}
+
+	if !ok {
+		return Migration{}, nil
+	}
+
+	migration := Migration{Version: config.Version}
+
+	if config.Version != "" {
+		file, err := updateProjectTOML(filepath.Join(workingDir, ProjectTOMLSource), config.Version)
+		if err != nil {
+			return Migration{}, err
+		}
+
+		if file.Original != file.Updated {
+			migration.Files = append(migration.Files, file)
+		}
+	}
+
+	if dropBundlerSection {
+		file, err := dropBuildpackYMLBundlerSection(buildpackYMLPath)
+		if err != nil {
+			return Migration{}, err
+		}
+
+		migration.Files = append(migration.Files, file)
+	}
+
+	return migration, nil
+}
+
+// updateProjectTOML sets BP_BUNDLER_VERSION in the build environment of a
+// project.toml by editing its text, so that comments and layout survive.
+func updateProjectTOML(path, version string) (MigrationFile, error) {
+	file := MigrationFile{Path: path}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return MigrationFile{}, fmt.Errorf("failed to read project.toml: %w", err)
+		}
+
+		file.Created = true
+		file.Updated = fmt.Sprintf("[_]\nschema-version = \"0.2\"\n\n[[io.buildpacks.build.env]]\n  name = \"BP_BUNDLER_VERSION\"\n  value = %q\n", version)
+		return file, nil
+	}
+
+	file.Original = string(content)
+	if _, err := toml.Decode(file.Original, &map[string]interface{}{}); err != nil {
+		return MigrationFile{}, fmt.Errorf("failed to parse project.toml: %w", err)
+	}
+
+	lines := strings.Split(file.Original, "\n")
+
+	var (
+		table            string
+		legacy, current  bool
+		nameLine         = -1
+		valueLine        = -1
+		inBundlerVersion bool
+	)
+	for i, line := range lines {
+		if matches := tomlTableHeaderPattern.FindStringSubmatch(line); matches != nil {
+			table = matches[1]
+			inBundlerVersion = false
+			legacy = legacy || table == "build.env"
+			current = current || table == "io.buildpacks.build.env"
+			continue
+		}
+
+		if table != "build.env" && table != "io.buildpacks.build.env" {
+			continue
+		}
+
+		var entry map[string]interface{}
+		if _, err := toml.Decode(line, &entry); err != nil {
+			continue
+		}
+
+		if name, ok := entry["name"].(string); ok {
+			inBundlerVersion = name == "BP_BUNDLER_VERSION"
+			if inBundlerVersion {
+				nameLine = i
+			}
+		}
+
+		if _, ok := entry["value"]; ok && inBundlerVersion {
+			valueLine = i
+		}
+	}
+
+	indent := func(line string) string {
+		return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	}
+
+	switch {
+	case valueLine >= 0:
+		lines[valueLine] = fmt.Sprintf("%svalue = %q", indent(lines[valueLine]), version)
+	case nameLine >= 0:
+		lines = append(lines[:nameLine+1], append([]string{fmt.Sprintf("%svalue = %q", indent(lines[nameLine]), version)}, lines[nameLine+1:]...)...)
+	default:
+		header := "io.buildpacks.build.env"
+		if legacy && !current {
+			header = "build.env"
+		}
+
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("[[%s]]", header), `  name = "BP_BUNDLER_VERSION"`, fmt.Sprintf("  value = %q", version), "")
+	}
+
+	file.Updated = strings.Join(lines, "\n")
+
+	env, err := projectTOMLEnv(file.Updated)
+	if err != nil || env["BP_BUNDLER_VERSION"] != version {
+		return MigrationFile{}, fmt.Errorf("failed to update project.toml: set BP_BUNDLER_VERSION to %q manually", version)
+	}
+
+	return file, nil
+}
+
+// projectTOMLEnv returns the build environment of a project.toml in either
+// version of the project descriptor schema.
+func projectTOMLEnv(content string) (map[string]string, error) {
+	type env struct {
+		Name  string `toml:"name"`
+		Value string `toml:"value"`
+	}
+
+	var descriptor struct {
+		IO struct {
+			Buildpacks struct {
+				Build struct {
+					Env []env `toml:"env"`
+				} `toml:"build"`
+			} `toml:"buildpacks"`
+		} `toml:"io"`
+		Build struct {
+			Env []env `toml:"env"`
+		} `toml:"build"`
+	}
+
+	_, err := toml.Decode(content, &descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{}
+	for _, e := range append(descriptor.Build.Env, descriptor.IO.Buildpacks.Build.Env...) {
+		result[e.Name] = e.Value
+	}
+
+	return result, nil
+}
+
+// dropBuildpackYMLBundlerSection removes the top-level bundler key and the
+// lines nested under it from a buildpack.yml.
+func dropBuildpackYMLBundlerSection(path string) (MigrationFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return MigrationFile{}, fmt.Errorf("failed to read buildpack.yml: %w", err)
+	}
+
+	file := MigrationFile{Path: path, Original: string(content)}
+
+	var kept []string
+	lines := strings.Split(file.Original, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !yamlBundlerSectionPattern.MatchString(lines[i]) {
+			kept = append(kept, lines[i])
+			continue
+		}
+
+		end := i + 1
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) == "" {
+				continue
+			}
+
+			if lines[j][0] != ' ' && lines[j][0] != '\t' {
+				break
+			}
+
+			end = j + 1
+		}
+		i = end - 1
+	}
+
+	file.Updated = strings.Join(kept, "\n")
+
+	var original, updated map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &original); err != nil {
+		return MigrationFile{}, fmt.Errorf("failed to parse buildpack.yml: %w", err)
+	}
+	delete(original, "bundler")
+
+	if err := yaml.Unmarshal([]byte(file.Updated), &updated); err != nil || (len(original) > 0 || len(updated) > 0) && !reflect.DeepEqual(original, updated) {
+		return MigrationFile{}, fmt.Errorf("failed to remove the bundler section from buildpack.yml: remove it manually")
+	}
+
+	if len(updated) == 0 {
+		file.Updated, file.Removed = "", true
+	}
+
+	return file, nil
+}
+
+// unifiedDiff returns a unified diff with three lines of context between two
+// texts, or an empty string when they are equal.
+func unifiedDiff(from, to, original, updated string) string {
+	a, b := diffLines(original), diffLines(updated)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte
+		line string
+		a, b int
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	const context = 3
+
+	var builder strings.Builder
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		first := max(start-context, 0)
+		last := start
+		for k := start; k < len(ops) && k <= last+2*context; k++ {
+			if ops[k].kind != ' ' {
+				last = k
+			}
+		}
+		end := min(last+context+1, len(ops))
+
+		var aCount, bCount int
+		for _, o := range ops[first:end] {
+			if o.kind != '+' {
+				aCount++
+			}
+			if o.kind != '-' {
+				bCount++
+			}
+		}
+
+		aStart, bStart := ops[first].a+1, ops[first].b+1
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+
+		if builder.Len() == 0 {
+			fmt.Fprintf(&builder, "--- %s\n+++ %s\n", from, to)
+		}
+		fmt.Fprintf(&builder, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, o := range ops[first:end] {
+			fmt.Fprintf(&builder, "%c%s\n", o.kind, o.line)
+		}
+
+		start = end
+	}
+
+	return builder.String()
+}
+
+func diffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
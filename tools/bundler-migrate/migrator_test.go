@@ -0,0 +1,296 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestUnitBundlerMigrate(t *testing.T) {
+	spec.Run(t, "BuildpackYMLMigrator", testBuildpackYMLMigrator, spec.Report(report.Terminal{}))
+}
+
+func testBuildpackYMLMigrator(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		migrator   BuildpackYMLMigrator
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+
+		Expect(os.WriteFile(filepath.Join(workingDir, "buildpack.yml"), []byte(`---
+mri:
+  version: 3.2.2
+
+bundler:
+  # pinned for the legacy deploy
+  version: 2.1.4
+
+nodejs:
+  version: 20.*
+`), 0644)).To(Succeed())
+
+		migrator = NewBuildpackYMLMigrator()
+	})
+
+	context("Plan", func() {
+		context("when there is no project.toml", func() {
+			it("creates one", func() {
+				migration, err := migrator.Plan(workingDir, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(migration.Version).To(Equal("2.1.4"))
+				Expect(migration.Files).To(Equal([]MigrationFile{
+					{
+						Path:    filepath.Join(workingDir, "project.toml"),
+						Created: true,
+						Updated: `[_]
+schema-version = "0.2"
+
+[[io.buildpacks.build.env]]
+  name = "BP_BUNDLER_VERSION"
+  value = "2.1.4"
+`,
+					},
+				}))
+
+				Expect(migration.Files[0].Diff()).To(Equal(`--- /dev/null
++++ b/project.toml
+@@ -0,0 +1,6 @@
++[_]
++schema-version = "0.2"
++
++[[io.buildpacks.build.env]]
++  name = "BP_BUNDLER_VERSION"
++  value = "2.1.4"
+`))
+			})
+		})
+
+		context("when project.toml sets other variables", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "project.toml"), []byte(`[_]
+schema-version = "0.2"
+
+# build settings
+[[io.buildpacks.build.env]]
+  name = "BP_LOG_LEVEL"
+  value = "DEBUG"
+`), 0644)).To(Succeed())
+			})
+
+			it("appends the variable", func() {
+				migration, err := migrator.Plan(workingDir, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(migration.Files).To(HaveLen(1))
+				Expect(migration.Files[0].Updated).To(Equal(`[_]
+schema-version = "0.2"
+
+# build settings
+[[io.buildpacks.build.env]]
+  name = "BP_LOG_LEVEL"
+  value = "DEBUG"
+
+[[io.buildpacks.build.env]]
+  name = "BP_BUNDLER_VERSION"
+  value = "2.1.4"
+`))
+
+				Expect(migration.Files[0].Diff()).To(Equal(`--- a/project.toml
++++ b/project.toml
+@@ -5,3 +5,7 @@
+ [[io.buildpacks.build.env]]
+   name = "BP_LOG_LEVEL"
+   value = "DEBUG"
++
++[[io.buildpacks.build.env]]
++  name = "BP_BUNDLER_VERSION"
++  value = "2.1.4"
+`))
+			})
+		})
+
+		context("when project.toml already sets BP_BUNDLER_VERSION", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "project.toml"), []byte(`[[build.env]]
+name = "BP_BUNDLER_VERSION"
+value = "2.0.0" # old
+`), 0644)).To(Succeed())
+			})
+
+			it("updates the value in place", func() {
+				migration, err := migrator.Plan(workingDir, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(migration.Files).To(HaveLen(1))
+				Expect(migration.Files[0].Updated).To(Equal(`[[build.env]]
+name = "BP_BUNDLER_VERSION"
+value = "2.1.4"
+`))
+			})
+
+			context("when the entry is surrounded by other settings", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "project.toml"), []byte(`[_]
+schema-version = "0.2"
+id = "my-app"
+name = "My App"
+
+[[build.env]]
+name = "BP_BUNDLER_VERSION"
+value = "2.0.0"
+
+[[build.env]]
+name = "BP_LOG_LEVEL"
+value = "DEBUG"
+
+[[build.env]]
+name = "BP_KEEP_FILES"
+value = "public/*"
+`), 0644)).To(Succeed())
+				})
+
+				it("shows only the changed lines and their context", func() {
+					migration, err := migrator.Plan(workingDir, false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(migration.Files).To(HaveLen(1))
+					Expect(migration.Files[0].Diff()).To(Equal(`--- a/project.toml
++++ b/project.toml
+@@ -5,7 +5,7 @@
+ 
+ [[build.env]]
+ name = "BP_BUNDLER_VERSION"
+-value = "2.0.0"
++value = "2.1.4"
+ 
+ [[build.env]]
+ name = "BP_LOG_LEVEL"
+`))
+				})
+			})
+		})
+
+		context("when project.toml is up to date", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "project.toml"), []byte(`[[io.buildpacks.build.env]]
+name = "BP_BUNDLER_VERSION"
+value = "2.1.4"
+`), 0644)).To(Succeed())
+			})
+
+			it("does not change it", func() {
+				migration, err := migrator.Plan(workingDir, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(migration.Files).To(BeEmpty())
+			})
+		})
+
+		context("when the bundler section should be dropped", func() {
+			it("removes it and leaves the other keys alone", func() {
+				migration, err := migrator.Plan(workingDir, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(migration.Files).To(HaveLen(2))
+				Expect(migration.Files[1].Path).To(Equal(filepath.Join(workingDir, "buildpack.yml")))
+				Expect(migration.Files[1].Removed).To(BeFalse())
+				Expect(migration.Files[1].Updated).To(Equal(`---
+mri:
+  version: 3.2.2
+
+
+nodejs:
+  version: 20.*
+`))
+			})
+
+			context("when nothing else is left in buildpack.yml", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "buildpack.yml"), []byte("bundler:\n  version: 2.1.4\n"), 0644)).To(Succeed())
+				})
+
+				it("removes the file", func() {
+					migration, err := migrator.Plan(workingDir, true)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(migration.Files).To(HaveLen(2))
+					Expect(migration.Files[1].Removed).To(BeTrue())
+					Expect(migration.Files[1].Diff()).To(Equal(`--- a/buildpack.yml
++++ /dev/null
+@@ -1,2 +0,0 @@
+-bundler:
+-  version: 2.1.4
+`))
+				})
+			})
+		})
+
+		context("when buildpack.yml has no bundler key", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "buildpack.yml"), []byte("mri:\n  version: 3.2.2\n"), 0644)).To(Succeed())
+			})
+
+			it("has nothing to migrate", func() {
+				migration, err := migrator.Plan(workingDir, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(migration).To(Equal(Migration{}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when buildpack.yml cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "buildpack.yml"), []byte("%%%"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := migrator.Plan(workingDir, false)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse buildpack.yml")))
+				})
+			})
+
+			context("when project.toml cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "project.toml"), []byte("%%%"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := migrator.Plan(workingDir, false)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse project.toml")))
+				})
+			})
+
+			context("when the build environment is an inline array", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "project.toml"), []byte(`[io.buildpacks.build]
+env = [{ name = "BP_LOG_LEVEL", value = "DEBUG" }]
+`), 0644)).To(Succeed())
+				})
+
+				it("asks for a manual change", func() {
+					_, err := migrator.Plan(workingDir, false)
+					Expect(err).To(MatchError(`failed to update project.toml: set BP_BUNDLER_VERSION to "2.1.4" manually`))
+				})
+			})
+		})
+	})
+
+	context("Apply", func() {
+		it("writes and removes the files", func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "buildpack.yml"), []byte("bundler:\n  version: 2.1.4\n"), 0644)).To(Succeed())
+
+			migration, err := migrator.Plan(workingDir, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(migration.Apply()).To(Succeed())
+
+			Expect(filepath.Join(workingDir, "buildpack.yml")).NotTo(BeAnExistingFile())
+
+			content, err := os.ReadFile(filepath.Join(workingDir, "project.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`value = "2.1.4"`))
+		})
+	})
+}
@@ -0,0 +1,107 @@
+// bundler-resolve explains which Bundler version the buildpack would install
+// for an application directory, without running a build.
+//
+//	bundler-resolve --app . --buildpack-toml buildpack.toml --format json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+func main() {
+	var (
+		app           = flag.String("app", ".", "path to the application directory")
+		buildpackTOML = flag.String("buildpack-toml", "buildpack.toml", "path to the buildpack.toml with the Bundler dependencies")
+		stack         = flag.String("stack", "*", "the stack ID of the build")
+		target        = flag.String("target", fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH), "the os/arch target of the build")
+		format        = flag.String("format", "text", "the output format, text or json")
+	)
+
+	flag.Parse()
+
+	if *format != "text" && *format != "json" {
+		log.Fatalf("invalid format %q (must be \"text\" or \"json\")", *format)
+	}
+
+	targetOS, targetArch, ok := strings.Cut(*target, "/")
+	if !ok {
+		log.Fatalf("invalid target %q (must be os/arch)", *target)
+	}
+
+	// The dependency manager reads the target from the same variables the
+	// lifecycle sets during a build.
+	for name, value := range map[string]string{"CNB_TARGET_OS": targetOS, "CNB_TARGET_ARCH": targetArch} {
+		if err := os.Setenv(name, value); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	explainer := bundler.NewResolutionExplainer(
+		bundler.Detect(
+			bundler.NewBuildpackYMLParser(),
+			bundler.NewGemfileLockParser(),
+			bundler.NewGemfileLockParser(),
+		),
+		postal.NewService(cargo.NewTransport()),
+		bundler.NewRubyCompatibilityChecker(),
+		bundler.NewEmitter(os.Stderr),
+	)
+
+	resolution, err := explainer.Explain(*app, *buildpackTOML, *stack)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(resolution)
+	} else {
+		err = printResolution(os.Stdout, resolution)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printResolution(w io.Writer, resolution bundler.Resolution) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Candidate version sources (in priority order):")
+	if len(resolution.Candidates) == 0 {
+		fmt.Fprintln(tw, "  none, the buildpack default version applies")
+	}
+	for _, candidate := range resolution.Candidates {
+		file := candidate.File
+		if file == "" {
+			file = "environment"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t(%s)\n", candidate.Source, candidate.Version, file)
+	}
+	fmt.Fprintln(tw)
+
+	source := resolution.Selected.Source
+	if source == "" {
+		source = "default version"
+	}
+	fmt.Fprintf(tw, "Selected Bundler %s (using %s)\n", resolution.Dependency.Version, source)
+	fmt.Fprintf(tw, "  URI:\t%s\n", resolution.Dependency.URI)
+	fmt.Fprintf(tw, "  Checksum:\t%s\n", resolution.Dependency.Checksum)
+	if resolution.Dependency.DeprecationDate != "" {
+		fmt.Fprintf(tw, "  End of life:\t%s\n", resolution.Dependency.DeprecationDate)
+	}
+
+	return tw.Flush()
+}
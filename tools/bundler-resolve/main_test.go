@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestUnitBundlerResolve(t *testing.T) {
+	spec.Run(t, "printResolution", testPrintResolution, spec.Report(report.Terminal{}))
+}
+
+func testPrintResolution(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buffer *bytes.Buffer
+	)
+
+	it.Before(func() {
+		buffer = bytes.NewBuffer(nil)
+	})
+
+	it("prints the candidates in priority order and the selected artifact", func() {
+		err := printResolution(buffer, bundler.Resolution{
+			Candidates: []bundler.VersionCandidate{
+				{Source: "BP_BUNDLER_VERSION", Version: "2.7.x"},
+				{Source: "Gemfile.lock", Version: "2.*.*", File: "/app/Gemfile.lock"},
+			},
+			Selected: bundler.VersionCandidate{Source: "BP_BUNDLER_VERSION", Version: "2.7.x"},
+			Dependency: bundler.ResolvedDependency{
+				Version:         "2.7.2",
+				URI:             "https://example.com/bundler-2.7.2.tgz",
+				Checksum:        "sha256:some-checksum",
+				DeprecationDate: "2027-04-01",
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buffer.String()).To(Equal(`Candidate version sources (in priority order):
+  BP_BUNDLER_VERSION  2.7.x  (environment)
+  Gemfile.lock        2.*.*  (/app/Gemfile.lock)
+
+Selected Bundler 2.7.2 (using BP_BUNDLER_VERSION)
+  URI:          https://example.com/bundler-2.7.2.tgz
+  Checksum:     sha256:some-checksum
+  End of life:  2027-04-01
+`))
+	})
+
+	context("when no version source is set", func() {
+		it("names the default version", func() {
+			err := printResolution(buffer, bundler.Resolution{
+				Dependency: bundler.ResolvedDependency{
+					Version:  "2.7.2",
+					URI:      "https://example.com/bundler-2.7.2.tgz",
+					Checksum: "sha256:some-checksum",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buffer.String()).To(Equal(`Candidate version sources (in priority order):
+  none, the buildpack default version applies
+
+Selected Bundler 2.7.2 (using default version)
+  URI:       https://example.com/bundler-2.7.2.tgz
+  Checksum:  sha256:some-checksum
+`))
+		})
+	})
+}
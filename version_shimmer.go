@@ -19,6 +19,10 @@ import (
 
 const VersionShimTemplate = "#!/usr/bin/env sh\nexec %s _%s_ ${@:-}"
 
+// ShimStrategy names the way executables are pinned to the installed version
+// in build reports.
+const ShimStrategy = "version-argument"
+
 type VersionShimmer struct{}
 
 func NewVersionShimmer() VersionShimmer {
@@ -0,0 +1,41 @@
+package bundler
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+)
+
+// YankedVersionChecker reads the [metadata.yanked-versions] table of
+// buildpack.toml, which lists the versions of each dependency that have been
+// yanked from RubyGems since they were added. The dependency retrieval tool
+// keeps the table up to date.
+type YankedVersionChecker struct{}
+
+func NewYankedVersionChecker() YankedVersionChecker {
+	return YankedVersionChecker{}
+}
+
+// Check reports whether buildpack.toml lists the version of the dependency as
+// yanked.
+func (c YankedVersionChecker) Check(buildpackTOMLPath string, dependency postal.Dependency) (bool, error) {
+	var config struct {
+		Metadata struct {
+			YankedVersions map[string][]string `toml:"yanked-versions"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.DecodeFile(buildpackTOMLPath, &config)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse buildpack.toml: %w", err)
+	}
+
+	for _, version := range config.Metadata.YankedVersions[dependency.ID] {
+		if version == dependency.Version {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,82 @@
+package bundler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/bundler"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testYankedVersionChecker(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buildpackTOMLPath string
+		checker           bundler.YankedVersionChecker
+	)
+
+	it.Before(func() {
+		buildpackTOMLPath = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(buildpackTOMLPath, []byte(`
+[metadata.yanked-versions]
+  bundler = ["2.7.1"]
+  other = ["2.7.2"]
+
+[[metadata.dependencies]]
+  id = "bundler"
+  version = "2.7.1"
+
+[[metadata.dependencies]]
+  id = "bundler"
+  version = "2.7.2"
+`), 0600)).To(Succeed())
+
+		checker = bundler.NewYankedVersionChecker()
+	})
+
+	context("Check", func() {
+		it("reports the versions listed for the dependency", func() {
+			yanked, err := checker.Check(buildpackTOMLPath, postal.Dependency{ID: "bundler", Version: "2.7.1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(yanked).To(BeTrue())
+
+			yanked, err = checker.Check(buildpackTOMLPath, postal.Dependency{ID: "bundler", Version: "2.7.2"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(yanked).To(BeFalse())
+		})
+
+		context("when buildpack.toml has no yanked versions", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(buildpackTOMLPath, []byte(`
+[[metadata.dependencies]]
+  id = "bundler"
+  version = "2.7.1"
+`), 0600)).To(Succeed())
+			})
+
+			it("reports nothing as yanked", func() {
+				yanked, err := checker.Check(buildpackTOMLPath, postal.Dependency{ID: "bundler", Version: "2.7.1"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(yanked).To(BeFalse())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when buildpack.toml cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(buildpackTOMLPath, []byte("%%%"), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := checker.Check(buildpackTOMLPath, postal.Dependency{ID: "bundler", Version: "2.7.1"})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse buildpack.toml")))
+				})
+			})
+		})
+	})
+}